@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	github.com/cli/go-gh/v2 v2.12.1
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/sys v0.31.0
 )
 
 require (
@@ -35,7 +36,6 @@ require (
 	github.com/stretchr/testify v1.7.0 // indirect
 	github.com/thlib/go-timezone-local v0.0.0-20210907160436-ef149e42d28e // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
-	golang.org/x/sys v0.31.0 // indirect
 	golang.org/x/term v0.30.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
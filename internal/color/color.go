@@ -0,0 +1,60 @@
+package color
+
+import "os"
+
+// SGR codes of the styles used in list output
+const (
+	bold   = "1"
+	dim    = "2"
+	green  = "32"
+	yellow = "33"
+	cyan   = "36"
+)
+
+// Enabled reports whether output should be colored: only to a terminal, and
+// never with --no-color, NO_COLOR set to any non-empty value (https://no-color.org),
+// or TERM=dumb
+func Enabled(terminal, noColor bool) bool {
+	if noColor || !terminal {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return true
+}
+
+// Palette wraps text in ANSI escape sequences, or returns it unchanged if
+// color is disabled
+type Palette struct {
+	enabled bool
+}
+
+// New returns a Palette that colors text only if enabled is true
+func New(enabled bool) *Palette {
+	return &Palette{enabled: enabled}
+}
+
+// Bold renders s in bold
+func (p *Palette) Bold(s string) string { return p.wrap(bold, s) }
+
+// Dim renders s with reduced intensity
+func (p *Palette) Dim(s string) string { return p.wrap(dim, s) }
+
+// Green renders s in green
+func (p *Palette) Green(s string) string { return p.wrap(green, s) }
+
+// Yellow renders s in yellow
+func (p *Palette) Yellow(s string) string { return p.wrap(yellow, s) }
+
+// Cyan renders s in cyan
+func (p *Palette) Cyan(s string) string { return p.wrap(cyan, s) }
+
+// wrap surrounds s with the SGR sequence for code and a reset.
+// Empty strings are left alone so that blank columns stay blank.
+func (p *Palette) wrap(code, s string) string {
+	if !p.enabled || s == "" {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
@@ -0,0 +1,54 @@
+package color
+
+import "testing"
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		terminal bool
+		noColor  bool
+		env      map[string]string
+		want     bool
+	}{
+		{name: "terminal", terminal: true, want: true},
+		{name: "not a terminal", terminal: false, want: false},
+		{name: "--no-color", terminal: true, noColor: true, want: false},
+		{name: "NO_COLOR set", terminal: true, env: map[string]string{"NO_COLOR": "1"}, want: false},
+		{name: "NO_COLOR empty", terminal: true, env: map[string]string{"NO_COLOR": ""}, want: true},
+		{name: "dumb terminal", terminal: true, env: map[string]string{"TERM": "dumb"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("TERM", "xterm-256color")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if got := Enabled(tt.terminal, tt.noColor); got != tt.want {
+				t.Errorf("Enabled(%v, %v) = %v, want %v", tt.terminal, tt.noColor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPalette(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		input   string
+		want    string
+	}{
+		{name: "enabled", enabled: true, input: "#42", want: "\033[32m#42\033[0m"},
+		{name: "disabled", enabled: false, input: "#42", want: "#42"},
+		{name: "empty string", enabled: true, input: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.enabled).Green(tt.input); got != tt.want {
+				t.Errorf("Green(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,144 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+var (
+	// verbose enables printing each git command before it runs
+	verbose bool
+	// logWriter is where verbose output is written
+	logWriter io.Writer = os.Stderr
+)
+
+// SetVerbose enables or disables printing git commands to stderr before they run
+func SetVerbose(v bool) {
+	verbose = v
+}
+
+// cancelGracePeriod is how long a canceled git command may take to exit after
+// being interrupted before it is killed
+const cancelGracePeriod = 5 * time.Second
+
+// Command returns an exec.Cmd for git with the given arguments,
+// printing the command line first when verbose mode is enabled
+func Command(args ...string) *exec.Cmd {
+	return CommandContext(context.Background(), args...)
+}
+
+// CommandContext is like Command, but the command is interrupted when ctx is done.
+// git is sent an interrupt rather than killed so that it can remove its lock files.
+func CommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	if verbose {
+		fmt.Fprintf(logWriter, "+ git %s\n", strings.Join(args, " "))
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			// Interrupts aren't supported on Windows
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = cancelGracePeriod
+	return cmd
+}
+
+// Version returns the output of `git --version`, e.g. "git version 2.43.0"
+func Version() (string, error) {
+	output, err := Command("--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git version: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // Remote represents a git remote
 type Remote struct {
 	Name string
 	URL  string
+	// Repo is the repository URL points to, or nil if URL doesn't name one
+	// (e.g. a local path)
+	Repo *RepoID
+}
+
+// NewRemote returns a remote with its URL canonicalized into Repo
+func NewRemote(name, url string) *Remote {
+	remote := &Remote{Name: name, URL: url}
+	if repo, ok := ParseRemoteURL(url); ok {
+		remote.Repo = &repo
+	}
+	return remote
+}
+
+// RepoID identifies a repository by host, owner and name
+type RepoID struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+// Equal reports whether r and other are the same repository. Like GitHub,
+// the comparison is case-insensitive.
+func (r RepoID) Equal(other RepoID) bool {
+	return strings.EqualFold(r.Host, other.Host) &&
+		strings.EqualFold(r.Owner, other.Owner) &&
+		strings.EqualFold(r.Name, other.Name)
+}
+
+// ParseRemoteURL canonicalizes a remote URL such as https://github.com/o/r,
+// https://github.com/o/r.git/, ssh://git@github.com:22/o/r.git or the scp-like
+// git@github.com:o/r.git to its host, owner and repository name. The host is
+// lowercased and stripped of user and port. It returns false if the URL
+// doesn't name an owner/repo, e.g. for local paths.
+func ParseRemoteURL(rawURL string) (RepoID, bool) {
+	var host, path string
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return RepoID{}, false
+		}
+		host = u.Hostname()
+		path = u.Path
+	} else if i := strings.Index(rawURL, ":"); i > 0 && !strings.Contains(rawURL[:i], "/") {
+		// scp-like syntax: [user@]host:owner/repo
+		host = rawURL[:i]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		path = rawURL[i+1:]
+	} else {
+		return RepoID{}, false
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	parts := strings.Split(path, "/")
+	if host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return RepoID{}, false
+	}
+	return RepoID{Host: strings.ToLower(host), Owner: parts[0], Name: parts[1]}, true
 }
 
 // GetRemotes returns all configured git remotes
 func GetRemotes() ([]*Remote, error) {
-	cmd := exec.Command("git", "remote", "-v")
+	return GetRemotesContext(context.Background())
+}
+
+// GetRemotesContext is like GetRemotes but can be canceled through ctx
+func GetRemotesContext(ctx context.Context) ([]*Remote, error) {
+	cmd := CommandContext(ctx, "remote", "-v")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -33,7 +155,7 @@ func GetRemotes() ([]*Remote, error) {
 				name := parts[0]
 				url := parts[1]
 				if !seen[name] {
-					remotes = append(remotes, &Remote{Name: name, URL: url})
+					remotes = append(remotes, NewRemote(name, url))
 					seen[name] = true
 				}
 			}
@@ -43,35 +165,47 @@ func GetRemotes() ([]*Remote, error) {
 	return remotes, nil
 }
 
-// GetRoot returns the root directory of the main git repository
-func GetRoot() (string, error) {
-	// Get the main repository root by finding the git common directory
-	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
+// GetCommonDir returns the absolute path of the git common directory shared by all worktrees
+func GetCommonDir() (string, error) {
+	cmd := Command("rev-parse", "--git-common-dir")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get git common dir: %w", err)
 	}
 
 	gitCommonDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitCommonDir) {
+		// If it's a relative path, resolve it from current directory
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current directory: %w", err)
+		}
+		gitCommonDir = filepath.Join(currentDir, gitCommonDir)
+	}
 
-	// If it's an absolute path, get its parent
-	if filepath.IsAbs(gitCommonDir) {
-		return filepath.Dir(gitCommonDir), nil
+	// Resolve symlinks so the path matches what `git worktree list` reports,
+	// regardless of whether we're invoked from the main checkout or a linked worktree
+	if resolved, err := filepath.EvalSymlinks(gitCommonDir); err == nil {
+		gitCommonDir = resolved
 	}
 
-	// If it's a relative path, resolve it from current directory
-	currentDir, err := os.Getwd()
+	return gitCommonDir, nil
+}
+
+// GetRoot returns the root directory of the main git repository
+func GetRoot() (string, error) {
+	// Get the main repository root by finding the git common directory
+	gitCommonDir, err := GetCommonDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get current directory: %w", err)
+		return "", err
 	}
 
-	absGitDir := filepath.Join(currentDir, gitCommonDir)
-	return filepath.Dir(absGitDir), nil
+	return filepath.Dir(gitCommonDir), nil
 }
 
 // GetMainWorktree returns the path to the main worktree
 func GetMainWorktree() (string, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
+	cmd := Command("worktree", "list", "--porcelain")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to list worktrees: %w", err)
@@ -90,13 +224,64 @@ func GetMainWorktree() (string, error) {
 
 // BranchExists checks if a local branch exists
 func BranchExists(branchName string) bool {
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", fmt.Sprintf("refs/heads/%s", branchName))
+	return RefExists(fmt.Sprintf("refs/heads/%s", branchName))
+}
+
+// RefExists checks if a fully qualified ref such as refs/remotes/origin/main exists
+func RefExists(ref string) bool {
+	cmd := Command("show-ref", "--verify", "--quiet", ref)
 	return cmd.Run() == nil
 }
 
+// IsIgnored reports whether path (relative to the worktree at repoPath) is
+// ignored by git. Directories need a trailing slash to match directory patterns.
+func IsIgnored(repoPath, path string) bool {
+	return Command("-C", repoPath, "check-ignore", "-q", path).Run() == nil
+}
+
+// AddExclude adds pattern to the info/exclude file of the repository of the
+// worktree at worktreePath unless it is already there. The file is shared by
+// all worktrees of the repository.
+func AddExclude(worktreePath, pattern string) error {
+	output, err := Command("-C", worktreePath, "rev-parse", "--git-path", "info/exclude").Output()
+	if err != nil {
+		return fmt.Errorf("failed to locate info/exclude: %w", err)
+	}
+	excludePath := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(excludePath) {
+		excludePath = filepath.Join(worktreePath, excludePath)
+	}
+
+	content, err := os.ReadFile(excludePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", excludePath, err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		pattern = "\n" + pattern
+	}
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(excludePath), err)
+	}
+	f, err := os.OpenFile(excludePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", excludePath, err)
+	}
+	if _, err := f.WriteString(pattern + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", excludePath, err)
+	}
+	return f.Close()
+}
+
 // GetBranchName returns the current branch name at the given path
 func GetBranchName(worktreePath string) string {
-	cmd := exec.Command("git", "-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD")
+	cmd := Command("-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD")
 	output, err := cmd.Output()
 	if err != nil {
 		return ""
@@ -104,22 +289,107 @@ func GetBranchName(worktreePath string) string {
 	return strings.TrimSpace(string(output))
 }
 
-// ExecuteCommands runs a series of git commands
+// GetHeadCommit returns the commit SHA of HEAD at the given path
+func GetHeadCommit(worktreePath string) string {
+	cmd := Command("-C", worktreePath, "rev-parse", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// ResolveCommit returns the SHA of the commit ref points to
+func ResolveCommit(ref string) (string, error) {
+	output, err := Command("rev-parse", "--verify", "--quiet", ref+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetHeadCommitTime returns the committer date of HEAD in the worktree at worktreePath
+func GetHeadCommitTime(worktreePath string) (time.Time, error) {
+	output, err := Command("-C", worktreePath, "log", "-1", "--format=%ct").Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last commit of %s: %w", worktreePath, err)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit date of %s: %w", worktreePath, err)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// IsAncestor reports whether commit is an ancestor of (or equal to) ref
+func IsAncestor(commit, ref string) (bool, error) {
+	cmd := Command("merge-base", "--is-ancestor", commit, ref)
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+	// Exit status 1 means "not an ancestor"; anything else is a real failure
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check ancestry of %s: %w", commit, err)
+}
+
+// ExecuteCommands runs a series of git commands, stopping at the first failure.
+// The returned error is a *CommandError identifying the failed command.
 func ExecuteCommands(cmdQueue [][]string) error {
-	for _, args := range cmdQueue {
-		cmd := exec.Command("git", args...)
+	return ExecuteCommandsContext(context.Background(), cmdQueue)
+}
+
+// ExecuteCommandsContext is like ExecuteCommands, but stops when ctx is done,
+// interrupting the running command
+func ExecuteCommandsContext(ctx context.Context, cmdQueue [][]string) error {
+	for i, args := range cmdQueue {
+		if err := ctx.Err(); err != nil {
+			return &CommandError{Index: i, Args: args, Err: err}
+		}
+		cmd := CommandContext(ctx, args...)
 		// Don't output to stdout/stderr to avoid interfering with shell mode
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return fmt.Errorf("failed to execute git %s: %w (output: %s)", strings.Join(args, " "), err, string(output))
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				// Report the cancellation rather than the signal that stopped git
+				err = ctxErr
+			}
+			return &CommandError{Index: i, Args: args, Output: string(output), Err: err}
 		}
 	}
 	return nil
 }
 
-// GetConfig gets a git config value from a specific path
+// CommandError reports which command of a queue passed to ExecuteCommands failed
+type CommandError struct {
+	// Index is the position of the failed command in the queue
+	Index  int
+	Args   []string
+	Output string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("failed to execute git %s: %v (output: %s)", strings.Join(e.Args, " "), e.Err, e.Output)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// GetConfig gets a git config value from the repository config of path.
+// The repository config is shared by all worktrees; values set with
+// `git config --worktree` are not read.
 func GetConfig(path, key string) (string, error) {
-	cmd := exec.Command("git", "-C", path, "config", "--local", key)
+	return GetConfigContext(context.Background(), path, key)
+}
+
+// GetConfigContext is like GetConfig but can be canceled through ctx
+func GetConfigContext(ctx context.Context, path, key string) (string, error) {
+	cmd := CommandContext(ctx, "-C", path, "config", "--local", key)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -127,8 +397,118 @@ func GetConfig(path, key string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// SetConfig sets a git config value at a specific path
+// SetConfig sets a git config value in the repository config of path,
+// the same scope GetConfig reads
 func SetConfig(path, key, value string) error {
-	cmd := exec.Command("git", "-C", path, "config", key, value)
+	return SetConfigContext(context.Background(), path, key, value)
+}
+
+// SetConfigContext is like SetConfig but can be canceled through ctx
+func SetConfigContext(ctx context.Context, path, key, value string) error {
+	cmd := CommandContext(ctx, "-C", path, "config", "--local", key, value)
 	return cmd.Run()
 }
+
+// UnsetConfig removes a git config value at a specific path. A key that
+// isn't set is not an error.
+func UnsetConfig(path, key string) error {
+	err := Command("-C", path, "config", "--unset", key).Run()
+	// Exit status 5 means the key doesn't exist
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 5 {
+		return nil
+	}
+	return err
+}
+
+// GetConfigAll returns all values of a multi-valued key in the repository
+// config of path. A key that isn't set has no values.
+func GetConfigAll(path, key string) ([]string, error) {
+	output, err := Command("-C", path, "config", "--local", "--get-all", key).Output()
+	if err != nil {
+		// Exit status 1 means the key doesn't exist
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(output), "\n"), "\n"), nil
+}
+
+// AddConfig adds a value to a multi-valued key in the repository config of path
+func AddConfig(path, key, value string) error {
+	return Command("-C", path, "config", "--local", "--add", key, value).Run()
+}
+
+// UnsetConfigValue removes the given value of a multi-valued key in the
+// repository config of path. A value that isn't set is not an error.
+func UnsetConfigValue(path, key, value string) error {
+	err := Command("-C", path, "config", "--local", "--unset-all", key, "^"+regexp.QuoteMeta(value)+"$").Run()
+	// Exit status 5 means the key doesn't exist
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 5 {
+		return nil
+	}
+	return err
+}
+
+// ConfigEntry is a single key/value pair from git config
+type ConfigEntry struct {
+	Key   string
+	Value string
+}
+
+// WorktreeConfigEnabled reports whether extensions.worktreeConfig is set, i.e.
+// whether worktrees can have config that isn't shared with the other worktrees
+func WorktreeConfigEnabled(path string) bool {
+	value, err := GetConfig(path, "extensions.worktreeConfig")
+	return err == nil && value == "true"
+}
+
+// GetWorktreeConfigRegexp returns the entries of the worktree-specific config
+// (config.worktree) at path whose keys match the regular expression
+func GetWorktreeConfigRegexp(path, keyRegexp string) ([]ConfigEntry, error) {
+	entries, err := getConfigRegexp(path, "--worktree", keyRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worktree config: %w", err)
+	}
+	return entries, nil
+}
+
+// GetConfigRegexp returns the entries of the repository config of path, the
+// scope GetConfig reads, whose keys match the regular expression
+func GetConfigRegexp(path, keyRegexp string) ([]ConfigEntry, error) {
+	entries, err := getConfigRegexp(path, "--local", keyRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %w", err)
+	}
+	return entries, nil
+}
+
+// getConfigRegexp returns the entries of one config scope ("--local" or "--worktree")
+// whose keys match the regular expression
+func getConfigRegexp(path, scope, keyRegexp string) ([]ConfigEntry, error) {
+	output, err := Command("-C", path, "config", scope, "--null", "--get-regexp", keyRegexp).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			// No matching keys
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// With --null, each entry is "key\nvalue\x00"
+	var entries []ConfigEntry
+	for _, record := range strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00") {
+		key, value, _ := strings.Cut(record, "\n")
+		entries = append(entries, ConfigEntry{Key: key, Value: value})
+	}
+	return entries, nil
+}
+
+// AddWorktreeConfig adds a value to the worktree-specific config at path
+func AddWorktreeConfig(path, key, value string) error {
+	return ExecuteCommands([][]string{{"-C", path, "config", "--worktree", "--add", key, value}})
+}
@@ -1,7 +1,12 @@
 package git
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -41,6 +46,40 @@ func TestGetBranchName(t *testing.T) {
 	}
 }
 
+func TestGetHeadCommit(t *testing.T) {
+	// Skip if not in a git repository
+	if _, err := os.Stat(".git"); os.IsNotExist(err) {
+		t.Skip("Not in a git repository")
+	}
+
+	if commit := GetHeadCommit("."); len(commit) != 40 {
+		t.Errorf("GetHeadCommit(.) = %q, want 40-character SHA", commit)
+	}
+	if commit := GetHeadCommit("/non/existent/path"); commit != "" {
+		t.Errorf("GetHeadCommit(/non/existent/path) = %q, want empty", commit)
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	// Skip if not in a git repository
+	if _, err := os.Stat(".git"); os.IsNotExist(err) {
+		t.Skip("Not in a git repository")
+	}
+
+	head := GetHeadCommit(".")
+	ok, err := IsAncestor(head, "HEAD")
+	if err != nil {
+		t.Fatalf("IsAncestor(HEAD, HEAD) error = %v", err)
+	}
+	if !ok {
+		t.Error("IsAncestor(HEAD, HEAD) = false, want true")
+	}
+
+	if _, err := IsAncestor("0000000000000000000000000000000000000000", "HEAD"); err == nil {
+		t.Error("IsAncestor() with unknown commit expected error, got nil")
+	}
+}
+
 func TestBranchExists(t *testing.T) {
 	// Skip if not in a git repository
 	if _, err := os.Stat(".git"); os.IsNotExist(err) {
@@ -172,6 +211,43 @@ func TestExecuteCommands(t *testing.T) {
 	}
 }
 
+func TestExecuteCommands_CommandError(t *testing.T) {
+	err := ExecuteCommands([][]string{
+		{"--version"},
+		{"invalid-command"},
+		{"--version"},
+	})
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("ExecuteCommands() error = %v, want *CommandError", err)
+	}
+	if cmdErr.Index != 1 {
+		t.Errorf("CommandError.Index = %d, want 1", cmdErr.Index)
+	}
+	if len(cmdErr.Args) != 1 || cmdErr.Args[0] != "invalid-command" {
+		t.Errorf("CommandError.Args = %v, want [invalid-command]", cmdErr.Args)
+	}
+}
+
+func TestExecuteCommandsContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := ExecuteCommandsContext(ctx, [][]string{{"--version"}})
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("ExecuteCommandsContext() error = %v, want *CommandError", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecuteCommandsContext() error = %v, want context.Canceled", err)
+	}
+	if cmdErr.Index != 0 {
+		t.Errorf("CommandError.Index = %d, want 0", cmdErr.Index)
+	}
+}
+
 func TestGetMainWorktree(t *testing.T) {
 	// Skip if not in a git repository
 	if _, err := os.Stat(".git"); os.IsNotExist(err) {
@@ -197,3 +273,100 @@ func TestGetMainWorktree(t *testing.T) {
 		t.Error("GetMainWorktree() returned path is not a directory")
 	}
 }
+
+func TestGetCommonDir(t *testing.T) {
+	// Skip if not in a git repository
+	if _, err := os.Stat(".git"); os.IsNotExist(err) {
+		t.Skip("Not in a git repository")
+	}
+
+	commonDir, err := GetCommonDir()
+	if err != nil {
+		t.Fatalf("GetCommonDir() error = %v", err)
+	}
+
+	if !filepath.IsAbs(commonDir) {
+		t.Errorf("GetCommonDir() = %s, want absolute path", commonDir)
+	}
+
+	root, err := GetRoot()
+	if err != nil {
+		t.Fatalf("GetRoot() error = %v", err)
+	}
+	if filepath.Dir(commonDir) != root {
+		t.Errorf("GetCommonDir() = %s, want child of %s", commonDir, root)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	got, err := Version()
+	if err != nil {
+		t.Fatalf("Version() error = %v", err)
+	}
+	if !strings.HasPrefix(got, "git version ") {
+		t.Errorf("Version() = %q, want prefix %q", got, "git version ")
+	}
+}
+
+func TestCommand_Verbose(t *testing.T) {
+	var buf bytes.Buffer
+	origWriter := logWriter
+	logWriter = &buf
+	defer func() {
+		logWriter = origWriter
+		SetVerbose(false)
+	}()
+
+	Command("status", "--short")
+	if buf.Len() != 0 {
+		t.Errorf("Command() printed %q with verbose disabled", buf.String())
+	}
+
+	SetVerbose(true)
+	cmd := Command("fetch", "origin", "--no-tags")
+	if got, want := buf.String(), "+ git fetch origin --no-tags\n"; got != want {
+		t.Errorf("Command() printed %q, want %q", got, want)
+	}
+	if len(cmd.Args) != 4 || cmd.Args[1] != "fetch" {
+		t.Errorf("Command() args = %v", cmd.Args)
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	want := RepoID{Host: "github.com", Owner: "owner", Name: "repo"}
+	tests := []struct {
+		name   string
+		url    string
+		want   RepoID
+		wantOK bool
+	}{
+		{name: "HTTPS", url: "https://github.com/owner/repo", want: want, wantOK: true},
+		{name: "HTTPS with .git", url: "https://github.com/owner/repo.git", want: want, wantOK: true},
+		{name: "HTTPS with trailing slash", url: "https://github.com/owner/repo/", want: want, wantOK: true},
+		{name: "HTTPS with .git and trailing slash", url: "https://github.com/owner/repo.git/", want: want, wantOK: true},
+		{name: "HTTPS with user and uppercase host", url: "https://user@GitHub.com/owner/repo", want: want, wantOK: true},
+		{name: "SSH URL with port", url: "ssh://git@github.com:22/owner/repo.git", want: want, wantOK: true},
+		{name: "scp-like", url: "git@github.com:owner/repo.git", want: want, wantOK: true},
+		{name: "scp-like without user", url: "github.com:owner/repo", want: want, wantOK: true},
+		{
+			name:   "GitHub Enterprise",
+			url:    "https://ghe.example.com/team/tool.git",
+			want:   RepoID{Host: "ghe.example.com", Owner: "team", Name: "tool"},
+			wantOK: true,
+		},
+		{name: "local path", url: "/srv/git/repo.git", wantOK: false},
+		{name: "relative path", url: "../repo", wantOK: false},
+		{name: "file URL", url: "file:///srv/git/repo.git", wantOK: false},
+		{name: "missing repo", url: "https://github.com/owner", wantOK: false},
+		{name: "too many segments", url: "https://github.com/owner/repo/extra", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseRemoteURL(tt.url)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ParseRemoteURL(%q) = %+v, %v, want %+v, %v", tt.url, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
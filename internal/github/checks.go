@@ -0,0 +1,135 @@
+package github
+
+import (
+	"fmt"
+	"time"
+)
+
+// Getter is the subset of the REST client used to read the GitHub API
+type Getter interface {
+	Get(path string, response interface{}) error
+}
+
+// CheckState is the overall state of the checks for a commit
+type CheckState string
+
+const (
+	CheckPending CheckState = "pending"
+	CheckSuccess CheckState = "success"
+	CheckFailure CheckState = "failure"
+)
+
+// CheckSummary counts the check runs and commit statuses of a commit
+type CheckSummary struct {
+	Passed  int
+	Failed  int
+	Pending int
+}
+
+// State returns the overall state: failure if anything failed, pending if
+// anything is still running, success otherwise
+func (s CheckSummary) State() CheckState {
+	switch {
+	case s.Failed > 0:
+		return CheckFailure
+	case s.Pending > 0:
+		return CheckPending
+	default:
+		return CheckSuccess
+	}
+}
+
+// Total returns the number of checks and statuses
+func (s CheckSummary) Total() int {
+	return s.Passed + s.Failed + s.Pending
+}
+
+type checkRunsResponse struct {
+	CheckRuns []struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+	} `json:"check_runs"`
+}
+
+type combinedStatusResponse struct {
+	Statuses []struct {
+		Context string `json:"context"`
+		State   string `json:"state"`
+	} `json:"statuses"`
+}
+
+// GetCheckSummary fetches the check runs and commit statuses for a commit
+func GetCheckSummary(client Getter, owner, repo, sha string) (CheckSummary, error) {
+	var summary CheckSummary
+
+	var runs checkRunsResponse
+	if err := client.Get(fmt.Sprintf("repos/%s/%s/commits/%s/check-runs?per_page=100", owner, repo, sha), &runs); err != nil {
+		return summary, fmt.Errorf("failed to get check runs: %w", err)
+	}
+	for _, run := range runs.CheckRuns {
+		if run.Status != "completed" {
+			summary.Pending++
+			continue
+		}
+		switch run.Conclusion {
+		case "success", "neutral", "skipped":
+			summary.Passed++
+		default:
+			// failure, cancelled, timed_out, action_required, stale, startup_failure
+			summary.Failed++
+		}
+	}
+
+	var status combinedStatusResponse
+	if err := client.Get(fmt.Sprintf("repos/%s/%s/commits/%s/status", owner, repo, sha), &status); err != nil {
+		return summary, fmt.Errorf("failed to get commit status: %w", err)
+	}
+	for _, s := range status.Statuses {
+		switch s.State {
+		case "success":
+			summary.Passed++
+		case "pending":
+			summary.Pending++
+		default:
+			// failure, error
+			summary.Failed++
+		}
+	}
+
+	return summary, nil
+}
+
+// Poll intervals for WaitForChecks. The interval doubles after each poll up to the maximum.
+var (
+	initialPollInterval = 10 * time.Second
+	maxPollInterval     = time.Minute
+)
+
+// WaitForChecks polls the checks of a commit until they all complete or timeout elapses.
+// progress is called with each summary. On timeout the last summary is returned with
+// a pending state and no error.
+func WaitForChecks(client Getter, owner, repo, sha string, timeout time.Duration, progress func(CheckSummary)) (CheckSummary, error) {
+	deadline := time.Now().Add(timeout)
+	interval := initialPollInterval
+
+	for {
+		summary, err := GetCheckSummary(client, owner, repo, sha)
+		if err != nil {
+			return summary, err
+		}
+		if progress != nil {
+			progress(summary)
+		}
+		if summary.State() != CheckPending {
+			return summary, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return summary, nil
+		}
+		time.Sleep(min(interval, remaining))
+		interval = min(interval*2, maxPollInterval)
+	}
+}
@@ -0,0 +1,124 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGetter returns canned JSON responses. Each path serves its responses in order,
+// repeating the last one.
+type fakeGetter struct {
+	responses map[string][]string
+	calls     map[string]int
+}
+
+func (f *fakeGetter) Get(path string, response interface{}) error {
+	for prefix, bodies := range f.responses {
+		if !strings.HasSuffix(strings.SplitN(path, "?", 2)[0], prefix) {
+			continue
+		}
+		i := min(f.calls[prefix], len(bodies)-1)
+		f.calls[prefix]++
+		return json.Unmarshal([]byte(bodies[i]), response)
+	}
+	return fmt.Errorf("unexpected path %s", path)
+}
+
+func newFakeGetter(checkRuns, statuses []string) *fakeGetter {
+	return &fakeGetter{
+		responses: map[string][]string{
+			"/check-runs": checkRuns,
+			"/status":     statuses,
+		},
+		calls: map[string]int{},
+	}
+}
+
+func TestGetCheckSummary(t *testing.T) {
+	client := newFakeGetter(
+		[]string{`{"check_runs": [
+			{"name": "build", "status": "completed", "conclusion": "success"},
+			{"name": "lint", "status": "completed", "conclusion": "skipped"},
+			{"name": "test", "status": "in_progress", "conclusion": null},
+			{"name": "e2e", "status": "completed", "conclusion": "timed_out"}
+		]}`},
+		[]string{`{"statuses": [
+			{"context": "ci/legacy", "state": "success"},
+			{"context": "deploy", "state": "pending"}
+		]}`},
+	)
+
+	got, err := GetCheckSummary(client, "owner", "repo", "abc123")
+	if err != nil {
+		t.Fatalf("GetCheckSummary() error = %v", err)
+	}
+	want := CheckSummary{Passed: 3, Failed: 1, Pending: 2}
+	if got != want {
+		t.Errorf("GetCheckSummary() = %+v, want %+v", got, want)
+	}
+	if got.State() != CheckFailure {
+		t.Errorf("State() = %s, want %s", got.State(), CheckFailure)
+	}
+}
+
+func TestCheckSummary_State(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary CheckSummary
+		want    CheckState
+	}{
+		{name: "all passed", summary: CheckSummary{Passed: 2}, want: CheckSuccess},
+		{name: "no checks", summary: CheckSummary{}, want: CheckSuccess},
+		{name: "pending", summary: CheckSummary{Passed: 1, Pending: 1}, want: CheckPending},
+		{name: "failure wins over pending", summary: CheckSummary{Failed: 1, Pending: 1}, want: CheckFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.summary.State(); got != tt.want {
+				t.Errorf("State() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForChecks(t *testing.T) {
+	origInitial, origMax := initialPollInterval, maxPollInterval
+	initialPollInterval, maxPollInterval = time.Millisecond, 2*time.Millisecond
+	defer func() {
+		initialPollInterval, maxPollInterval = origInitial, origMax
+	}()
+
+	pending := `{"check_runs": [{"name": "build", "status": "queued"}]}`
+	passed := `{"check_runs": [{"name": "build", "status": "completed", "conclusion": "success"}]}`
+	noStatuses := `{"statuses": []}`
+
+	t.Run("completes", func(t *testing.T) {
+		client := newFakeGetter([]string{pending, pending, passed}, []string{noStatuses})
+		var polls int
+		got, err := WaitForChecks(client, "owner", "repo", "abc123", time.Minute, func(CheckSummary) { polls++ })
+		if err != nil {
+			t.Fatalf("WaitForChecks() error = %v", err)
+		}
+		if got.State() != CheckSuccess {
+			t.Errorf("WaitForChecks() state = %s, want %s", got.State(), CheckSuccess)
+		}
+		if polls != 3 {
+			t.Errorf("WaitForChecks() polled %d times, want 3", polls)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		client := newFakeGetter([]string{pending}, []string{noStatuses})
+		got, err := WaitForChecks(client, "owner", "repo", "abc123", 5*time.Millisecond, nil)
+		if err != nil {
+			t.Fatalf("WaitForChecks() error = %v", err)
+		}
+		if got.State() != CheckPending {
+			t.Errorf("WaitForChecks() state = %s, want %s", got.State(), CheckPending)
+		}
+	})
+}
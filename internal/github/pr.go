@@ -2,16 +2,30 @@ package github
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/knqyf263/gh-worktree/internal/validate"
 )
 
+// User represents a GitHub user
+type User struct {
+	Login string `json:"login"`
+}
+
+// Label represents a GitHub issue or pull request label
+type Label struct {
+	Name string `json:"name"`
+}
+
 // PullRequest represents a GitHub pull request
 type PullRequest struct {
 	Number int    `json:"number"`
 	Title  string `json:"title"`
+	Body   string `json:"body"`
 	Head   struct {
 		Ref  string `json:"ref"`
 		Repo struct {
@@ -22,29 +36,122 @@ type PullRequest struct {
 		} `json:"repo"`
 	} `json:"head"`
 	Base struct {
+		Ref  string `json:"ref"`
 		Repo struct {
 			FullName string `json:"full_name"`
 		} `json:"repo"`
 	} `json:"base"`
-	MaintainerCanModify bool `json:"maintainer_can_modify"`
+	MaintainerCanModify bool    `json:"maintainer_can_modify"`
+	User                User    `json:"user"`
+	Assignees           []User  `json:"assignees"`
+	RequestedReviewers  []User  `json:"requested_reviewers"`
+	Labels              []Label `json:"labels"`
+	// MergedAt is empty unless the PR is merged
+	MergedAt string `json:"merged_at"`
+	HTMLURL  string `json:"html_url"`
+}
+
+// IsMerged reports whether the PR has been merged
+func (pr *PullRequest) IsMerged() bool {
+	return pr.MergedAt != ""
+}
+
+// PrimaryLabel returns the name of the PR's first label, or "" if it has none
+func (pr *PullRequest) PrimaryLabel() string {
+	if len(pr.Labels) == 0 {
+		return ""
+	}
+	return pr.Labels[0].Name
+}
+
+// MergedPRs returns the numbers of the given PRs that have been merged.
+// PRs are fetched one by one, so the number of requests grows with len(numbers).
+func MergedPRs(client Getter, owner, repo string, numbers []int) ([]int, error) {
+	var merged []int
+	for _, n := range numbers {
+		var pr PullRequest
+		if err := client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", owner, repo, n), &pr); err != nil {
+			return nil, fmt.Errorf("failed to get PR #%d: %w", n, err)
+		}
+		if pr.IsMerged() {
+			merged = append(merged, n)
+		}
+	}
+	return merged, nil
+}
+
+// ListOptions are the query parameters for listing PRs with the pulls API
+type ListOptions struct {
+	State string
+	// PerPage is the number of PRs fetched; 0 means the maximum of 100
+	PerPage int
+	// Sort is one of created, updated, popularity or long-running; empty for the API default
+	Sort string
+	// Direction is asc or desc; empty for the API default
+	Direction string
+}
+
+// maxPerPage is the largest page size the pulls API accepts
+const maxPerPage = 100
+
+// Query returns the query string for `repos/{owner}/{repo}/pulls`.
+// Invalid values fall back to the defaults; a warning is returned for each.
+func (o ListOptions) Query() (string, []string) {
+	var warnings []string
+	query := url.Values{}
+	query.Set("state", o.State)
+
+	perPage := o.PerPage
+	if perPage < 0 || perPage > maxPerPage {
+		warnings = append(warnings, fmt.Sprintf("list.per_page must be between 1 and %d, using %d", maxPerPage, maxPerPage))
+		perPage = 0
+	}
+	if perPage == 0 {
+		perPage = maxPerPage
+	}
+	query.Set("per_page", strconv.Itoa(perPage))
+
+	switch o.Sort {
+	case "":
+	case "created", "updated", "popularity", "long-running":
+		query.Set("sort", o.Sort)
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid list.sort %q (must be created, updated, popularity, or long-running), using the default", o.Sort))
+	}
+
+	switch o.Direction {
+	case "":
+	case "asc", "desc":
+		query.Set("direction", o.Direction)
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid list.direction %q (must be asc or desc), using the default", o.Direction))
+	}
+
+	return query.Encode(), warnings
 }
 
 // ParsePRNumber parses a PR number from a string selector
 // Accepts either direct number (e.g. "123") or GitHub URL format
 func ParsePRNumber(selector string) (int, error) {
-	// Handle URL format: https://github.com/OWNER/REPO/pull/NUMBER
+	// Handle URL format: https://github.com/OWNER/REPO/pull/NUMBER, optionally
+	// followed by a tab such as /files, a query or a #discussion_r... fragment
 	if strings.Contains(selector, "/pull/") {
 		// Validate URL first
 		if err := validate.URL(selector); err != nil {
 			return 0, fmt.Errorf("invalid URL: %w", err)
 		}
+		parsedURL, err := url.Parse(selector)
+		if err != nil {
+			return 0, fmt.Errorf("invalid URL: %w", err)
+		}
 
-		parts := strings.Split(selector, "/pull/")
+		parts := strings.Split(parsedURL.Path, "/pull/")
 		if len(parts) != 2 {
 			return 0, fmt.Errorf("invalid PR URL format")
 		}
+		numberPart, _, _ := strings.Cut(parts[1], "/")
 
-		prNumber, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		prNumber, err := strconv.Atoi(numberPart)
 		if err != nil {
 			return 0, fmt.Errorf("invalid PR number in URL: %w", err)
 		}
@@ -69,6 +176,17 @@ func ParsePRNumber(selector string) (int, error) {
 	return prNumber, nil
 }
 
+// ParseCandidateLine parses a PR number from a line in the format produced by
+// FormatPRCandidate or `gh worktree pr list` (e.g. "#123\tbranch\towner/repo").
+// A line containing only a PR number or URL is also accepted.
+func ParseCandidateLine(line string) (int, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty selector")
+	}
+	return ParsePRNumber(strings.TrimPrefix(fields[0], "#"))
+}
+
 // FormatPRCandidate formats a PR for display in selection list
 func FormatPRCandidate(pr *PullRequest) string {
 	return fmt.Sprintf("#%d\t%s\t%s",
@@ -76,3 +194,131 @@ func FormatPRCandidate(pr *PullRequest) string {
 		pr.Head.Ref,
 		pr.Head.Repo.Owner.Login+"/"+pr.Head.Repo.Name)
 }
+
+var (
+	// terminalEscapes matches ANSI CSI and OSC escape sequences
+	terminalEscapes = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)?`)
+	htmlComments    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	excessBlankRows = regexp.MustCompile(`\n{3,}`)
+)
+
+// FormatDescription formats the PR's title, author and body for display in a
+// terminal. Escape sequences and other control characters are removed so that
+// the body can't alter the terminal, as are HTML comments left over from PR
+// templates.
+func FormatDescription(pr *PullRequest) string {
+	body := sanitizeForTerminal(htmlComments.ReplaceAllString(pr.Body, ""))
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	body = strings.TrimSpace(excessBlankRows.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+	if body == "" {
+		body = "No description provided."
+	}
+
+	header := fmt.Sprintf("#%d %s", pr.Number, sanitizeForTerminal(pr.Title))
+	if pr.User.Login != "" {
+		header += fmt.Sprintf(" (@%s)", pr.User.Login)
+	}
+	return header + "\n\n" + body + "\n"
+}
+
+// sanitizeForTerminal normalizes line endings and removes escape sequences and
+// control characters other than newlines and tabs
+func sanitizeForTerminal(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = terminalEscapes.ReplaceAllString(s, "")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// maxTitleSlugLength limits the title part of branch names derived from PR titles
+const maxTitleSlugLength = 50
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// TitleBranchName derives a local branch name from the PR title, e.g.
+// "Fix login redirect (#12)" for PR 1234 becomes "fix-login-redirect-12-1234".
+// The PR number is appended so that PRs with similar titles don't collide.
+func TitleBranchName(pr *PullRequest) string {
+	slug := strings.ToLower(validate.SanitizeForGitConfig(pr.Title))
+	slug = strings.Trim(nonSlugChars.ReplaceAllString(slug, "-"), "-")
+	if len(slug) > maxTitleSlugLength {
+		slug = strings.TrimRight(slug[:maxTitleSlugLength], "-")
+	}
+	if slug == "" {
+		return fmt.Sprintf("pr-%d", pr.Number)
+	}
+	return fmt.Sprintf("%s-%d", slug, pr.Number)
+}
+
+// FilterPRs returns the PRs authored by author and assigned to assignee.
+// An empty filter matches all PRs. Logins are compared case-insensitively.
+func FilterPRs(prs []PullRequest, author, assignee string) []PullRequest {
+	var filtered []PullRequest
+	for _, pr := range prs {
+		if author != "" && !strings.EqualFold(pr.User.Login, author) {
+			continue
+		}
+		if assignee != "" && !isAssignedTo(&pr, assignee) {
+			continue
+		}
+		filtered = append(filtered, pr)
+	}
+	return filtered
+}
+
+func isAssignedTo(pr *PullRequest, login string) bool {
+	return containsLogin(pr.Assignees, login)
+}
+
+// FilterReviewRequested returns the PRs where login is a requested reviewer
+func FilterReviewRequested(prs []PullRequest, login string) []PullRequest {
+	var filtered []PullRequest
+	for _, pr := range prs {
+		if pr.IsReviewRequested(login) {
+			filtered = append(filtered, pr)
+		}
+	}
+	return filtered
+}
+
+// IsReviewRequested reports whether login is a requested reviewer of the PR.
+// Requests to teams are not included in requested_reviewers and are not considered.
+func (pr *PullRequest) IsReviewRequested(login string) bool {
+	return login != "" && containsLogin(pr.RequestedReviewers, login)
+}
+
+func containsLogin(users []User, login string) bool {
+	for _, u := range users {
+		if strings.EqualFold(u.Login, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatFilteredPRCandidate formats a PR for display in selection list,
+// appending the author and assignee that matched the active filters
+// and a marker when viewer is a requested reviewer.
+func FormatFilteredPRCandidate(pr *PullRequest, author, assignee, viewer string) string {
+	candidate := FormatPRCandidate(pr)
+	if author != "" {
+		candidate += "\tauthor:@" + pr.User.Login
+	}
+	if assignee != "" {
+		candidate += "\tassignee:@" + assignee
+	}
+	if pr.IsReviewRequested(viewer) {
+		candidate += "\treview requested"
+	}
+	return candidate
+}
@@ -1,7 +1,11 @@
 package github
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/knqyf263/gh-worktree/internal/validate"
 )
 
 func TestParsePRNumber(t *testing.T) {
@@ -89,6 +93,54 @@ func TestParsePRNumber(t *testing.T) {
 			want:     0,
 			wantErr:  true,
 		},
+		{
+			name:     "files tab URL",
+			selector: "https://github.com/owner/repo/pull/42/files",
+			want:     42,
+			wantErr:  false,
+		},
+		{
+			name:     "commits tab URL",
+			selector: "https://github.com/owner/repo/pull/42/commits",
+			want:     42,
+			wantErr:  false,
+		},
+		{
+			name:     "URL with trailing slash",
+			selector: "https://github.com/owner/repo/pull/42/",
+			want:     42,
+			wantErr:  false,
+		},
+		{
+			name:     "URL with discussion fragment",
+			selector: "https://github.com/owner/repo/pull/42#discussion_r123456",
+			want:     42,
+			wantErr:  false,
+		},
+		{
+			name:     "files tab URL with query and fragment",
+			selector: "https://github.com/owner/repo/pull/42/files?w=1#diff-abc",
+			want:     42,
+			wantErr:  false,
+		},
+		{
+			name:     "non-HTTPS URL with trailing segments",
+			selector: "http://github.com/owner/repo/pull/42/files",
+			want:     0,
+			wantErr:  true,
+		},
+		{
+			name:     "URL with credentials and fragment",
+			selector: "https://user@github.com/owner/repo/pull/42#issuecomment-1",
+			want:     0,
+			wantErr:  true,
+		},
+		{
+			name:     "URL with several pull segments",
+			selector: "https://github.com/owner/repo/pull/42/pull/43",
+			want:     0,
+			wantErr:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -142,3 +194,362 @@ func TestFormatPRCandidate(t *testing.T) {
 		t.Errorf("FormatPRCandidate() = %q, want %q", result, expected)
 	}
 }
+
+func TestParseCandidateLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "candidate line",
+			line: "#123\tfeature-branch\towner/repo",
+			want: 123,
+		},
+		{
+			name: "list output line with indentation",
+			line: "  #456\tfix-bug\t3f2a9c1d\tFix bug\t../repo-pr456\n",
+			want: 456,
+		},
+		{
+			name: "plain number",
+			line: "789\n",
+			want: 789,
+		},
+		{
+			name: "URL",
+			line: "https://github.com/owner/repo/pull/42",
+			want: 42,
+		},
+		{
+			name:    "empty line",
+			line:    "  \n",
+			wantErr: true,
+		},
+		{
+			name:    "branch worktree line",
+			line:    "feature-auth\t(local development)",
+			wantErr: true,
+		},
+		{
+			name:    "invalid PR number",
+			line:    "#0\tbranch",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCandidateLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCandidateLine(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseCandidateLine(%q) = %d, want %d", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterPRs(t *testing.T) {
+	prs := []PullRequest{
+		{Number: 1, User: User{Login: "alice"}, Assignees: []User{{Login: "bob"}}},
+		{Number: 2, User: User{Login: "bob"}, Assignees: []User{{Login: "alice"}, {Login: "carol"}}},
+		{Number: 3, User: User{Login: "Alice"}},
+	}
+
+	tests := []struct {
+		name     string
+		author   string
+		assignee string
+		want     []int
+	}{
+		{
+			name: "no filters",
+			want: []int{1, 2, 3},
+		},
+		{
+			name:   "author case-insensitive",
+			author: "alice",
+			want:   []int{1, 3},
+		},
+		{
+			name:     "assignee among several",
+			assignee: "carol",
+			want:     []int{2},
+		},
+		{
+			name:     "author and assignee",
+			author:   "alice",
+			assignee: "bob",
+			want:     []int{1},
+		},
+		{
+			name:   "no match",
+			author: "dave",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterPRs(prs, tt.author, tt.assignee)
+			var gotNumbers []int
+			for _, pr := range got {
+				gotNumbers = append(gotNumbers, pr.Number)
+			}
+			if len(gotNumbers) != len(tt.want) {
+				t.Fatalf("FilterPRs() = %v, want %v", gotNumbers, tt.want)
+			}
+			for i := range tt.want {
+				if gotNumbers[i] != tt.want[i] {
+					t.Errorf("FilterPRs() = %v, want %v", gotNumbers, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatFilteredPRCandidate(t *testing.T) {
+	pr := &PullRequest{Number: 123, User: User{Login: "alice"}, RequestedReviewers: []User{{Login: "Carol"}}}
+	pr.Head.Ref = "feature-branch"
+	pr.Head.Repo.Name = "test-repo"
+	pr.Head.Repo.Owner.Login = "test-owner"
+
+	tests := []struct {
+		name     string
+		author   string
+		assignee string
+		viewer   string
+		want     string
+	}{
+		{
+			name: "no filters",
+			want: "#123\tfeature-branch\ttest-owner/test-repo",
+		},
+		{
+			name:   "author filter",
+			author: "alice",
+			want:   "#123\tfeature-branch\ttest-owner/test-repo\tauthor:@alice",
+		},
+		{
+			name:     "assignee filter",
+			assignee: "bob",
+			want:     "#123\tfeature-branch\ttest-owner/test-repo\tassignee:@bob",
+		},
+		{
+			name:   "viewer is a requested reviewer",
+			viewer: "carol",
+			want:   "#123\tfeature-branch\ttest-owner/test-repo\treview requested",
+		},
+		{
+			name:   "viewer is not a requested reviewer",
+			viewer: "alice",
+			want:   "#123\tfeature-branch\ttest-owner/test-repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatFilteredPRCandidate(pr, tt.author, tt.assignee, tt.viewer)
+			if got != tt.want {
+				t.Errorf("FormatFilteredPRCandidate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterReviewRequested(t *testing.T) {
+	prs := []PullRequest{
+		{Number: 1, RequestedReviewers: []User{{Login: "alice"}}},
+		{Number: 2},
+		{Number: 3, RequestedReviewers: []User{{Login: "bob"}, {Login: "Alice"}}},
+	}
+
+	tests := []struct {
+		name  string
+		login string
+		want  []int
+	}{
+		{
+			name:  "requested on several PRs",
+			login: "alice",
+			want:  []int{1, 3},
+		},
+		{
+			name:  "not requested",
+			login: "carol",
+			want:  nil,
+		},
+		{
+			name:  "empty login",
+			login: "",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, pr := range FilterReviewRequested(prs, tt.login) {
+				got = append(got, pr.Number)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("FilterReviewRequested() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("FilterReviewRequested() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTitleBranchName(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{
+			name:  "simple title",
+			title: "Add new feature",
+			want:  "add-new-feature-1234",
+		},
+		{
+			name:  "punctuation and shell characters",
+			title: "Fix: `rm -rf` in $HOME (really!)",
+			want:  "fix-rm-rf-in-home-really-1234",
+		},
+		{
+			name:  "non-ASCII characters",
+			title: "Ünïcode title ✨",
+			want:  "n-code-title-1234",
+		},
+		{
+			name:  "long title is truncated",
+			title: strings.Repeat("word ", 20),
+			want:  "word-word-word-word-word-word-word-word-word-word-1234",
+		},
+		{
+			name:  "no usable characters",
+			title: "✨✨✨",
+			want:  "pr-1234",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := &PullRequest{Number: 1234, Title: tt.title}
+			got := TitleBranchName(pr)
+			if got != tt.want {
+				t.Errorf("TitleBranchName(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+			if err := validate.BranchName(got); err != nil {
+				t.Errorf("TitleBranchName(%q) = %q is not a valid branch name: %v", tt.title, got, err)
+			}
+		})
+	}
+}
+
+func TestFormatDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "plain body",
+			body: "Fixes the login redirect.\r\n\r\nCloses #12",
+			want: "#1234 Fix login (@alice)\n\nFixes the login redirect.\n\nCloses #12\n",
+		},
+		{
+			name: "empty body",
+			body: "  \n",
+			want: "#1234 Fix login (@alice)\n\nNo description provided.\n",
+		},
+		{
+			name: "escape sequences and control characters",
+			body: "\x1b[31mred\x1b[0m \x1b]8;;https://example.com\x07link\x1b]8;;\x07\x07\b",
+			want: "#1234 Fix login (@alice)\n\nred link\n",
+		},
+		{
+			name: "template comments and blank lines",
+			body: "<!-- Describe\nyour change -->\nSummary   \n\n\n\n\tDetails",
+			want: "#1234 Fix login (@alice)\n\nSummary\n\n\tDetails\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := &PullRequest{Number: 1234, Title: "Fix login", Body: tt.body, User: User{Login: "alice"}}
+			if got := FormatDescription(pr); got != tt.want {
+				t.Errorf("FormatDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergedPRs(t *testing.T) {
+	client := &fakeGetter{
+		responses: map[string][]string{
+			"/pulls/1": {`{"number": 1, "merged_at": "2024-05-01T10:00:00Z"}`},
+			"/pulls/2": {`{"number": 2, "merged_at": null}`},
+			"/pulls/3": {`{"number": 3, "merged_at": "2024-05-02T10:00:00Z"}`},
+		},
+		calls: map[string]int{},
+	}
+
+	got, err := MergedPRs(client, "owner", "repo", []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("MergedPRs() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("MergedPRs() = %v, want [1 3]", got)
+	}
+
+	if _, err := MergedPRs(client, "owner", "repo", []int{4}); err == nil {
+		t.Error("MergedPRs() expected error for unknown PR")
+	}
+}
+
+func TestListOptions_Query(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         ListOptions
+		want         string
+		wantWarnings int
+	}{
+		{
+			name: "defaults",
+			opts: ListOptions{State: "open"},
+			want: "per_page=100&state=open",
+		},
+		{
+			name: "configured",
+			opts: ListOptions{State: "all", PerPage: 30, Sort: "updated", Direction: "asc"},
+			want: "direction=asc&per_page=30&sort=updated&state=all",
+		},
+		{
+			name:         "invalid values fall back",
+			opts:         ListOptions{State: "open", PerPage: 500, Sort: "stars", Direction: "up"},
+			want:         "per_page=100&state=open",
+			wantWarnings: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := tt.opts.Query()
+			if got != tt.want {
+				t.Errorf("Query() = %q, want %q", got, tt.want)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Query() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
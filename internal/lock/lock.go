@@ -0,0 +1,68 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// retryInterval is how often a held lock is polled
+const retryInterval = 100 * time.Millisecond
+
+// errLocked is returned by tryLock when another process holds the lock
+var errLocked = errors.New("lock is held")
+
+// Lock represents an acquired lock file
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes an exclusive lock on the file at path, waiting up to timeout for another holder to release it.
+// The lock is held by the operating system on the open file, so it is released when the holder exits,
+// even if it is killed, and a left-over lock file never blocks later callers.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(f)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLocked) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out waiting for lock %s; another gh worktree command may be running", path)
+		}
+		time.Sleep(retryInterval)
+	}
+
+	// The PID is informational only, to help users identify the holder
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release unlocks the lock file. The file itself is left in place so that
+// waiters never lock a file that has been replaced. It is safe to call more than once.
+func (l *Lock) Release() error {
+	if l.f == nil {
+		return nil
+	}
+	f := l.f
+	l.f = nil
+	if err := unlock(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to unlock lock file: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,85 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	l, err := Acquire(path, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Acquire() did not create lock file: %v", err)
+	}
+
+	// A second acquire should time out while the lock is held
+	if _, err := Acquire(path, 200*time.Millisecond); err == nil {
+		t.Error("Acquire() on held lock expected error, got nil")
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Release() removed lock file: %v", err)
+	}
+
+	// Releasing twice is a no-op
+	if err := l.Release(); err != nil {
+		t.Errorf("second Release() error = %v", err)
+	}
+
+	// The lock can be acquired again after release
+	l, err = Acquire(path, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	l.Release()
+}
+
+func TestAcquire_WaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	l, err := Acquire(path, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		l.Release()
+	}()
+
+	l2, err := Acquire(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() waiting for release error = %v", err)
+	}
+	l2.Release()
+}
+
+func TestAcquire_LeftOverLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	// A lock file left behind by an earlier run doesn't block
+	if err := os.WriteFile(path, []byte("12345"), 0644); err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+	l, err := Acquire(path, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Acquire() with left-over lock file error = %v", err)
+	}
+
+	// Closing the file without releasing, as happens when the holder is killed, frees the lock
+	l.f.Close()
+	l2, err := Acquire(path, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Acquire() after holder exited error = %v", err)
+	}
+	l2.Release()
+}
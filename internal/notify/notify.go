@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Send shows a desktop notification.
+// It is best-effort: if no notifier is available or it fails, the notification is silently skipped.
+func Send(title, message string) {
+	cmd := command(runtime.GOOS, title, message)
+	if cmd == nil {
+		return
+	}
+	_ = cmd.Run()
+}
+
+// command builds the notifier command for the given OS, or returns nil if the notifier isn't installed
+func command(goos, title, message string) *exec.Cmd {
+	var name string
+	var args []string
+
+	switch goos {
+	case "darwin":
+		name = "osascript"
+		args = []string{"-e", fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		name = "notify-send"
+		args = []string{title, message}
+	case "windows":
+		name = "powershell"
+		args = []string{"-NoProfile", "-NonInteractive", "-Command", toastScript(title, message)}
+	default:
+		return nil
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil
+	}
+	return exec.Command(path, args...)
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// powerShellString quotes s as a PowerShell single-quoted string literal
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// toastScript returns a PowerShell script that shows a Windows toast notification
+func toastScript(title, message string) string {
+	return strings.Join([]string{
+		"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null",
+		"$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)",
+		"$text = $template.GetElementsByTagName('text')",
+		fmt.Sprintf("$text.Item(0).AppendChild($template.CreateTextNode(%s)) | Out-Null", powerShellString(title)),
+		fmt.Sprintf("$text.Item(1).AppendChild($template.CreateTextNode(%s)) | Out-Null", powerShellString(message)),
+		"$toast = [Windows.UI.Notifications.ToastNotification]::new($template)",
+		"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('gh-worktree').Show($toast)",
+	}, "; ")
+}
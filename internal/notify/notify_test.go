@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppleScriptString(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain text",
+			input: "Checkout completed",
+			want:  `"Checkout completed"`,
+		},
+		{
+			name:  "double quotes",
+			input: `Title: "fix"`,
+			want:  `"Title: \"fix\""`,
+		},
+		{
+			name:  "backslash",
+			input: `a\b`,
+			want:  `"a\\b"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appleScriptString(tt.input)
+			if got != tt.want {
+				t.Errorf("appleScriptString(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPowerShellString(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain text",
+			input: "Checkout completed",
+			want:  "'Checkout completed'",
+		},
+		{
+			name:  "single quotes",
+			input: "it's done",
+			want:  "'it''s done'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := powerShellString(tt.input)
+			if got != tt.want {
+				t.Errorf("powerShellString(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToastScript(t *testing.T) {
+	script := toastScript("gh worktree", "it's ready")
+	if !strings.Contains(script, "CreateTextNode('gh worktree')") {
+		t.Errorf("toastScript() missing title: %s", script)
+	}
+	if !strings.Contains(script, "CreateTextNode('it''s ready')") {
+		t.Errorf("toastScript() missing escaped message: %s", script)
+	}
+}
+
+func TestCommand_UnsupportedOS(t *testing.T) {
+	if cmd := command("plan9", "title", "message"); cmd != nil {
+		t.Errorf("command() on unsupported OS = %v, want nil", cmd)
+	}
+}
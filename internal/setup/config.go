@@ -4,38 +4,322 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// configFileName is the repository-level configuration file name.
+// It is only looked up at the root of the main worktree, not in subdirectories.
+const configFileName = ".gh-worktree.yml"
+
 // Config represents the .gh-worktree.yml configuration
 type Config struct {
-	Setup SetupConfig `yaml:"setup"`
+	Setup        SetupConfig        `yaml:"setup"`
+	Teardown     TeardownConfig     `yaml:"teardown"`
+	MirrorConfig MirrorConfigConfig `yaml:"mirror_config"`
+	Worktree     WorktreeConfig     `yaml:"worktree"`
+	// AutoPrune offers to remove worktrees of merged PRs when listing or switching
+	AutoPrune *bool         `yaml:"auto_prune"`
+	List      ListConfig    `yaml:"list"`
+	Hooks     HooksConfig   `yaml:"hooks"`
+	Remotes   RemotesConfig `yaml:"remotes"`
+	Switch    SwitchConfig  `yaml:"switch"`
+	Fetch     FetchConfig   `yaml:"fetch"`
+	// PullRefTemplate is the ref PRs are fetched from when their head
+	// repository has no remote, with a {number} placeholder
+	// (default: refs/pull/{number}/head)
+	PullRefTemplate string `yaml:"pull_ref_template"`
+	// RepoName is the repository name used in worktree names instead of the
+	// main worktree's directory name, as with --assume-repo-name
+	RepoName string `yaml:"repo_name"`
+}
+
+// RemotesConfig designates the remotes PRs are fetched from and pushed to,
+// e.g. when origin is a read-only mirror
+type RemotesConfig struct {
+	// Fetch is the remote PRs are fetched from (default: upstream, origin, or the first remote)
+	Fetch string `yaml:"fetch"`
+	// Push is set as the pushRemote of same-repository PR branches (default: the branch's remote)
+	Push string `yaml:"push"`
+}
+
+// FetchConfig controls how PRs are fetched
+type FetchConfig struct {
+	// Tags fetches the tags pointing into the fetched history, as with
+	// --tags. Unset means false, i.e. git fetch --no-tags.
+	Tags *bool `yaml:"tags"`
+}
+
+// Path styles for SwitchConfig.AbsolutePaths
+const (
+	// AbsolutePathsMain prints the main worktree's path as an absolute path
+	AbsolutePathsMain = "main"
+	// AbsolutePathsAlways prints every path as an absolute path
+	AbsolutePathsAlways = "always"
+)
+
+// SwitchConfig controls the paths printed by switch, which are relative to
+// the current directory by default
+type SwitchConfig struct {
+	AbsolutePaths string `yaml:"absolute_paths"`
+}
+
+// Absolute reports whether switch prints an absolute path for its target,
+// where isMain tells whether the target is the main worktree
+func (s SwitchConfig) Absolute(isMain bool) (bool, error) {
+	switch s.AbsolutePaths {
+	case "":
+		return false, nil
+	case AbsolutePathsMain:
+		return isMain, nil
+	case AbsolutePathsAlways:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid switch.absolute_paths %q (must be %s or %s)", s.AbsolutePaths, AbsolutePathsMain, AbsolutePathsAlways)
+	}
+}
+
+// ListConfig controls which PRs interactive checkout fetches and in which order
+type ListConfig struct {
+	PerPage   int    `yaml:"per_page"`
+	Sort      string `yaml:"sort"`
+	Direction string `yaml:"direction"`
+}
+
+// Worktree locations for WorktreeConfig.Location
+const (
+	// LocationSibling places worktrees next to the main worktree (the default)
+	LocationSibling = "sibling"
+	// LocationNested places worktrees in a worktrees/ directory inside the main worktree
+	LocationNested = "nested"
+)
+
+// WorktreeConfig controls where worktrees are created and how they are named
+type WorktreeConfig struct {
+	Location string `yaml:"location"`
+	// NameFrom is the default for --name-from
+	NameFrom string `yaml:"name_from"`
+	// PRBranchNaming is the default for --pr-branch-naming
+	PRBranchNaming string `yaml:"pr_branch_naming"`
+	// LabelDir groups PR worktrees in subdirectories named after their
+	// primary label, as with --label-dir
+	LabelDir *bool `yaml:"label_dir"`
+	// CheckDiskSpace checks for enough free space before creating a PR
+	// worktree, as with --check-disk-space
+	CheckDiskSpace *bool `yaml:"check_disk_space"`
+	// Max is the number of PR and branch worktrees beyond which checkout
+	// refuses to create more without --force, as with --max-worktrees.
+	// 0 means no limit.
+	Max int `yaml:"max"`
+	// Annotate writes an untracked .gh-worktree-info.md describing the PR
+	// into new PR worktrees, as with --annotate
+	Annotate *bool `yaml:"annotate"`
+	// NoMetadata doesn't record gh-worktree-* keys in the git config of new
+	// worktrees' branches, as with --no-metadata
+	NoMetadata *bool `yaml:"no_metadata"`
+}
+
+// Nested reports whether worktrees are created inside the main worktree
+func (w WorktreeConfig) Nested() (bool, error) {
+	switch w.Location {
+	case "", LocationSibling:
+		return false, nil
+	case LocationNested:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid worktree.location %q (must be %s or %s)", w.Location, LocationSibling, LocationNested)
+	}
 }
 
 // SetupConfig contains post-creation setup commands
 type SetupConfig struct {
 	Run []string `yaml:"run"`
+	// Bootstrap is a script in the repository, e.g. ./script/setup, that runs
+	// in every new worktree that contains it before the setup commands
+	Bootstrap string `yaml:"bootstrap"`
+	// Link lists directories of the main worktree, e.g. node_modules, that
+	// are symlinked into new worktrees instead of being installed again
+	Link []string `yaml:"link"`
+	// Profiles are named alternatives to Run, selected with --setup-profile
+	Profiles map[string]ProfileConfig `yaml:"profiles"`
+}
+
+// ProfileConfig contains the commands of a named setup profile
+type ProfileConfig struct {
+	Run []string `yaml:"run"`
+}
+
+// Commands returns the setup commands for the given profile.
+// An empty profile selects the top-level run commands.
+func (s *SetupConfig) Commands(profile string) ([]string, error) {
+	if profile == "" {
+		return s.Run, nil
+	}
+
+	p, ok := s.Profiles[profile]
+	if !ok {
+		var names []string
+		for name := range s.Profiles {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("setup profile %q not found: no profiles are configured", profile)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("setup profile %q not found (available: %s)", profile, strings.Join(names, ", "))
+	}
+	return p.Run, nil
+}
+
+// MirrorConfigConfig lists the git config keys copied from the main worktree with --mirror-config
+type MirrorConfigConfig struct {
+	Keys []string `yaml:"keys"`
+}
+
+// TeardownConfig contains pre-removal teardown commands
+type TeardownConfig struct {
+	Run []string `yaml:"run"`
 }
 
-// LoadConfig loads the .gh-worktree.yml configuration from the main worktree
+// merge appends the settings from other after the receiver's settings.
+// Single values such as worktree.location and booleans set in other are
+// overridden instead.
+func (c *Config) merge(other *Config) {
+	c.Setup.Run = append(c.Setup.Run, other.Setup.Run...)
+	for name, profile := range other.Setup.Profiles {
+		if c.Setup.Profiles == nil {
+			c.Setup.Profiles = make(map[string]ProfileConfig)
+		}
+		merged := c.Setup.Profiles[name]
+		merged.Run = append(merged.Run, profile.Run...)
+		c.Setup.Profiles[name] = merged
+	}
+	if other.Setup.Bootstrap != "" {
+		c.Setup.Bootstrap = other.Setup.Bootstrap
+	}
+	c.Teardown.Run = append(c.Teardown.Run, other.Teardown.Run...)
+	c.Setup.Link = append(c.Setup.Link, other.Setup.Link...)
+	c.MirrorConfig.Keys = append(c.MirrorConfig.Keys, other.MirrorConfig.Keys...)
+	if other.Worktree.Location != "" {
+		c.Worktree.Location = other.Worktree.Location
+	}
+	if other.Worktree.NameFrom != "" {
+		c.Worktree.NameFrom = other.Worktree.NameFrom
+	}
+	if other.Worktree.PRBranchNaming != "" {
+		c.Worktree.PRBranchNaming = other.Worktree.PRBranchNaming
+	}
+	if other.Worktree.LabelDir != nil {
+		c.Worktree.LabelDir = other.Worktree.LabelDir
+	}
+	if other.Worktree.CheckDiskSpace != nil {
+		c.Worktree.CheckDiskSpace = other.Worktree.CheckDiskSpace
+	}
+	if other.Worktree.Annotate != nil {
+		c.Worktree.Annotate = other.Worktree.Annotate
+	}
+	if other.Worktree.NoMetadata != nil {
+		c.Worktree.NoMetadata = other.Worktree.NoMetadata
+	}
+	if other.Worktree.Max != 0 {
+		c.Worktree.Max = other.Worktree.Max
+	}
+	if other.AutoPrune != nil {
+		c.AutoPrune = other.AutoPrune
+	}
+	if other.Remotes.Fetch != "" {
+		c.Remotes.Fetch = other.Remotes.Fetch
+	}
+	if other.Remotes.Push != "" {
+		c.Remotes.Push = other.Remotes.Push
+	}
+	if other.Fetch.Tags != nil {
+		c.Fetch.Tags = other.Fetch.Tags
+	}
+	if other.PullRefTemplate != "" {
+		c.PullRefTemplate = other.PullRefTemplate
+	}
+	if other.RepoName != "" {
+		c.RepoName = other.RepoName
+	}
+	if other.Switch.AbsolutePaths != "" {
+		c.Switch.AbsolutePaths = other.Switch.AbsolutePaths
+	}
+	if other.List.PerPage != 0 {
+		c.List.PerPage = other.List.PerPage
+	}
+	if other.List.Sort != "" {
+		c.List.Sort = other.List.Sort
+	}
+	if other.List.Direction != "" {
+		c.List.Direction = other.List.Direction
+	}
+	if other.Hooks.PostCreate != "" {
+		c.Hooks.PostCreate = other.Hooks.PostCreate
+	}
+}
+
+// GlobalConfigPath returns the path of the user-level configuration file.
+// It honors XDG_CONFIG_HOME and falls back to ~/.config/gh-worktree/config.yml.
+func GlobalConfigPath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "gh-worktree", "config.yml"), nil
+}
+
+// LoadConfig loads the configuration for the main worktree.
+// The global config is loaded first and the repository's .gh-worktree.yml
+// at the root of the main worktree is merged on top of it, so global setup
+// commands run before repository ones.
 func LoadConfig(mainWorktreePath string) (*Config, error) {
-	configPath := filepath.Join(mainWorktreePath, ".gh-worktree.yml")
+	config := &Config{}
+
+	globalPath, err := GlobalConfigPath()
+	if err == nil {
+		globalConfig, err := readConfigFile(globalPath)
+		if err != nil {
+			return nil, err
+		}
+		if globalConfig != nil {
+			config.merge(globalConfig)
+		}
+	}
+
+	repoConfig, err := readConfigFile(filepath.Join(mainWorktreePath, configFileName))
+	if err != nil {
+		return nil, err
+	}
+	if repoConfig != nil {
+		config.merge(repoConfig)
+	}
+
+	return config, nil
+}
 
+// readConfigFile reads a single config file. It returns nil if the file doesn't exist.
+func readConfigFile(configPath string) (*Config, error) {
 	// Check if config file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// No config file is not an error, just return empty config
-		return &Config{}, nil
+		// No config file is not an error
+		return nil, nil
 	}
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
 
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
 
 	return &config, nil
@@ -3,6 +3,7 @@ package setup
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -104,3 +105,376 @@ func TestLoadConfig_InvalidYAML(t *testing.T) {
 		t.Error("LoadConfig() expected error for invalid YAML, got nil")
 	}
 }
+
+func TestGlobalConfigPath(t *testing.T) {
+	t.Run("XDG_CONFIG_HOME set", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+		got, err := GlobalConfigPath()
+		if err != nil {
+			t.Fatalf("GlobalConfigPath() error = %v", err)
+		}
+		want := filepath.Join("/tmp/xdg", "gh-worktree", "config.yml")
+		if got != want {
+			t.Errorf("GlobalConfigPath() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("XDG_CONFIG_HOME unset", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", "")
+		home, err := os.UserHomeDir()
+		if err != nil {
+			t.Skip("home directory not available")
+		}
+		got, err := GlobalConfigPath()
+		if err != nil {
+			t.Fatalf("GlobalConfigPath() error = %v", err)
+		}
+		want := filepath.Join(home, ".config", "gh-worktree", "config.yml")
+		if got != want {
+			t.Errorf("GlobalConfigPath() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestLoadConfig_MergesGlobalConfig(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	globalDir := filepath.Join(configHome, "gh-worktree")
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatalf("failed to create global config dir: %v", err)
+	}
+	globalYAML := `setup:
+  run:
+    - echo global`
+	if err := os.WriteFile(filepath.Join(globalDir, "config.yml"), []byte(globalYAML), 0644); err != nil {
+		t.Fatalf("failed to write global config: %v", err)
+	}
+
+	repoDir := t.TempDir()
+	repoYAML := `setup:
+  run:
+    - echo repo`
+	if err := os.WriteFile(filepath.Join(repoDir, ".gh-worktree.yml"), []byte(repoYAML), 0644); err != nil {
+		t.Fatalf("failed to write repo config: %v", err)
+	}
+
+	config, err := LoadConfig(repoDir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	want := []string{"echo global", "echo repo"}
+	if len(config.Setup.Run) != len(want) {
+		t.Fatalf("LoadConfig() got %d run commands, want %d", len(config.Setup.Run), len(want))
+	}
+	for i := range want {
+		if config.Setup.Run[i] != want[i] {
+			t.Errorf("LoadConfig() run[%d] = %q, want %q", i, config.Setup.Run[i], want[i])
+		}
+	}
+}
+
+func TestLoadConfig_InvalidGlobalYAML(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	globalDir := filepath.Join(configHome, "gh-worktree")
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatalf("failed to create global config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(globalDir, "config.yml"), []byte("setup: [invalid"), 0644); err != nil {
+		t.Fatalf("failed to write global config: %v", err)
+	}
+
+	_, err := LoadConfig(t.TempDir())
+	if err == nil {
+		t.Error("LoadConfig() expected error for invalid global YAML, got nil")
+	}
+}
+
+func TestSetupConfig_Commands(t *testing.T) {
+	repoDir := t.TempDir()
+	repoYAML := `setup:
+  run:
+    - npm install
+  profiles:
+    frontend:
+      run:
+        - npm install
+        - npm run build
+    backend:
+      run:
+        - go mod download`
+	if err := os.WriteFile(filepath.Join(repoDir, ".gh-worktree.yml"), []byte(repoYAML), 0644); err != nil {
+		t.Fatalf("failed to write repo config: %v", err)
+	}
+
+	config, err := LoadConfig(repoDir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "default",
+			profile: "",
+			want:    []string{"npm install"},
+		},
+		{
+			name:    "named profile",
+			profile: "backend",
+			want:    []string{"go mod download"},
+		},
+		{
+			name:    "unknown profile",
+			profile: "mobile",
+			wantErr: `setup profile "mobile" not found (available: backend, frontend)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config.Setup.Commands(tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Commands(%q) error = %v, want %q", tt.profile, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Commands(%q) error = %v", tt.profile, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Commands(%q) = %v, want %v", tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_MergesGlobalProfiles(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	globalDir := filepath.Join(configHome, "gh-worktree")
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatalf("failed to create global config dir: %v", err)
+	}
+	globalYAML := `setup:
+  profiles:
+    frontend:
+      run:
+        - echo global`
+	if err := os.WriteFile(filepath.Join(globalDir, "config.yml"), []byte(globalYAML), 0644); err != nil {
+		t.Fatalf("failed to write global config: %v", err)
+	}
+
+	repoDir := t.TempDir()
+	repoYAML := `setup:
+  profiles:
+    frontend:
+      run:
+        - echo repo`
+	if err := os.WriteFile(filepath.Join(repoDir, ".gh-worktree.yml"), []byte(repoYAML), 0644); err != nil {
+		t.Fatalf("failed to write repo config: %v", err)
+	}
+
+	config, err := LoadConfig(repoDir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	got, err := config.Setup.Commands("frontend")
+	if err != nil {
+		t.Fatalf("Commands(frontend) error = %v", err)
+	}
+	want := []string{"echo global", "echo repo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Commands(frontend) = %v, want %v", got, want)
+	}
+}
+
+func TestSwitchConfig_Absolute(t *testing.T) {
+	tests := []struct {
+		absolutePaths string
+		isMain        bool
+		want          bool
+		wantErr       bool
+	}{
+		{absolutePaths: "", isMain: true, want: false},
+		{absolutePaths: "main", isMain: true, want: true},
+		{absolutePaths: "main", isMain: false, want: false},
+		{absolutePaths: "always", isMain: false, want: true},
+		{absolutePaths: "never", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := SwitchConfig{AbsolutePaths: tt.absolutePaths}.Absolute(tt.isMain)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Absolute(%v) with %q error = %v, wantErr %v", tt.isMain, tt.absolutePaths, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("Absolute(%v) with %q = %v, want %v", tt.isMain, tt.absolutePaths, got, tt.want)
+		}
+	}
+}
+
+func TestWorktreeConfig_Nested(t *testing.T) {
+	tests := []struct {
+		location string
+		want     bool
+		wantErr  bool
+	}{
+		{location: "", want: false},
+		{location: "sibling", want: false},
+		{location: "nested", want: true},
+		{location: "inside", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			got, err := WorktreeConfig{Location: tt.location}.Nested()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Nested() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Nested() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_WorktreeMax(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	globalDir := filepath.Join(configHome, "gh-worktree")
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatalf("failed to create global config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(globalDir, "config.yml"), []byte("worktree:\n  max: 20\n"), 0644); err != nil {
+		t.Fatalf("failed to write global config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		repoYAML string
+		want     int
+	}{
+		{name: "global limit", repoYAML: "setup:\n  run: []\n", want: 20},
+		{name: "repository overrides global", repoYAML: "worktree:\n  max: 5\n", want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(repoDir, ".gh-worktree.yml"), []byte(tt.repoYAML), 0644); err != nil {
+				t.Fatalf("failed to write repo config: %v", err)
+			}
+
+			config, err := LoadConfig(repoDir)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if config.Worktree.Max != tt.want {
+				t.Errorf("LoadConfig() worktree.max = %d, want %d", config.Worktree.Max, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_FetchTags(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	globalDir := filepath.Join(configHome, "gh-worktree")
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatalf("failed to create global config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(globalDir, "config.yml"), []byte("fetch:\n  tags: true\n"), 0644); err != nil {
+		t.Fatalf("failed to write global config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		repoYAML string
+		want     bool
+	}{
+		{name: "global setting", repoYAML: "setup:\n  run: []\n", want: true},
+		{name: "repository overrides global", repoYAML: "fetch:\n  tags: false\n", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(repoDir, ".gh-worktree.yml"), []byte(tt.repoYAML), 0644); err != nil {
+				t.Fatalf("failed to write repo config: %v", err)
+			}
+
+			config, err := LoadConfig(repoDir)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if config.Fetch.Tags == nil || *config.Fetch.Tags != tt.want {
+				t.Errorf("LoadConfig() fetch.tags = %v, want %v", config.Fetch.Tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Booleans(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	globalDir := filepath.Join(configHome, "gh-worktree")
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatalf("failed to create global config dir: %v", err)
+	}
+	globalYAML := "auto_prune: true\nworktree:\n  label_dir: true\n  check_disk_space: true\n  annotate: true\n  no_metadata: true\n"
+	if err := os.WriteFile(filepath.Join(globalDir, "config.yml"), []byte(globalYAML), 0644); err != nil {
+		t.Fatalf("failed to write global config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		repoYAML string
+		want     bool
+	}{
+		{name: "global setting", repoYAML: "setup:\n  run: []\n", want: true},
+		{
+			name:     "repository overrides global",
+			repoYAML: "auto_prune: false\nworktree:\n  label_dir: false\n  check_disk_space: false\n  annotate: false\n  no_metadata: false\n",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(repoDir, ".gh-worktree.yml"), []byte(tt.repoYAML), 0644); err != nil {
+				t.Fatalf("failed to write repo config: %v", err)
+			}
+
+			config, err := LoadConfig(repoDir)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			for key, value := range map[string]*bool{
+				"auto_prune":                config.AutoPrune,
+				"worktree.label_dir":        config.Worktree.LabelDir,
+				"worktree.check_disk_space": config.Worktree.CheckDiskSpace,
+				"worktree.annotate":         config.Worktree.Annotate,
+				"worktree.no_metadata":      config.Worktree.NoMetadata,
+			} {
+				if value == nil || *value != tt.want {
+					t.Errorf("LoadConfig() %s = %v, want %v", key, value, tt.want)
+				}
+			}
+		})
+	}
+}
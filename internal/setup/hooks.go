@@ -0,0 +1,64 @@
+package setup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// HooksConfig contains commands that are notified of events with a JSON payload on stdin
+type HooksConfig struct {
+	PostCreate string `yaml:"post_create"`
+}
+
+// PostCreateEvent is the JSON payload passed to the hooks.post_create command.
+// Fields are only ever added to it, so hooks can rely on the existing ones.
+type PostCreateEvent struct {
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+	// PRNumber is omitted for branch worktrees
+	PRNumber int `json:"prNumber,omitempty"`
+	// Type is "pr" or "branch"
+	Type string `json:"type"`
+}
+
+// RunPostCreateHook runs the configured hooks.post_create command in the new
+// worktree with the event as JSON on stdin. Like setup commands, a failure
+// is returned as a warning rather than an error.
+func RunPostCreateHook(mainWorktreePath string, event PostCreateEvent) []string {
+	config, err := LoadConfig(mainWorktreePath)
+	if err != nil {
+		return hookWarning("failed to load config: %v", err)
+	}
+	if config.Hooks.PostCreate == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return hookWarning("failed to encode post_create payload: %v", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "→ Running post_create hook...")
+
+	cmd := exec.Command("sh", "-c", config.Hooks.PostCreate)
+	cmd.Dir = event.Path
+	cmd.Env = append(os.Environ(), fmt.Sprintf("GH_WORKTREE_MAIN_DIR=%s", mainWorktreePath))
+	cmd.Stdin = bytes.NewReader(payload)
+	// Keep stdout clean for shell mode
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return hookWarning("post_create hook failed (exit %d): %s", cmd.ProcessState.ExitCode(), config.Hooks.PostCreate)
+	}
+	return nil
+}
+
+func hookWarning(format string, args ...any) []string {
+	warning := fmt.Sprintf(format, args...)
+	fmt.Fprintf(os.Stderr, "  ⚠ %s\n", warning)
+	return []string{warning}
+}
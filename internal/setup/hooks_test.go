@@ -0,0 +1,79 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunPostCreateHook(t *testing.T) {
+	tests := []struct {
+		name         string
+		configYAML   string
+		event        PostCreateEvent
+		wantPayload  string
+		wantWarnings int
+	}{
+		{
+			name: "PR worktree",
+			configYAML: `hooks:
+  post_create: cat > payload.json`,
+			event:       PostCreateEvent{Branch: "feature", PRNumber: 42, Type: "pr"},
+			wantPayload: `{"path":"%s","branch":"feature","prNumber":42,"type":"pr"}`,
+		},
+		{
+			name: "branch worktree omits prNumber",
+			configYAML: `hooks:
+  post_create: cat > payload.json`,
+			event:       PostCreateEvent{Branch: "feature", Type: "branch"},
+			wantPayload: `{"path":"%s","branch":"feature","type":"branch"}`,
+		},
+		{
+			name: "failing hook is a warning",
+			configYAML: `hooks:
+  post_create: exit 3`,
+			event:        PostCreateEvent{Branch: "feature", Type: "branch"},
+			wantWarnings: 1,
+		},
+		{
+			name:       "no hook configured",
+			configYAML: `setup:`,
+			event:      PostCreateEvent{Branch: "feature", Type: "branch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mainDir := t.TempDir()
+			newDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte(tt.configYAML), 0644); err != nil {
+				t.Fatalf("failed to write test config: %v", err)
+			}
+
+			tt.event.Path = newDir
+			warnings := RunPostCreateHook(mainDir, tt.event)
+			if len(warnings) != tt.wantWarnings {
+				t.Fatalf("RunPostCreateHook() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+
+			data, err := os.ReadFile(filepath.Join(newDir, "payload.json"))
+			if tt.wantPayload == "" {
+				if err == nil {
+					t.Errorf("hook should not have written a payload, got %s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("hook did not run in the new worktree: %v", err)
+			}
+			path, _ := json.Marshal(newDir)
+			want := fmt.Sprintf(tt.wantPayload, strings.Trim(string(path), `"`))
+			if string(data) != want {
+				t.Errorf("payload = %s, want %s", data, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/validate"
+)
+
+// NodeModulesDir is the directory linked with --link-node-modules
+const NodeModulesDir = "node_modules"
+
+// LinkDirs symlinks directories of the main worktree into the new worktree
+// instead of installing them again: extra, and the setup.link directories
+// unless useConfig is false. Each link is added to info/exclude so that it is
+// never committed. Directories missing from the main worktree or already
+// present in the new one are skipped with a warning.
+func LinkDirs(newWorktreePath, mainWorktreePath string, extra []string, useConfig bool) ([]string, error) {
+	var dirs []string
+	if useConfig {
+		config, err := LoadConfig(mainWorktreePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		for _, dir := range config.Setup.Link {
+			if err := validate.LinkPath(dir); err != nil {
+				return nil, fmt.Errorf("invalid setup.link: %w", err)
+			}
+		}
+		dirs = append(dirs, config.Setup.Link...)
+	}
+	dirs = append(dirs, extra...)
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	fmt.Fprintln(os.Stderr, "→ Linking directories from the main worktree...")
+	fmt.Fprintln(os.Stderr, "  Linked directories are shared, so this only works if their contents suit every branch")
+
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warning := fmt.Sprintf(format, args...)
+		warnings = append(warnings, warning)
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", warning)
+	}
+	linked := map[string]bool{}
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if linked[dir] {
+			continue
+		}
+		linked[dir] = true
+
+		source := filepath.Join(mainWorktreePath, dir)
+		if info, err := os.Stat(source); err != nil || !info.IsDir() {
+			warn("Skipping link: %s is not a directory in the main worktree", dir)
+			continue
+		}
+		target := filepath.Join(newWorktreePath, dir)
+		if _, err := os.Lstat(target); err == nil {
+			warn("Skipping link: %s already exists in the new worktree", dir)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			warn("Failed to link %s: %v", dir, err)
+			continue
+		}
+		// Directory patterns such as node_modules/ in .gitignore don't match
+		// symlinks, so the link itself is excluded
+		if err := git.AddExclude(newWorktreePath, "/"+filepath.ToSlash(dir)); err != nil {
+			warn("Failed to link %s: %v", dir, err)
+			continue
+		}
+		if err := os.Symlink(source, target); err != nil {
+			warn("Failed to link %s: %v", dir, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  ✓ %s → %s\n", dir, source)
+	}
+	return warnings, nil
+}
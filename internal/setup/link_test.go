@@ -0,0 +1,98 @@
+package setup
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLinkDirs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	mainDir := filepath.Join(dir, "repo")
+	newDir := filepath.Join(dir, "repo-pr42")
+
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	gitRun("init", "-q", mainDir)
+	gitRun("-C", mainDir, "commit", "-q", "--allow-empty", "-m", "initial")
+	gitRun("-C", mainDir, "worktree", "add", "-q", "-b", "pr-branch", newDir)
+
+	for _, d := range []string{"node_modules", "vendor/bundle", "cache"} {
+		if err := os.MkdirAll(filepath.Join(mainDir, d), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", d, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(newDir, "cache"), 0755); err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	configYAML := "setup:\n  link:\n    - vendor/bundle\n    - cache\n    - missing\n"
+	if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	gitRun("-C", mainDir, "add", ".gh-worktree.yml")
+	gitRun("-C", mainDir, "commit", "-q", "-m", "config")
+
+	warnings, err := LinkDirs(newDir, mainDir, []string{NodeModulesDir, "vendor/bundle"}, true)
+	if err != nil {
+		t.Fatalf("LinkDirs() error = %v", err)
+	}
+	wantWarnings := []string{
+		"Skipping link: cache already exists in the new worktree",
+		"Skipping link: missing is not a directory in the main worktree",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("LinkDirs() warnings = %q, want %q", warnings, wantWarnings)
+	}
+
+	for _, d := range []string{"node_modules", "vendor/bundle"} {
+		got, err := os.Readlink(filepath.Join(newDir, d))
+		if err != nil {
+			t.Errorf("%s is not a link: %v", d, err)
+			continue
+		}
+		if want := filepath.Join(mainDir, d); got != want {
+			t.Errorf("%s links to %s, want %s", d, got, want)
+		}
+	}
+
+	status, err := exec.Command("git", "-C", newDir, "status", "--porcelain", "--untracked-files=all").Output()
+	if err != nil {
+		t.Fatalf("git status failed: %v", err)
+	}
+	if len(status) != 0 {
+		t.Errorf("git status = %q, want the links to be excluded", status)
+	}
+
+	// Without the config only the extra directories are linked
+	otherDir := filepath.Join(dir, "repo-feature")
+	gitRun("-C", mainDir, "worktree", "add", "-q", "-b", "feature", otherDir)
+	if _, err := LinkDirs(otherDir, mainDir, nil, false); err != nil {
+		t.Fatalf("LinkDirs() error = %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(otherDir, "vendor/bundle")); !os.IsNotExist(err) {
+		t.Errorf("LinkDirs() without config linked vendor/bundle")
+	}
+}
+
+func TestLinkDirs_InvalidConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	mainDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte("setup:\n  link:\n    - ../shared\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	if _, err := LinkDirs(t.TempDir(), mainDir, nil, true); err == nil {
+		t.Error("LinkDirs() expected error for a link outside the worktree")
+	}
+}
@@ -0,0 +1,19 @@
+package setup
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Isolate tests from the user's global config
+	configHome, err := os.MkdirTemp("", "gh-worktree-config")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", configHome)
+
+	code := m.Run()
+	os.RemoveAll(configHome)
+	os.Exit(code)
+}
@@ -0,0 +1,184 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/validate"
+)
+
+// MirrorConfig copies the worktree-specific git config of the main worktree
+// to the new worktree for the keys listed under mirror_config.keys.
+// Config in .git/config is already shared by all worktrees; only config.worktree
+// (with extensions.worktreeConfig enabled) needs to be copied.
+// Keys that fail to copy are returned as warnings.
+func MirrorConfig(newWorktreePath, mainWorktreePath string) ([]string, error) {
+	config, err := LoadConfig(mainWorktreePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(config.MirrorConfig.Keys) == 0 {
+		return nil, fmt.Errorf("--mirror-config requires mirror_config.keys in the configuration")
+	}
+	for _, pattern := range config.MirrorConfig.Keys {
+		if err := validate.ConfigKeyPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	if !git.WorktreeConfigEnabled(mainWorktreePath) {
+		fmt.Fprintln(os.Stderr, "  (git config is shared by all worktrees; nothing to mirror)")
+		return nil, nil
+	}
+
+	fmt.Fprintln(os.Stderr, "→ Mirroring git config from the main worktree...")
+
+	var warnings []string
+	for _, pattern := range config.MirrorConfig.Keys {
+		entries, err := git.GetWorktreeConfigRegexp(mainWorktreePath, configKeyRegexp(pattern))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Failed to read %s: %v", pattern, err))
+			continue
+		}
+		for _, entry := range entries {
+			if err := git.AddWorktreeConfig(newWorktreePath, entry.Key, entry.Value); err != nil {
+				warning := fmt.Sprintf("Failed to set %s: %v", entry.Key, err)
+				warnings = append(warnings, warning)
+				fmt.Fprintf(os.Stderr, "  ⚠ %s\n", warning)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "  ✓ %s\n", entry.Key)
+		}
+	}
+
+	return warnings, nil
+}
+
+// MirrorConfigFrom copies the git config of another worktree to the new worktree
+// for the keys listed under mirror_config.keys.
+// Patterns in the branch section ("branch.*" or "branch.<name>") copy the source
+// branch's settings to newBranch; other keys are copied from the source worktree's
+// config.worktree. Tracking settings, gh-worktree metadata and credentials are never copied.
+// Keys that fail to copy are returned as warnings.
+func MirrorConfigFrom(newWorktreePath, newBranch, sourceWorktreePath, sourceBranch, mainWorktreePath string) ([]string, error) {
+	config, err := LoadConfig(mainWorktreePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(config.MirrorConfig.Keys) == 0 {
+		return nil, fmt.Errorf("--config-from requires mirror_config.keys in the configuration")
+	}
+	for _, pattern := range config.MirrorConfig.Keys {
+		if err := validate.ConfigKeyPattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "→ Copying git config from %s...\n", sourceWorktreePath)
+
+	worktreeConfig := git.WorktreeConfigEnabled(mainWorktreePath)
+	var warnings []string
+	warn := func(warning string) {
+		warnings = append(warnings, warning)
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", warning)
+	}
+	for _, pattern := range config.MirrorConfig.Keys {
+		section, variable, _ := strings.Cut(pattern, ".")
+		if strings.EqualFold(section, "branch") && !strings.Contains(variable, ".") {
+			// Per-branch settings live in the shared config under the branch's name
+			if sourceBranch == "" || newBranch == "" || sourceBranch == newBranch {
+				continue
+			}
+			entries, err := git.GetConfigRegexp(sourceWorktreePath, configKeyRegexp("branch."+sourceBranch+"."+variable))
+			if err != nil {
+				warn(fmt.Sprintf("Failed to read %s: %v", pattern, err))
+				continue
+			}
+			for _, entry := range entries {
+				name := entry.Key[strings.LastIndex(entry.Key, ".")+1:]
+				if trackingConfigKey(name) || sensitiveConfigKey(entry.Key) {
+					continue
+				}
+				key := "branch." + newBranch + "." + name
+				if err := git.SetConfig(newWorktreePath, key, entry.Value); err != nil {
+					warn(fmt.Sprintf("Failed to set %s: %v", key, err))
+					continue
+				}
+				fmt.Fprintf(os.Stderr, "  ✓ %s\n", key)
+			}
+			continue
+		}
+
+		// Other config in .git/config is already shared by all worktrees
+		if !worktreeConfig {
+			continue
+		}
+		entries, err := git.GetWorktreeConfigRegexp(sourceWorktreePath, configKeyRegexp(pattern))
+		if err != nil {
+			warn(fmt.Sprintf("Failed to read %s: %v", pattern, err))
+			continue
+		}
+		for _, entry := range entries {
+			if sensitiveConfigKey(entry.Key) {
+				continue
+			}
+			if err := git.AddWorktreeConfig(newWorktreePath, entry.Key, entry.Value); err != nil {
+				warn(fmt.Sprintf("Failed to set %s: %v", entry.Key, err))
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "  ✓ %s\n", entry.Key)
+		}
+	}
+
+	return warnings, nil
+}
+
+// trackingConfigKey reports whether name is a branch variable that is specific
+// to the branch itself: its upstream, push target, or gh-worktree metadata
+func trackingConfigKey(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "remote", "merge", "pushremote":
+		return true
+	}
+	return strings.HasPrefix(name, "gh-worktree-")
+}
+
+// sensitiveConfigKey reports whether key may hold credentials
+func sensitiveConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasPrefix(key, "credential.") {
+		return true
+	}
+	for _, word := range []string{"password", "token", "secret", "extraheader", "cookie"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// configKeyRegexp converts a validated key pattern to a regexp for `git config --get-regexp`.
+// git matches against canonical names, where the section and variable name are
+// lowercased but a subsection keeps its case.
+func configKeyRegexp(pattern string) string {
+	wildcard := strings.HasSuffix(pattern, ".*")
+	pattern = strings.TrimSuffix(pattern, ".*")
+
+	parts := strings.Split(pattern, ".")
+	parts[0] = strings.ToLower(parts[0])
+	if !wildcard && len(parts) > 1 {
+		parts[len(parts)-1] = strings.ToLower(parts[len(parts)-1])
+	}
+
+	key := regexp.QuoteMeta(strings.Join(parts, "."))
+	if wildcard {
+		return "^" + key + `\.[^.]+$`
+	}
+	return "^" + key + "$"
+}
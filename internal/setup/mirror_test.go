@@ -0,0 +1,172 @@
+package setup
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+)
+
+func TestMirrorConfig(t *testing.T) {
+	dir := t.TempDir()
+	mainDir := filepath.Join(dir, "repo")
+	worktreeDir := filepath.Join(dir, "repo-feature")
+
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	gitRun("init", "-q", mainDir)
+	gitRun("-C", mainDir, "commit", "-q", "--allow-empty", "-m", "initial")
+	gitRun("-C", mainDir, "worktree", "add", "-q", "-b", "feature", worktreeDir)
+
+	configYAML := `mirror_config:
+  keys:
+    - core.sparseCheckout
+    - lfs.*`
+	if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	// Without extensions.worktreeConfig there is nothing to mirror
+	warnings, err := MirrorConfig(worktreeDir, mainDir)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("MirrorConfig() = %v, %v; want no warnings and no error", warnings, err)
+	}
+
+	gitRun("-C", mainDir, "config", "extensions.worktreeConfig", "true")
+	gitRun("-C", mainDir, "config", "--worktree", "core.sparseCheckout", "true")
+	gitRun("-C", mainDir, "config", "--worktree", "lfs.fetchexclude", "*.bin")
+	gitRun("-C", mainDir, "config", "--worktree", "user.email", "main@example.com")
+
+	warnings, err = MirrorConfig(worktreeDir, mainDir)
+	if err != nil {
+		t.Fatalf("MirrorConfig() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("MirrorConfig() warnings = %v", warnings)
+	}
+
+	for key, want := range map[string]string{
+		"core.sparseCheckout": "true",
+		"lfs.fetchexclude":    "*.bin",
+	} {
+		got, err := git.GetWorktreeConfigRegexp(worktreeDir, "^"+key+"$")
+		if err != nil {
+			t.Fatalf("GetWorktreeConfigRegexp(%s) error = %v", key, err)
+		}
+		if len(got) != 1 || got[0].Value != want {
+			t.Errorf("%s in new worktree = %v, want %q", key, got, want)
+		}
+	}
+	// Keys outside the allowlist are not copied
+	if got, _ := git.GetWorktreeConfigRegexp(worktreeDir, `^user\.email$`); len(got) != 0 {
+		t.Errorf("user.email was mirrored: %v", got)
+	}
+}
+
+func TestMirrorConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		configYAML string
+	}{
+		{name: "no keys configured", configYAML: ""},
+		{name: "invalid key", configYAML: "mirror_config:\n  keys:\n    - core.(hooksPath)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mainDir := t.TempDir()
+			if tt.configYAML != "" {
+				if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte(tt.configYAML), 0644); err != nil {
+					t.Fatalf("failed to write test config: %v", err)
+				}
+			}
+			if _, err := MirrorConfig(t.TempDir(), mainDir); err == nil {
+				t.Error("MirrorConfig() expected error")
+			}
+		})
+	}
+}
+
+func TestMirrorConfigFrom(t *testing.T) {
+	dir := t.TempDir()
+	mainDir := filepath.Join(dir, "repo")
+	sourceDir := filepath.Join(dir, "repo-feature")
+	newDir := filepath.Join(dir, "repo-pr42")
+
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	gitRun("init", "-q", mainDir)
+	gitRun("-C", mainDir, "commit", "-q", "--allow-empty", "-m", "initial")
+	gitRun("-C", mainDir, "worktree", "add", "-q", "-b", "feature", sourceDir)
+	gitRun("-C", mainDir, "worktree", "add", "-q", "-b", "pr-branch", newDir)
+
+	configYAML := `mirror_config:
+  keys:
+    - branch.*
+    - lfs.*
+    - http.*`
+	if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	gitRun("-C", mainDir, "config", "extensions.worktreeConfig", "true")
+	gitRun("-C", sourceDir, "config", "branch.feature.rebase", "true")
+	gitRun("-C", sourceDir, "config", "branch.feature.remote", "origin")
+	gitRun("-C", sourceDir, "config", "branch.feature.gh-worktree-type", "branch")
+	gitRun("-C", sourceDir, "config", "--worktree", "lfs.fetchexclude", "*.bin")
+	gitRun("-C", sourceDir, "config", "--worktree", "http.extraHeader", "Authorization: token secret")
+	gitRun("-C", sourceDir, "config", "--worktree", "user.email", "feature@example.com")
+
+	warnings, err := MirrorConfigFrom(newDir, "pr-branch", sourceDir, "feature", mainDir)
+	if err != nil {
+		t.Fatalf("MirrorConfigFrom() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("MirrorConfigFrom() warnings = %v", warnings)
+	}
+
+	if got, _ := git.GetConfig(newDir, "branch.pr-branch.rebase"); got != "true" {
+		t.Errorf("branch.pr-branch.rebase = %q, want %q", got, "true")
+	}
+	if got, _ := git.GetWorktreeConfigRegexp(newDir, `^lfs\.fetchexclude$`); len(got) != 1 || got[0].Value != "*.bin" {
+		t.Errorf("lfs.fetchexclude in new worktree = %v, want *.bin", got)
+	}
+
+	// Tracking settings, metadata, credentials and keys outside the allowlist are not copied
+	for _, key := range []string{"branch.pr-branch.remote", "branch.pr-branch.gh-worktree-type"} {
+		if got, _ := git.GetConfig(newDir, key); got != "" {
+			t.Errorf("%s was copied: %q", key, got)
+		}
+	}
+	for _, key := range []string{`^http\.extraheader$`, `^user\.email$`} {
+		if got, _ := git.GetWorktreeConfigRegexp(newDir, key); len(got) != 0 {
+			t.Errorf("%s was copied: %v", key, got)
+		}
+	}
+
+	// An empty allowlist is an error
+	if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), nil, 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	if _, err := MirrorConfigFrom(newDir, "pr-branch", sourceDir, "feature", mainDir); err == nil {
+		t.Error("MirrorConfigFrom() expected error without mirror_config.keys")
+	}
+}
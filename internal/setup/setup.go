@@ -4,31 +4,84 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+
+	"github.com/knqyf263/gh-worktree/internal/validate"
 )
 
-// RunSetup executes post-creation setup commands in the new worktree
-func RunSetup(newWorktreePath, mainWorktreePath string) error {
+// RunSetup executes post-creation setup commands in the new worktree.
+// profile selects a named setup profile; if empty, the top-level setup commands run.
+// The setup.bootstrap script runs first if the new worktree contains it.
+// Failing commands don't stop the setup; they are returned as warnings.
+func RunSetup(newWorktreePath, mainWorktreePath, profile string) ([]string, error) {
 	config, err := LoadConfig(mainWorktreePath)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	commands, err := config.Setup.Commands(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	bootstrap := config.Setup.Bootstrap
+	if bootstrap != "" {
+		if err := validate.ScriptPath(bootstrap); err != nil {
+			return nil, fmt.Errorf("invalid setup.bootstrap: %w", err)
+		}
+		// The script is optional: branches that predate it don't have it
+		if info, err := os.Stat(filepath.Join(newWorktreePath, bootstrap)); err != nil || info.IsDir() {
+			bootstrap = ""
+		}
 	}
 
 	// If no setup commands are configured, skip
-	if len(config.Setup.Run) == 0 {
-		return nil
+	if len(commands) == 0 && bootstrap == "" {
+		return nil, nil
 	}
 
 	fmt.Fprintln(os.Stderr, "→ Running post-creation setup...")
 
+	// Execute commands in the new worktree directory with GH_WORKTREE_MAIN_DIR env var
+	env := []string{
+		fmt.Sprintf("GH_WORKTREE_MAIN_DIR=%s", mainWorktreePath),
+	}
+	var warnings []string
+	if bootstrap != "" {
+		warnings = append(warnings, runScript(bootstrap, newWorktreePath, env)...)
+	}
+	warnings = append(warnings, runCommands(commands, newWorktreePath, env)...)
+
+	if len(warnings) > 0 {
+		fmt.Fprintln(os.Stderr, "  ⚠ Setup completed with warnings")
+	} else {
+		fmt.Fprintln(os.Stderr, "  ✓ Setup completed")
+	}
+
+	return warnings, nil
+}
+
+// RunAfter executes an ad hoc command given with --after in the new worktree.
+// Unlike setup commands it comes from the invocation rather than the config.
+// A failure is returned as a warning.
+func RunAfter(newWorktreePath, mainWorktreePath, command string) []string {
+	fmt.Fprintln(os.Stderr, "→ Running after command...")
+	return runCommands([]string{command}, newWorktreePath, []string{
+		fmt.Sprintf("GH_WORKTREE_MAIN_DIR=%s", mainWorktreePath),
+	})
+}
+
+// runCommands executes shell commands in dir with extra environment variables.
+// It keeps going when a command fails and returns the failures as warnings.
+func runCommands(commands []string, dir string, env []string) []string {
 	var warnings []string
 
-	for _, cmdStr := range config.Setup.Run {
+	for _, cmdStr := range commands {
 		fmt.Fprintf(os.Stderr, "  ✓ %s\n", cmdStr)
 
-		// Execute command in the new worktree directory with GH_WORKTREE_MAIN_DIR env var
 		cmd := exec.Command("sh", "-c", cmdStr)
-		cmd.Dir = newWorktreePath
-		cmd.Env = append(os.Environ(), fmt.Sprintf("GH_WORKTREE_MAIN_DIR=%s", mainWorktreePath))
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
 		cmd.Stdout = os.Stderr
 		cmd.Stderr = os.Stderr
 
@@ -39,12 +92,28 @@ func RunSetup(newWorktreePath, mainWorktreePath string) error {
 		}
 	}
 
-	if len(warnings) > 0 {
-		fmt.Fprintln(os.Stderr, "  ⚠ Setup completed with warnings")
-	} else {
-		fmt.Fprintln(os.Stderr, "  ✓ Setup completed")
-	}
+	return warnings
+}
 
+// runScript executes the script at the relative path in dir with extra
+// environment variables. A failure is returned as a warning.
+func runScript(path, dir string, env []string) []string {
+	fmt.Fprintf(os.Stderr, "  ✓ %s\n", path)
+
+	cmd := exec.Command(filepath.Join(dir, path))
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		warning := fmt.Sprintf("Bootstrap script failed: %s: %v", path, err)
+		if cmd.ProcessState != nil {
+			warning = fmt.Sprintf("Bootstrap script failed (exit %d): %s", cmd.ProcessState.ExitCode(), path)
+		}
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", warning)
+		return []string{warning}
+	}
 	return nil
 }
 
@@ -54,7 +123,7 @@ func ShouldRunSetup(mainWorktreePath string) bool {
 	if err != nil {
 		return false
 	}
-	return len(config.Setup.Run) > 0
+	return len(config.Setup.Run) > 0 || config.Setup.Bootstrap != "" || len(config.Setup.Link) > 0
 }
 
 // PrintSkippedMessage prints a message when setup is skipped
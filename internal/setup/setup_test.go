@@ -19,6 +19,12 @@ func TestShouldRunSetup(t *testing.T) {
     - echo "test"`,
 			want: true,
 		},
+		{
+			name: "config with bootstrap script",
+			configYAML: `setup:
+  bootstrap: ./script/setup`,
+			want: true,
+		},
 		{
 			name:       "empty config",
 			configYAML: `setup:`,
@@ -56,7 +62,7 @@ func TestRunSetup_NoConfig(t *testing.T) {
 	newDir := t.TempDir()
 
 	// Run setup with no config file (should succeed without doing anything)
-	err := RunSetup(newDir, mainDir)
+	_, err := RunSetup(newDir, mainDir, "")
 	if err != nil {
 		t.Errorf("RunSetup() with no config should not error, got: %v", err)
 	}
@@ -77,7 +83,7 @@ func TestRunSetup_WithSimpleCommand(t *testing.T) {
 	}
 
 	// Run setup
-	err := RunSetup(newDir, mainDir)
+	_, err := RunSetup(newDir, mainDir, "")
 	if err != nil {
 		t.Errorf("RunSetup() error = %v", err)
 	}
@@ -110,7 +116,7 @@ func TestRunSetup_WithEnvironmentVariable(t *testing.T) {
 	}
 
 	// Run setup
-	err := RunSetup(newDir, mainDir)
+	_, err := RunSetup(newDir, mainDir, "")
 	if err != nil {
 		t.Errorf("RunSetup() error = %v", err)
 	}
@@ -121,3 +127,119 @@ func TestRunSetup_WithEnvironmentVariable(t *testing.T) {
 		t.Errorf("Expected file %s to be created via environment variable", copiedFile)
 	}
 }
+
+func TestRunSetup_ReturnsWarnings(t *testing.T) {
+	mainDir := t.TempDir()
+	newDir := t.TempDir()
+
+	configYAML := `setup:
+  run:
+    - "true"
+    - exit 3`
+	configPath := filepath.Join(mainDir, ".gh-worktree.yml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	warnings, err := RunSetup(newDir, mainDir, "")
+	if err != nil {
+		t.Fatalf("RunSetup() error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("RunSetup() got %d warnings, want 1", len(warnings))
+	}
+	if warnings[0] != "Command failed (exit 3): exit 3" {
+		t.Errorf("RunSetup() warning = %q", warnings[0])
+	}
+}
+
+func TestRunSetup_Bootstrap(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		wantRun      bool
+		wantWarnings int
+	}{
+		{
+			name:    "script present",
+			script:  "#!/bin/sh\necho \"$GH_WORKTREE_MAIN_DIR\" > bootstrapped.txt\n",
+			wantRun: true,
+		},
+		{
+			name:    "script missing",
+			script:  "",
+			wantRun: false,
+		},
+		{
+			name:         "script fails",
+			script:       "#!/bin/sh\ntouch bootstrapped.txt\nexit 2\n",
+			wantRun:      true,
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mainDir := t.TempDir()
+			newDir := t.TempDir()
+
+			configYAML := `setup:
+  bootstrap: ./script/setup`
+			if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte(configYAML), 0644); err != nil {
+				t.Fatalf("failed to write test config: %v", err)
+			}
+			if tt.script != "" {
+				if err := os.MkdirAll(filepath.Join(newDir, "script"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(newDir, "script", "setup"), []byte(tt.script), 0755); err != nil {
+					t.Fatalf("failed to write bootstrap script: %v", err)
+				}
+			}
+
+			warnings, err := RunSetup(newDir, mainDir, "")
+			if err != nil {
+				t.Fatalf("RunSetup() error = %v", err)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("RunSetup() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			_, statErr := os.Stat(filepath.Join(newDir, "bootstrapped.txt"))
+			if ran := statErr == nil; ran != tt.wantRun {
+				t.Errorf("bootstrap ran = %v, want %v", ran, tt.wantRun)
+			}
+		})
+	}
+}
+
+func TestRunSetup_InvalidBootstrap(t *testing.T) {
+	mainDir := t.TempDir()
+
+	configYAML := `setup:
+  bootstrap: ../outside/setup`
+	if err := os.WriteFile(filepath.Join(mainDir, ".gh-worktree.yml"), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	if _, err := RunSetup(t.TempDir(), mainDir, ""); err == nil {
+		t.Error("RunSetup() expected error for a bootstrap path outside the worktree")
+	}
+}
+
+func TestRunAfter(t *testing.T) {
+	mainDir := t.TempDir()
+	newDir := t.TempDir()
+
+	warnings := RunAfter(newDir, mainDir, "touch after.txt")
+	if len(warnings) != 0 {
+		t.Errorf("RunAfter() got warnings: %v", warnings)
+	}
+	if _, err := os.Stat(filepath.Join(newDir, "after.txt")); err != nil {
+		t.Errorf("RunAfter() did not run in the new worktree: %v", err)
+	}
+
+	warnings = RunAfter(newDir, mainDir, "exit 2")
+	if len(warnings) != 1 {
+		t.Errorf("RunAfter() with failing command got %d warnings, want 1", len(warnings))
+	}
+}
@@ -0,0 +1,36 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+)
+
+// RunTeardown executes teardown commands in a worktree that is about to be removed.
+// It must be called while the worktree directory still exists.
+// Failing commands don't stop the teardown; they are returned as warnings.
+func RunTeardown(worktreePath, mainWorktreePath, branchName string) ([]string, error) {
+	config, err := LoadConfig(mainWorktreePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// If no teardown commands are configured, skip
+	if len(config.Teardown.Run) == 0 {
+		return nil, nil
+	}
+
+	fmt.Fprintln(os.Stderr, "→ Running teardown...")
+
+	warnings := runCommands(config.Teardown.Run, worktreePath, []string{
+		fmt.Sprintf("GH_WORKTREE_MAIN_DIR=%s", mainWorktreePath),
+		fmt.Sprintf("GH_WORKTREE_BRANCH=%s", branchName),
+	})
+
+	if len(warnings) > 0 {
+		fmt.Fprintln(os.Stderr, "  ⚠ Teardown completed with warnings")
+	} else {
+		fmt.Fprintln(os.Stderr, "  ✓ Teardown completed")
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,72 @@
+package setup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunTeardown_NoConfig(t *testing.T) {
+	mainDir := t.TempDir()
+	worktreeDir := t.TempDir()
+
+	warnings, err := RunTeardown(worktreeDir, mainDir, "feature")
+	if err != nil {
+		t.Errorf("RunTeardown() with no config should not error, got: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("RunTeardown() with no config got warnings: %v", warnings)
+	}
+}
+
+func TestRunTeardown_WithEnvironmentVariables(t *testing.T) {
+	mainDir := t.TempDir()
+	worktreeDir := t.TempDir()
+
+	configYAML := `teardown:
+  run:
+    - echo "$GH_WORKTREE_BRANCH" > "$GH_WORKTREE_MAIN_DIR/teardown-branch.txt"`
+	configPath := filepath.Join(mainDir, ".gh-worktree.yml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	if _, err := RunTeardown(worktreeDir, mainDir, "feature-auth"); err != nil {
+		t.Fatalf("RunTeardown() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(mainDir, "teardown-branch.txt"))
+	if err != nil {
+		t.Fatalf("expected teardown command to write file: %v", err)
+	}
+	if string(data) != "feature-auth\n" {
+		t.Errorf("GH_WORKTREE_BRANCH = %q, want %q", string(data), "feature-auth\n")
+	}
+}
+
+func TestRunTeardown_FailureIsWarning(t *testing.T) {
+	mainDir := t.TempDir()
+	worktreeDir := t.TempDir()
+
+	configYAML := `teardown:
+  run:
+    - exit 1
+    - touch after-failure.txt`
+	configPath := filepath.Join(mainDir, ".gh-worktree.yml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	warnings, err := RunTeardown(worktreeDir, mainDir, "feature")
+	if err != nil {
+		t.Fatalf("RunTeardown() error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("RunTeardown() got %d warnings, want 1", len(warnings))
+	}
+
+	// Commands after a failure still run
+	if _, err := os.Stat(filepath.Join(worktreeDir, "after-failure.txt")); err != nil {
+		t.Errorf("expected command after failure to run: %v", err)
+	}
+}
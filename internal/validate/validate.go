@@ -3,8 +3,10 @@ package validate
 import (
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var (
@@ -12,6 +14,13 @@ var (
 	validBranchName = regexp.MustCompile(`^[a-zA-Z0-9._/-]+$`)
 	// validRepoName matches valid repository names
 	validRepoName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+	// validCommitSHA matches full or abbreviated commit SHAs
+	validCommitSHA = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+	// validConfigKeyPattern matches git config keys ("section[.subsection].name")
+	// or all keys of a section ("section[.subsection].*")
+	validConfigKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]*(\.[a-zA-Z0-9_./:-]+)?\.([a-zA-Z][a-zA-Z0-9-]*|\*)$`)
+	// validRef matches fully qualified refs made of safe characters
+	validRef = regexp.MustCompile(`^refs/[a-zA-Z0-9._/-]+$`)
 )
 
 // SanitizeForGitConfig removes or escapes dangerous characters for git config values
@@ -36,6 +45,10 @@ func BranchName(name string) error {
 	if len(name) > 255 {
 		return fmt.Errorf("branch name too long")
 	}
+	// HEAD and @ are valid-looking but refer to the current commit, not a branch
+	if name == "HEAD" || name == "@" {
+		return fmt.Errorf("invalid branch name: %s is reserved", name)
+	}
 	if !validBranchName.MatchString(name) {
 		return fmt.Errorf("invalid branch name: contains unsafe characters")
 	}
@@ -70,8 +83,159 @@ func PRNumber(prNumber int) error {
 	return nil
 }
 
+// CommitSHA checks if sha is a full or abbreviated commit SHA
+func CommitSHA(sha string) error {
+	if !validCommitSHA.MatchString(sha) {
+		return fmt.Errorf("invalid commit SHA: %s", sha)
+	}
+	return nil
+}
+
+// ConfigKeyPattern checks if pattern is a git config key or a "section.*" wildcard
+func ConfigKeyPattern(pattern string) error {
+	if !validConfigKeyPattern.MatchString(pattern) {
+		return fmt.Errorf("invalid config key: %s (must be section.name or section.*)", pattern)
+	}
+	return nil
+}
+
+// SparsePath checks if path is a directory inside the repository suitable for
+// cone-mode sparse-checkout
+func SparsePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("sparse-checkout path cannot be empty")
+	}
+	slashed := filepath.ToSlash(path)
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
+		return fmt.Errorf("invalid sparse-checkout path %s: must be relative to the repository root", path)
+	}
+	if strings.HasPrefix(path, "-") {
+		return fmt.Errorf("invalid sparse-checkout path %s: must not start with '-'", path)
+	}
+	for _, segment := range strings.Split(slashed, "/") {
+		if segment == ".." {
+			return fmt.Errorf("invalid sparse-checkout path %s: must not leave the repository", path)
+		}
+	}
+	if strings.ContainsAny(path, "*?[\n") {
+		return fmt.Errorf("invalid sparse-checkout path %s: must be a directory, not a pattern", path)
+	}
+	return nil
+}
+
+// PullRefTemplate checks if template is a safe ref for pull requests once its
+// {number} placeholder is replaced, e.g. "refs/pull/{number}/head"
+func PullRefTemplate(template string) error {
+	if !strings.Contains(template, "{number}") {
+		return fmt.Errorf("invalid pull ref template %s: must contain {number}", template)
+	}
+	ref := strings.ReplaceAll(template, "{number}", "1")
+	if !validRef.MatchString(ref) {
+		return fmt.Errorf("invalid pull ref template %s: must be a ref under refs/ made of letters, digits, '.', '_', '-' and '/'", template)
+	}
+	for _, component := range strings.Split(ref, "/") {
+		if component == "" || strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("invalid pull ref template %s: invalid ref component %q", template, component)
+		}
+	}
+	if strings.Contains(ref, "..") || strings.HasSuffix(ref, ".") {
+		return fmt.Errorf("invalid pull ref template %s: invalid ref format", template)
+	}
+	return nil
+}
+
+// ScriptPath checks if path is a relative path to a script inside the worktree
+func ScriptPath(path string) error {
+	return worktreeRelativePath("script path", path)
+}
+
+// LinkPath checks if path is a relative path to a directory inside the
+// worktree that can be replaced with a link, i.e. not the worktree root
+func LinkPath(path string) error {
+	if err := worktreeRelativePath("link path", path); err != nil {
+		return err
+	}
+	if clean := filepath.Clean(path); clean == "." || clean == ".git" {
+		return fmt.Errorf("invalid link path %s: must be a directory inside the worktree", path)
+	}
+	return nil
+}
+
+// worktreeRelativePath checks if path is relative to the worktree root and stays inside it
+func worktreeRelativePath(kind, path string) error {
+	if path == "" {
+		return fmt.Errorf("%s cannot be empty", kind)
+	}
+	slashed := filepath.ToSlash(path)
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
+		return fmt.Errorf("invalid %s %s: must be relative to the worktree root", kind, path)
+	}
+	if strings.HasPrefix(path, "-") {
+		return fmt.Errorf("invalid %s %s: must not start with '-'", kind, path)
+	}
+	for _, segment := range strings.Split(slashed, "/") {
+		if segment == ".." {
+			return fmt.Errorf("invalid %s %s: must not leave the worktree", kind, path)
+		}
+	}
+	return nil
+}
+
+// PRState checks if state is a valid pull request state filter
+func PRState(state string) error {
+	switch state {
+	case "open", "closed", "all":
+		return nil
+	}
+	return fmt.Errorf("invalid PR state: %s (must be open, closed, or all)", state)
+}
+
+// NameFrom checks if nameFrom is a valid source for PR worktree directory names
+func NameFrom(nameFrom string) error {
+	switch nameFrom {
+	case "number", "head", "title", "branch":
+		return nil
+	}
+	return fmt.Errorf("invalid name source: %s (must be number, head, title, or branch)", nameFrom)
+}
+
+// BranchNaming checks if naming is a valid source for PR worktree branch names
+func BranchNaming(naming string) error {
+	switch naming {
+	case "head", "title", "number":
+		return nil
+	}
+	return fmt.Errorf("invalid branch naming: %s (must be head, title, or number)", naming)
+}
+
+// BranchExistsPolicy checks if policy is a valid --branch-exists-policy
+func BranchExistsPolicy(policy string) error {
+	switch policy {
+	case "ff", "reset", "rename", "error":
+		return nil
+	}
+	return fmt.Errorf("invalid branch exists policy: %s (must be ff, reset, rename, or error)", policy)
+}
+
+// Date checks if date is in YYYY-MM-DD or RFC 3339 format
+func Date(date string) error {
+	if _, err := time.Parse("2006-01-02", date); err == nil {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, date); err == nil {
+		return nil
+	}
+	return fmt.Errorf("invalid date: %s (must be YYYY-MM-DD or RFC 3339)", date)
+}
+
 // URL checks if URL is safe GitHub URL
 func URL(urlStr string) error {
+	return HostURL(urlStr, "github.com")
+}
+
+// HostURL checks if URL is a safe HTTPS URL on the given GitHub host,
+// e.g. a GitHub Enterprise Server hostname
+func HostURL(urlStr, host string) error {
 	if urlStr == "" {
 		return fmt.Errorf("URL cannot be empty")
 	}
@@ -90,8 +254,8 @@ func URL(urlStr string) error {
 		return fmt.Errorf("URL cannot contain credentials")
 	}
 
-	if parsedURL.Host != "github.com" {
-		return fmt.Errorf("only github.com URLs are allowed")
+	if parsedURL.Host != host {
+		return fmt.Errorf("only %s URLs are allowed", host)
 	}
 
 	return nil
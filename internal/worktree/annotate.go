@@ -0,0 +1,46 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/github"
+)
+
+// AnnotationFile is the file --annotate writes into PR worktrees
+const AnnotationFile = ".gh-worktree-info.md"
+
+// Annotate writes AnnotationFile with the PR's number, title and URL and the
+// creation time into a new worktree if opts.Annotate or worktree.annotate is
+// set. The file is added to info/exclude so it is never committed.
+func (c *Creator) Annotate(worktreePath string, pr *github.PullRequest, opts *CheckoutOptions) error {
+	if !opts.Annotate && !c.annotate {
+		return nil
+	}
+
+	if err := git.AddExclude(worktreePath, "/"+AnnotationFile); err != nil {
+		return err
+	}
+	url := pr.HTMLURL
+	if url == "" {
+		url = fmt.Sprintf("https://%s/%s/%s/pull/%d", c.repo.Host, c.repo.Owner, c.repo.Name, pr.Number)
+	}
+	if err := os.WriteFile(filepath.Join(worktreePath, AnnotationFile), []byte(annotation(pr, url, time.Now())), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", AnnotationFile, err)
+	}
+	return nil
+}
+
+// annotation returns the content of AnnotationFile
+func annotation(pr *github.PullRequest, url string, created time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# #%d %s\n\n", pr.Number, pr.Title)
+	fmt.Fprintf(&b, "- Pull request: %s\n", url)
+	fmt.Fprintf(&b, "- Created: %s\n\n", created.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Written by `gh worktree pr checkout --annotate`; excluded from git via info/exclude.\n")
+	return b.String()
+}
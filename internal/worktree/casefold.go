@@ -0,0 +1,59 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkCaseCollision returns an error if path would collide with an existing entry
+// that differs only in case, on a case-insensitive filesystem (e.g. macOS APFS).
+// On such filesystems "repo-Feature" and "repo-feature" are the same directory.
+func checkCaseCollision(path string) error {
+	dir := filepath.Dir(path)
+	if !isCaseInsensitiveFS(dir) {
+		return nil
+	}
+
+	existing, err := findCaseCollision(dir, filepath.Base(path))
+	if err != nil || existing == "" {
+		return nil
+	}
+	return fmt.Errorf("path %s collides with existing %s on a case-insensitive filesystem", path, filepath.Join(dir, existing))
+}
+
+// findCaseCollision returns the name of an entry in dir that equals name
+// case-insensitively but not exactly, or "" if there is none
+func findCaseCollision(dir, name string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() != name && strings.EqualFold(entry.Name(), name) {
+			return entry.Name(), nil
+		}
+	}
+	return "", nil
+}
+
+// isCaseInsensitiveFS probes whether dir is on a case-insensitive filesystem
+// by creating a temporary file and looking it up with a different case
+func isCaseInsensitiveFS(dir string) bool {
+	f, err := os.CreateTemp(dir, ".gh-worktree-case-probe-")
+	if err != nil {
+		return false
+	}
+	probe := f.Name()
+	f.Close()
+	defer os.Remove(probe)
+
+	upper := filepath.Join(dir, strings.ToUpper(filepath.Base(probe)))
+	if upper == probe {
+		return false
+	}
+	_, err = os.Stat(upper)
+	return err == nil
+}
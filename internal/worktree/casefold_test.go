@@ -0,0 +1,72 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindCaseCollision(t *testing.T) {
+	dir := t.TempDir()
+
+	// Simulate an existing worktree for branch "Feature"
+	existing := "repo-" + sanitizeBranchNameForPath("Feature")
+	if err := os.Mkdir(filepath.Join(dir, existing), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		branchName string
+		want       string
+	}{
+		{
+			name:       "case-differing branch collides",
+			branchName: "feature",
+			want:       "repo-Feature",
+		},
+		{
+			name:       "same branch is not a collision",
+			branchName: "Feature",
+			want:       "",
+		},
+		{
+			name:       "different branch",
+			branchName: "bugfix",
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findCaseCollision(dir, "repo-"+sanitizeBranchNameForPath(tt.branchName))
+			if err != nil {
+				t.Fatalf("findCaseCollision() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("findCaseCollision(%q) = %q, want %q", tt.branchName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCaseInsensitiveFS(t *testing.T) {
+	dir := t.TempDir()
+
+	// The result depends on the host filesystem, but the probe must not leave files behind
+	isCaseInsensitiveFS(dir)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("isCaseInsensitiveFS() left %d files behind", len(entries))
+	}
+}
+
+func TestCheckCaseCollision_NonExistentDir(t *testing.T) {
+	if err := checkCaseCollision("/non/existent/dir/repo-feature"); err != nil {
+		t.Errorf("checkCaseCollision() error = %v, want nil", err)
+	}
+}
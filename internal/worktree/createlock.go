@@ -0,0 +1,119 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/lock"
+)
+
+// createLockTimeout is how long checkout waits for a concurrent checkout to finish adding its worktree
+const createLockTimeout = 2 * time.Minute
+
+// AcquireCreateLock takes the per-repository lock that guards adding worktrees.
+// It lives in the git common directory so it is shared by all worktrees of the repository.
+func AcquireCreateLock() (*lock.Lock, error) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return nil, err
+	}
+
+	createLock, err := lock.Acquire(filepath.Join(commonDir, "gh-worktree.lock"), createLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return createLock, nil
+}
+
+// lock takes the creation lock unless the creator already holds it or doesn't
+// lock at all, and checks that worktreePath wasn't created by another gh
+// worktree command since the caller checked it
+func (c *Creator) lock(worktreePath string) error {
+	if !c.locking || c.createLock != nil {
+		return nil
+	}
+	createLock, err := AcquireCreateLock()
+	if err != nil {
+		return err
+	}
+	c.createLock = createLock
+	return CheckNotCreated(worktreePath)
+}
+
+// CheckNotCreated returns an error if a worktree or isolated clone was
+// registered at worktreePath, e.g. by another gh worktree command that added
+// it while the caller waited for the creation lock
+func CheckNotCreated(worktreePath string) error {
+	registered, err := IsRegistered(worktreePath)
+	if err != nil {
+		return err
+	}
+	if registered || IsIsolated(worktreePath) {
+		return fmt.Errorf("worktree %s was created by another gh worktree command in the meantime", worktreePath)
+	}
+	return nil
+}
+
+// unlock releases the creation lock if the creator holds it
+func (c *Creator) unlock() {
+	if c.createLock != nil {
+		c.createLock.Release()
+		c.createLock = nil
+	}
+}
+
+// execute runs cmdQueue with the creation lock held for everything but its
+// leading fetches, so that slow fetches don't hold up other checkouts. Queues
+// that read FETCH_HEAD, which every fetch in the repository overwrites, run
+// entirely under the lock. The index of a failed command is that in cmdQueue.
+func (c *Creator) execute(worktreePath string, cmdQueue [][]string) error {
+	fetches := leadingFetches(cmdQueue)
+	if err := git.ExecuteCommandsContext(c.context(), cmdQueue[:fetches]); err != nil {
+		return err
+	}
+	if err := c.lock(worktreePath); err != nil {
+		return err
+	}
+
+	err := git.ExecuteCommandsContext(c.context(), cmdQueue[fetches:])
+	var cmdErr *git.CommandError
+	if errors.As(err, &cmdErr) {
+		cmdErr.Index += fetches
+	}
+	return err
+}
+
+// leadingFetches returns the number of fetch commands at the start of
+// cmdQueue that can run without the creation lock
+func leadingFetches(cmdQueue [][]string) int {
+	for _, cmd := range cmdQueue {
+		if slices.Contains(cmd, "FETCH_HEAD") {
+			return 0
+		}
+	}
+
+	n := 0
+	for _, cmd := range cmdQueue {
+		if subcommand(cmd) != "fetch" {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// subcommand returns the git subcommand of args, skipping the -c and -C
+// options that SkipHooks and per-worktree commands put in front of it
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] != "-c" && args[i] != "-C" {
+			return args[i]
+		}
+		i++
+	}
+	return ""
+}
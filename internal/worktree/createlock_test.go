@@ -0,0 +1,66 @@
+package worktree
+
+import "testing"
+
+func TestLeadingFetches(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmdQueue [][]string
+		want     int
+	}{
+		{
+			name:     "empty queue",
+			cmdQueue: nil,
+			want:     0,
+		},
+		{
+			name: "fetch then worktree add",
+			cmdQueue: [][]string{
+				{"fetch", "origin", "+refs/heads/feature:refs/remotes/origin/feature"},
+				{"worktree", "add", "-b", "feature", "/tmp/repo-pr1", "origin/feature"},
+			},
+			want: 1,
+		},
+		{
+			name: "fetches with hooks skipped",
+			cmdQueue: [][]string{
+				{"-c", "core.hooksPath=/dev/null", "fetch", "origin", "refs/pull/1/head:pr-1"},
+				{"-c", "core.hooksPath=/dev/null", "fetch", "origin", "main"},
+				{"-c", "core.hooksPath=/dev/null", "worktree", "add", "/tmp/repo-pr1", "pr-1"},
+			},
+			want: 2,
+		},
+		{
+			name: "fetch after worktree add",
+			cmdQueue: [][]string{
+				{"worktree", "add", "/tmp/repo-pr1", "pr-1"},
+				{"fetch", "origin", "main"},
+			},
+			want: 0,
+		},
+		{
+			name: "queue reading FETCH_HEAD",
+			cmdQueue: [][]string{
+				{"fetch", "origin", "refs/pull/1/head"},
+				{"worktree", "add", "--detach", "/tmp/repo-pr1", "FETCH_HEAD"},
+			},
+			want: 0,
+		},
+		{
+			name: "remote add before fetch",
+			cmdQueue: [][]string{
+				{"remote", "add", "alice", "https://github.com/alice/repo.git"},
+				{"fetch", "alice", "feature"},
+			},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leadingFetches(tt.cmdQueue); got != tt.want {
+				t.Errorf("leadingFetches() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
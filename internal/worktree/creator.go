@@ -1,13 +1,18 @@
 package worktree
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cli/go-gh/v2/pkg/repository"
 	"github.com/knqyf263/gh-worktree/internal/git"
 	"github.com/knqyf263/gh-worktree/internal/github"
+	"github.com/knqyf263/gh-worktree/internal/lock"
 	"github.com/knqyf263/gh-worktree/internal/setup"
 	"github.com/knqyf263/gh-worktree/internal/validate"
 )
@@ -15,50 +20,247 @@ import (
 // CheckoutOptions represents options for creating a worktree
 type CheckoutOptions struct {
 	RecurseSubmodules bool
+	StrictSubmodules  bool
 	Force             bool
 	Detach            bool
 	BranchName        string
 	ShellMode         bool
 	NoSetup           bool
+	IntoCurrent       bool
+	NameFrom          string
+	LabelDir          bool
+	SetupProfile      string
+	MirrorConfig      bool
+	Reuse             bool
+	Update            bool
+	Clean             bool
+	Notify            bool
+	WaitForChecks     bool
+	ChecksTimeout     time.Duration
+	State             string
+	Author            string
+	Assignee          string
+	Requested         bool
+	TitleBranch       bool
+	ShallowSince      string
+	PushRemote        string
+	AtCommit          string
+	RebaseOnto        string
+	After             string
+	NoVerify          bool
+	Sparse            []string
+	FetchBase         bool
+	NoFetch           bool
+	RemoteBranch      string
+	OpenTerminal      bool
+	Describe          bool
+	IsolateGitDir     bool
+	// BranchExistsPolicy is one of the BranchExists* policies, or "" for the
+	// default (BranchExistsReset with Force, BranchExistsFF otherwise)
+	BranchExistsPolicy string
+	CheckDiskSpace     bool
+	// Fork is the owner of the fork the PR's head branch is fetched from
+	// instead of the PR's head repository
+	Fork        string
+	Renormalize bool
+	// Ephemeral removes the worktree and its branch after running Run in it
+	Ephemeral bool
+	Run       string
+	// ConfigFrom identifies the worktree whose git config is copied to the new
+	// one; ConfigSource is the worktree it resolved to
+	ConfigFrom   string
+	ConfigSource *Info
+	// BranchNaming is one of the BranchNaming* sources of the local branch name,
+	// resolved from --pr-branch-naming or worktree.pr_branch_naming
+	BranchNaming string
+	// Export prints export statements instead of the path in shell mode
+	Export bool
+	// SwitchExisting switches to a worktree that already has the PR or branch
+	// checked out without prompting
+	SwitchExisting bool
+	// MaxWorktrees overrides worktree.max, the number of worktrees beyond
+	// which checkout refuses to create more without Force
+	MaxWorktrees int
+	// SummaryFile is a manifest that a JSON record of each created worktree
+	// is appended to
+	SummaryFile string
+	// RefreshExistingBranch fast-forwards an existing local branch to the
+	// fetched PR head before adding the worktree instead of after
+	RefreshExistingBranch bool
+	// Annotate writes AnnotationFile describing the PR into the new worktree
+	Annotate bool
+	// LinkNodeModules symlinks node_modules of the main worktree into the new one
+	LinkNodeModules bool
+	// InteractiveMulti selects several PRs interactively and checks out each
+	InteractiveMulti bool
+	// IgnoreSubmoduleErrors updates submodules like RecurseSubmodules but
+	// records their failures as warnings instead of failing the checkout
+	IgnoreSubmoduleErrors bool
+	// NoMetadata doesn't record the gh-worktree-* metadata of the new
+	// worktree's branch, leaving it to be classified by directory name
+	NoMetadata bool
+	// Tmux opens the new worktree in a tmux window named after its directory
+	Tmux bool
+	// Tags fetches tags along with the PR if true (--tags) and not if false
+	// (--no-tags); nil leaves it to fetch.tags from the config
+	Tags *bool
+	// DirHash shortens directory names derived from a branch or title with
+	// ShortDirName
+	DirHash bool
+}
+
+// LinkDirs returns the directories to link into the new worktree in addition
+// to setup.link
+func (o *CheckoutOptions) LinkDirs() []string {
+	if o.LinkNodeModules {
+		return []string{setup.NodeModulesDir}
+	}
+	return nil
 }
 
 // Creator handles worktree creation logic
 type Creator struct {
 	remotes []*git.Remote
 	repo    repository.Repository
+	// fetchRemote and pushRemote are remotes.fetch and remotes.push from the config
+	fetchRemote string
+	pushRemote  string
+	// pullRefTemplate is pull_ref_template from the config
+	pullRefTemplate string
+	// checkDiskSpace is worktree.check_disk_space from the config
+	checkDiskSpace bool
+	// annotate is worktree.annotate from the config
+	annotate bool
+	// noMetadata is worktree.no_metadata from the config
+	noMetadata bool
+	// tags is fetch.tags from the config
+	tags          bool
+	setupWarnings []string
+	// ctx interrupts the git commands run by the creator; nil means context.Background()
+	ctx context.Context
+	// newBranch is the local branch Create is making, which is deleted if it is interrupted
+	newBranch string
+	// locking makes Create take the creation lock before adding the worktree
+	locking bool
+	// createLock is the creation lock while Create holds it
+	createLock *lock.Lock
 }
 
+// defaultPullRefTemplate is where GitHub exposes the head of each PR
+const defaultPullRefTemplate = "refs/pull/{number}/head"
+
 // NewCreator creates a new worktree creator
 func NewCreator(repo repository.Repository) (*Creator, error) {
-	remotes, err := git.GetRemotes()
+	return NewCreatorContext(context.Background(), repo)
+}
+
+// NewCreatorContext creates a new worktree creator whose git commands are
+// interrupted when ctx is done
+func NewCreatorContext(ctx context.Context, repo repository.Repository) (*Creator, error) {
+	remotes, err := git.GetRemotesContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get remotes: %w", err)
 	}
 
-	return &Creator{
-		remotes: remotes,
-		repo:    repo,
-	}, nil
+	mainWorktree, err := git.GetMainWorktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get main worktree: %w", err)
+	}
+	config, err := setup.LoadConfig(mainWorktree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	c := &Creator{
+		remotes:         remotes,
+		repo:            repo,
+		fetchRemote:     config.Remotes.Fetch,
+		pushRemote:      config.Remotes.Push,
+		pullRefTemplate: config.PullRefTemplate,
+		checkDiskSpace:  config.Worktree.CheckDiskSpace != nil && *config.Worktree.CheckDiskSpace,
+		annotate:        config.Worktree.Annotate != nil && *config.Worktree.Annotate,
+		noMetadata:      config.Worktree.NoMetadata != nil && *config.Worktree.NoMetadata,
+		tags:            config.Fetch.Tags != nil && *config.Fetch.Tags,
+		ctx:             ctx,
+		locking:         true,
+	}
+	if c.pullRefTemplate != "" {
+		if err := validate.PullRefTemplate(c.pullRefTemplate); err != nil {
+			return nil, fmt.Errorf("pull_ref_template: %w", err)
+		}
+	}
+	if c.fetchRemote != "" && c.remoteByName(c.fetchRemote) == nil {
+		return nil, fmt.Errorf("remotes.fetch: %s is not a configured remote", c.fetchRemote)
+	}
+	if c.pushRemote != "" && c.remoteByName(c.pushRemote) == nil {
+		return nil, fmt.Errorf("remotes.push: %s is not a configured remote", c.pushRemote)
+	}
+	return c, nil
 }
 
-// Create creates a new worktree for the given PR
+// Create creates a new worktree for the given PR.
+// If it is interrupted, the partially created worktree and branch are removed.
+// The creation lock is held while the worktree is added, but not while the PR is fetched.
 func (c *Creator) Create(worktreePath string, pr *github.PullRequest, opts *CheckoutOptions) error {
+	defer c.unlock()
+	err := c.create(worktreePath, pr, opts)
+	if err != nil && Interrupted(c.context(), err) {
+		RollBack(worktreePath, c.newBranch)
+	}
+	return err
+}
+
+func (c *Creator) create(worktreePath string, pr *github.PullRequest, opts *CheckoutOptions) error {
 	// Find base remote (origin or upstream)
 	baseRemote := c.findBaseRemote()
 	if baseRemote == nil {
 		return fmt.Errorf("no suitable remote found")
 	}
 
+	if opts.CheckDiskSpace || c.checkDiskSpace {
+		if err := checkDiskSpace(worktreePath, opts.IsolateGitDir); err != nil {
+			return err
+		}
+	}
+
+	if opts.AtCommit != "" {
+		return c.createAtCommit(worktreePath, pr, baseRemote, opts)
+	}
+	if opts.IsolateGitDir {
+		return c.createIsolated(worktreePath, pr, opts)
+	}
+	if opts.Fork != "" {
+		c.overrideFork(pr, opts.Fork)
+	}
+
 	// Determine if we have a head remote
 	headRemote := baseRemote
 	isCrossRepo := pr.Head.Repo.Owner.Login != c.repo.Owner
 	if isCrossRepo {
 		headRemote = c.findHeadRemote(pr)
 	}
+	// Without a remote, the overridden branch can only be fetched from the fork
+	if headRemote == nil && opts.RemoteBranch != "" && opts.NoFetch {
+		return fmt.Errorf("--remote-branch cannot be used with --no-fetch because %s/%s has no remote; add one and fetch %s first",
+			pr.Head.Repo.Owner.Login, pr.Head.Repo.Name, pr.Head.Ref)
+	}
 
-	branchName := pr.Head.Ref
-	if opts.BranchName != "" {
-		branchName = opts.BranchName
+	branchName, err := LocalBranchName(pr, opts)
+	if err != nil {
+		return err
+	}
+	if !opts.Detach && git.BranchExists(branchName) {
+		switch branchExistsPolicy(opts) {
+		case BranchExistsError:
+			return fmt.Errorf("local branch %s already exists (--branch-exists-policy error)", branchName)
+		case BranchExistsRename:
+			renamed := freeBranchName(branchName)
+			fmt.Fprintf(os.Stderr, "Branch %s already exists; using %s\n", branchName, renamed)
+			branchName = renamed
+		}
+	}
+	if !opts.Detach && !git.BranchExists(branchName) {
+		c.newBranch = branchName
 	}
 
 	var cmdQueue [][]string
@@ -69,7 +271,7 @@ func (c *Creator) Create(worktreePath string, pr *github.PullRequest, opts *Chec
 			return fmt.Errorf("failed to create commands for existing remote: %w", err)
 		}
 		cmdQueue = append(cmdQueue, cmds...)
-	} else {
+	} else if opts.RemoteBranch == "" && opts.Fork == "" {
 		cmds, err := c.cmdsForMissingRemote(pr, baseRemote, opts, worktreePath, branchName)
 		if err != nil {
 			return fmt.Errorf("failed to create commands for missing remote: %w", err)
@@ -77,38 +279,412 @@ func (c *Creator) Create(worktreePath string, pr *github.PullRequest, opts *Chec
 		cmdQueue = append(cmdQueue, cmds...)
 	}
 
-	if opts.RecurseSubmodules {
-		cmdQueue = append(cmdQueue, []string{"submodule", "sync", "--recursive"})
-		cmdQueue = append(cmdQueue, []string{"submodule", "update", "--init", "--recursive"})
+	cmdQueue, err = c.appendFinishingCmds(cmdQueue, pr, opts, worktreePath, branchName)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case headRemote == nil && opts.RemoteBranch != "":
+		// The pull ref always points at the PR's own head, so an overridden
+		// head ref has to be fetched from the fork
+		err = c.createFromFork(worktreePath, pr, opts, branchName, errHeadRefOverridden)
+	case headRemote == nil && opts.Fork != "":
+		err = c.createFromFork(worktreePath, pr, opts, branchName, errForkOverridden)
+	default:
+		if target := c.refreshTarget(pr, headRemote, opts, branchName); target != "" {
+			err = c.executeRefreshing(worktreePath, cmdQueue, branchName, target, opts.NoFetch)
+		} else {
+			err = c.execute(worktreePath, cmdQueue)
+		}
+	}
+	// Some GitHub Enterprise servers refuse to serve pull refs. The pull ref fetch
+	// is the first command for a missing remote, so fall back to fetching from the fork.
+	var cmdErr *git.CommandError
+	if headRemote == nil && !opts.NoFetch && opts.RemoteBranch == "" && opts.Fork == "" && errors.As(err, &cmdErr) && cmdErr.Index == 0 {
+		fmt.Fprintf(os.Stderr, "Fetching %s failed; falling back to fetching from the fork\n", c.pullRef(pr.Number))
+		err = c.createFromFork(worktreePath, pr, opts, branchName, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Store PR metadata in worktree git config. Detached worktrees have no
+	// branch to key it by and are identified by their directory name instead.
+	if !opts.Detach && !opts.NoMetadata && !c.noMetadata {
+		err = c.storePRMetadata(branchName, pr)
+		if err != nil {
+			return fmt.Errorf("failed to store PR metadata: %w", err)
+		}
+	}
+
+	return c.updateSubmodules(worktreePath, opts)
+}
+
+// Policies for --branch-exists-policy, i.e. what to do if the PR's local branch already exists
+const (
+	// BranchExistsFF fast-forwards the branch to the PR (the default)
+	BranchExistsFF = "ff"
+	// BranchExistsReset resets the branch to the PR, discarding local commits (--force)
+	BranchExistsReset = "reset"
+	// BranchExistsRename creates a new branch with a numeric suffix instead
+	BranchExistsRename = "rename"
+	// BranchExistsError fails the checkout
+	BranchExistsError = "error"
+)
+
+// branchExistsPolicy returns --branch-exists-policy, defaulting to
+// BranchExistsReset with --force and BranchExistsFF otherwise
+func branchExistsPolicy(opts *CheckoutOptions) string {
+	switch {
+	case opts.BranchExistsPolicy != "":
+		return opts.BranchExistsPolicy
+	case opts.Force:
+		return BranchExistsReset
+	default:
+		return BranchExistsFF
+	}
+}
+
+// freeBranchName returns branchName with the lowest numeric suffix (-2, -3, ...)
+// that doesn't name an existing branch
+func freeBranchName(branchName string) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", branchName, n)
+		if !git.BranchExists(candidate) {
+			return candidate
+		}
+	}
+}
+
+// Sources of the local branch name of PR worktrees for --pr-branch-naming
+const (
+	// BranchNamingHead uses the PR's head ref (the default)
+	BranchNamingHead = "head"
+	// BranchNamingTitle uses a slug of the PR title, as with --title-branch
+	BranchNamingTitle = "title"
+	// BranchNamingNumber uses pr-{N}, which is predictable even for forks
+	// whose head refs are arbitrary
+	BranchNamingNumber = "number"
+)
+
+// ResolveBranchNaming returns naming, or the configured worktree.pr_branch_naming
+// if it is empty, defaulting to BranchNamingHead
+func ResolveBranchNaming(naming string) (string, error) {
+	if naming == "" {
+		gitRoot, err := git.GetRoot()
+		if err != nil {
+			return "", fmt.Errorf("failed to get git root: %w", err)
+		}
+		config, err := setup.LoadConfig(gitRoot)
+		if err != nil {
+			return "", fmt.Errorf("failed to load config: %w", err)
+		}
+		naming = config.Worktree.PRBranchNaming
+	}
+	if naming == "" {
+		return BranchNamingHead, nil
+	}
+	if err := validate.BranchNaming(naming); err != nil {
+		return "", err
+	}
+	return naming, nil
+}
+
+// LocalBranchName returns the name of the local branch for the PR: --branch,
+// a slug of the PR title with --title-branch, pr-{N} with --pr-branch-naming
+// number, or the PR's head ref. The branch still tracks the head ref.
+func LocalBranchName(pr *github.PullRequest, opts *CheckoutOptions) (string, error) {
+	switch {
+	case opts.BranchName != "":
+		return opts.BranchName, nil
+	case opts.TitleBranch || opts.BranchNaming == BranchNamingTitle:
+		branchName := github.TitleBranchName(pr)
+		if err := validate.BranchName(branchName); err != nil {
+			return "", fmt.Errorf("failed to derive branch name from PR title: %w", err)
+		}
+		return branchName, nil
+	case opts.BranchNaming == BranchNamingNumber:
+		return fmt.Sprintf("pr-%d", pr.Number), nil
+	default:
+		return pr.Head.Ref, nil
+	}
+}
+
+// appendFinishingCmds appends the commands that run after the worktree is created
+// and applies --sparse and --no-verify to the whole queue. Submodules are updated separately
+// by updateSubmodules so that their failures aren't fatal.
+func (c *Creator) appendFinishingCmds(cmdQueue [][]string, pr *github.PullRequest, opts *CheckoutOptions, worktreePath, branchName string) ([][]string, error) {
+	if opts.FetchBase {
+		fetch, err := c.baseFetchCmd(pr, opts)
+		if err != nil {
+			return nil, err
+		}
+		cmdQueue = append(cmdQueue, fetch)
+	}
+
+	// An explicit push remote overrides the automatic pushRemote handling.
+	// Otherwise branches of same-repo PRs are pushed to remotes.push, since the
+	// remote they were fetched from may be a read-only mirror.
+	if opts.PushRemote != "" && !opts.Detach {
+		pushRemote, err := c.resolvePushRemote(opts.PushRemote)
+		if err != nil {
+			return nil, err
+		}
+		cmdQueue = append(cmdQueue, []string{"-C", worktreePath, "config", fmt.Sprintf("branch.%s.pushRemote", branchName), pushRemote})
+	} else if c.pushRemote != "" && !opts.Detach && !c.isCrossRepoPR(pr) {
+		cmdQueue = append(cmdQueue, []string{"-C", worktreePath, "config", fmt.Sprintf("branch.%s.pushRemote", branchName), c.pushRemote})
+	}
+
+	if len(opts.Sparse) > 0 {
+		cmdQueue = SparseCheckout(cmdQueue, worktreePath, opts.Sparse)
+	}
+
+	if opts.NoVerify {
+		cmdQueue = SkipHooks(cmdQueue)
 	}
 
-	err := git.ExecuteCommands(cmdQueue)
+	return cmdQueue, nil
+}
+
+// baseFetchCmd returns the command that fetches the PR's base branch into its
+// remote-tracking ref, e.g. upstream/main, so that it can be diffed or merged
+func (c *Creator) baseFetchCmd(pr *github.PullRequest, opts *CheckoutOptions) ([]string, error) {
+	baseRemote := c.findBaseRemote()
+	if baseRemote == nil {
+		return nil, fmt.Errorf("no suitable remote found")
+	}
+	if err := validate.BranchName(pr.Base.Ref); err != nil {
+		return nil, fmt.Errorf("invalid base ref: %w", err)
+	}
+	refSpec := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", pr.Base.Ref, baseRemote.Name, pr.Base.Ref)
+	return c.fetchCmd(opts, baseRemote.Name, refSpec), nil
+}
+
+// updateSubmodules initializes and updates the submodules of a new worktree if
+// requested. Failures fail the checkout unless opts.IgnoreSubmoduleErrors is
+// set, in which case they are recorded as warnings since the worktree itself was created.
+func (c *Creator) updateSubmodules(worktreePath string, opts *CheckoutOptions) error {
+	if !opts.RecurseSubmodules && !opts.IgnoreSubmoduleErrors {
+		return nil
+	}
+	// Submodules are fetched, and the worktree is already added
+	c.unlock()
+
+	cmdQueue := [][]string{
+		{"-C", worktreePath, "submodule", "sync", "--recursive"},
+		{"-C", worktreePath, "submodule", "update", "--init", "--recursive"},
+	}
+	if opts.NoVerify {
+		cmdQueue = SkipHooks(cmdQueue)
+	}
+
+	err := git.ExecuteCommandsContext(c.context(), cmdQueue)
+	if err == nil {
+		return nil
+	}
+	if !opts.IgnoreSubmoduleErrors {
+		return fmt.Errorf("failed to update submodules: %w", err)
+	}
+
+	warning := fmt.Sprintf("Failed to update submodules: %v", err)
+	var cmdErr *git.CommandError
+	if errors.As(err, &cmdErr) {
+		warning = fmt.Sprintf("Failed to update submodules: %s", strings.TrimSpace(cmdErr.Output))
+	}
+	c.warn(warning)
+	return nil
+}
+
+// errHeadRefOverridden is passed to createFromFork instead of a fetch error
+// when --remote-branch rules out fetching the pull ref
+var errHeadRefOverridden = errors.New("the pull ref cannot be used with --remote-branch")
+
+// errForkOverridden is passed to createFromFork instead of a fetch error when
+// --fork names a fork other than the PR's head repository
+var errForkOverridden = errors.New("the pull ref cannot be used with --fork")
+
+// overrideFork points the PR's head repository at the fork of owner (--fork),
+// so that its head branch is fetched from there rather than from whichever
+// fork the PR was opened from
+func (c *Creator) overrideFork(pr *github.PullRequest, owner string) {
+	if pr.Head.Repo.Name == "" {
+		// The PR's own fork was deleted; forks keep the repository's name by default
+		pr.Head.Repo.Name = c.repo.Name
+	}
+	fmt.Fprintf(os.Stderr, "Fetching %s from fork %s/%s\n", pr.Head.Ref, owner, pr.Head.Repo.Name)
+	pr.Head.Repo.Owner.Login = owner
+}
+
+// createFromFork adds the PR's fork as a remote and checks out its head branch directly.
+// It is used when fetching the pull ref failed with pullRefErr.
+func (c *Creator) createFromFork(worktreePath string, pr *github.PullRequest, opts *CheckoutOptions, branchName string, pullRefErr error) error {
+	if pr.Head.Repo.Name == "" {
+		return fmt.Errorf("failed to fetch PR #%d and its fork no longer exists: %w", pr.Number, pullRefErr)
+	}
+
+	forkURL, err := c.buildForkURL(pr)
 	if err != nil {
 		return err
 	}
 
-	// Store PR metadata in worktree git config
-	err = c.storePRMetadata(worktreePath, pr)
+	remoteName := pr.Head.Repo.Owner.Login
+	for _, remote := range c.remotes {
+		if remote.Name == remoteName {
+			return fmt.Errorf("failed to fetch PR #%d, and cannot add fork %s as remote %s because that remote already exists with URL %s: %w",
+				pr.Number, forkURL, remoteName, remote.URL, pullRefErr)
+		}
+	}
+
+	forkRemote := git.NewRemote(remoteName, forkURL)
+	cmds, err := c.cmdsForExistingRemote(forkRemote, pr, opts, worktreePath, branchName)
 	if err != nil {
-		return fmt.Errorf("failed to store PR metadata: %w", err)
+		return fmt.Errorf("failed to create commands for fork remote: %w", err)
 	}
 
-	// Run post-creation setup if not disabled
-	if !opts.NoSetup {
-		mainWorktree, err := git.GetMainWorktree()
+	cmdQueue := append([][]string{{"remote", "add", remoteName, forkURL}}, cmds...)
+	cmdQueue, err = c.appendFinishingCmds(cmdQueue, pr, opts, worktreePath, branchName)
+	if err != nil {
+		return err
+	}
+
+	// The remote is added under the lock so that concurrent checkouts from the same fork don't race
+	if err := c.lock(worktreePath); err != nil {
+		return err
+	}
+	if err := git.ExecuteCommandsContext(c.context(), cmdQueue); err != nil {
+		if errors.Is(pullRefErr, errHeadRefOverridden) || errors.Is(pullRefErr, errForkOverridden) {
+			return fmt.Errorf("failed to fetch branch %s from fork %s: %w", pr.Head.Ref, forkURL, err)
+		}
+		return fmt.Errorf("failed to fetch PR #%d both via %s and from fork %s: %w", pr.Number, c.pullRef(pr.Number), forkURL, err)
+	}
+	c.remotes = append(c.remotes, forkRemote)
+	return nil
+}
+
+// Setup mirrors or copies git config if requested and runs the post-creation setup in a
+// worktree created by Create, unless disabled.
+// It is separate from Create so that it never runs under the creation lock.
+func (c *Creator) Setup(worktreePath string, opts *CheckoutOptions) error {
+	if !opts.MirrorConfig && opts.ConfigSource == nil && opts.NoSetup && !opts.LinkNodeModules {
+		return nil
+	}
+
+	mainWorktree, err := git.GetMainWorktree()
+	if err != nil {
+		return fmt.Errorf("failed to get main worktree: %w", err)
+	}
+
+	if opts.MirrorConfig {
+		warnings, err := setup.MirrorConfig(worktreePath, mainWorktree)
 		if err != nil {
-			return fmt.Errorf("failed to get main worktree: %w", err)
+			return fmt.Errorf("failed to mirror git config: %w", err)
 		}
+		c.setupWarnings = append(c.setupWarnings, warnings...)
+	}
+
+	if opts.ConfigSource != nil {
+		warnings, err := setup.MirrorConfigFrom(worktreePath, git.GetBranchName(worktreePath), opts.ConfigSource.Path, opts.ConfigSource.Branch, mainWorktree)
+		if err != nil {
+			return fmt.Errorf("failed to copy git config: %w", err)
+		}
+		c.setupWarnings = append(c.setupWarnings, warnings...)
+	}
+
+	// Links come before the setup commands so that these can use them
+	warnings, err := setup.LinkDirs(worktreePath, mainWorktree, opts.LinkDirs(), !opts.NoSetup)
+	if err != nil {
+		return fmt.Errorf("failed to link directories: %w", err)
+	}
+	c.setupWarnings = append(c.setupWarnings, warnings...)
 
-		if err := setup.RunSetup(worktreePath, mainWorktree); err != nil {
+	if !opts.NoSetup {
+		warnings, err := setup.RunSetup(worktreePath, mainWorktree, opts.SetupProfile)
+		if err != nil {
 			return fmt.Errorf("failed to run setup: %w", err)
 		}
+		c.setupWarnings = append(c.setupWarnings, warnings...)
 	}
 
 	return nil
 }
 
+// createAtCommit creates a detached worktree at a specific commit of the PR.
+// The full PR head is fetched so that the commit can be validated to belong to the PR.
+func (c *Creator) createAtCommit(worktreePath string, pr *github.PullRequest, baseRemote *git.Remote, opts *CheckoutOptions) error {
+	if err := validate.PRNumber(pr.Number); err != nil {
+		return fmt.Errorf("invalid PR number: %w", err)
+	}
+	if err := validate.CommitSHA(opts.AtCommit); err != nil {
+		return err
+	}
+
+	ref := c.pullRef(pr.Number)
+	fetch := []string{"fetch", baseRemote.Name, ref}
+	if !c.fetchTags(opts) {
+		fetch = append(fetch, "--no-tags")
+	}
+	fetchQueue := [][]string{fetch}
+	if opts.NoVerify {
+		fetchQueue = SkipHooks(fetchQueue)
+	}
+	// FETCH_HEAD is shared with every other fetch, so it is only read under the lock
+	if err := c.lock(worktreePath); err != nil {
+		return err
+	}
+	if err := git.ExecuteCommandsContext(c.context(), fetchQueue); err != nil {
+		return err
+	}
+
+	isAncestor, err := git.IsAncestor(opts.AtCommit, "FETCH_HEAD")
+	if err != nil {
+		return err
+	}
+	if !isAncestor {
+		return fmt.Errorf("commit %s is not part of PR #%d", opts.AtCommit, pr.Number)
+	}
+
+	cmdQueue := [][]string{{"worktree", "add", "--detach", worktreePath, opts.AtCommit}}
+	if opts.FetchBase {
+		fetch, err := c.baseFetchCmd(pr, opts)
+		if err != nil {
+			return err
+		}
+		cmdQueue = append(cmdQueue, fetch)
+	}
+	if len(opts.Sparse) > 0 {
+		cmdQueue = SparseCheckout(cmdQueue, worktreePath, opts.Sparse)
+	}
+	if opts.NoVerify {
+		cmdQueue = SkipHooks(cmdQueue)
+	}
+	if err := git.ExecuteCommandsContext(c.context(), cmdQueue); err != nil {
+		return err
+	}
+
+	// Like other detached worktrees, it has no branch to record metadata on
+	return c.updateSubmodules(worktreePath, opts)
+}
+
+// context returns the context the creator's git commands run under
+func (c *Creator) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// SetupWarnings returns the warnings from submodule updates during Create and from Setup
+func (c *Creator) SetupWarnings() []string {
+	return c.setupWarnings
+}
+
 func (c *Creator) findBaseRemote() *git.Remote {
+	// A remote designated by remotes.fetch takes precedence
+	if c.fetchRemote != "" {
+		return c.remoteByName(c.fetchRemote)
+	}
+
 	// Prefer upstream remote if it exists
 	for _, remote := range c.remotes {
 		if remote.Name == "upstream" {
@@ -131,12 +707,37 @@ func (c *Creator) findBaseRemote() *git.Remote {
 	return nil
 }
 
+// pullRef returns the ref the PR's head is fetched from when its head repository
+// has no remote: refs/pull/{N}/head unless pull_ref_template is set
+func (c *Creator) pullRef(number int) string {
+	template := c.pullRefTemplate
+	if template == "" {
+		template = defaultPullRefTemplate
+	}
+	return strings.ReplaceAll(template, "{number}", strconv.Itoa(number))
+}
+
+// remoteByName returns the configured remote with the given name, or nil
+func (c *Creator) remoteByName(name string) *git.Remote {
+	for _, remote := range c.remotes {
+		if remote.Name == name {
+			return remote
+		}
+	}
+	return nil
+}
+
+// findHeadRemote returns the remote whose URL points to the PR's head
+// repository on the same host, or nil if there is none
 func (c *Creator) findHeadRemote(pr *github.PullRequest) *git.Remote {
-	headRepoName := pr.Head.Repo.Name
-	headOwner := pr.Head.Repo.Owner.Login
+	host := c.repo.Host
+	if host == "" {
+		host = "github.com"
+	}
+	head := git.RepoID{Host: host, Owner: pr.Head.Repo.Owner.Login, Name: pr.Head.Repo.Name}
 
 	for _, remote := range c.remotes {
-		if strings.Contains(remote.URL, headOwner) && strings.Contains(remote.URL, headRepoName) {
+		if remote.Repo != nil && remote.Repo.Equal(head) {
 			return remote
 		}
 	}
@@ -144,6 +745,23 @@ func (c *Creator) findHeadRemote(pr *github.PullRequest) *git.Remote {
 	return nil
 }
 
+// resolvePushRemote validates a --push-remote value, which is either a GitHub URL or the name of a configured remote
+func (c *Creator) resolvePushRemote(value string) (string, error) {
+	if strings.Contains(value, "://") {
+		if err := validate.URL(value); err != nil {
+			return "", fmt.Errorf("invalid push remote URL: %w", err)
+		}
+		return value, nil
+	}
+
+	for _, remote := range c.remotes {
+		if remote.Name == value {
+			return remote.Name, nil
+		}
+	}
+	return "", fmt.Errorf("push remote %s is neither a GitHub URL nor a configured remote", value)
+}
+
 func (c *Creator) isCrossRepoPR(pr *github.PullRequest) bool {
 	return pr.Head.Repo.Owner.Login != c.repo.Owner
 }
@@ -157,8 +775,14 @@ func (c *Creator) buildForkURL(pr *github.PullRequest) (string, error) {
 		return "", fmt.Errorf("invalid head repo owner: %w", err)
 	}
 
-	forkURL := fmt.Sprintf("https://github.com/%s/%s", pr.Head.Repo.Owner.Login, pr.Head.Repo.Name)
-	if err := validate.URL(forkURL); err != nil {
+	// Forks live on the same host as the repository, which may be GitHub Enterprise Server
+	host := c.repo.Host
+	if host == "" {
+		host = "github.com"
+	}
+
+	forkURL := fmt.Sprintf("https://%s/%s/%s", host, pr.Head.Repo.Owner.Login, pr.Head.Repo.Name)
+	if err := validate.HostURL(forkURL, host); err != nil {
 		return "", fmt.Errorf("invalid fork URL: %w", err)
 	}
 	
@@ -177,18 +801,25 @@ func (c *Creator) cmdsForExistingRemote(remote *git.Remote, pr *github.PullReque
 	var cmds [][]string
 	remoteBranch := fmt.Sprintf("%s/%s", remote.Name, pr.Head.Ref)
 
-	refSpec := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", pr.Head.Ref, remoteBranch)
-	if opts.Detach {
-		refSpec = fmt.Sprintf("+refs/heads/%s", pr.Head.Ref)
+	detachTarget := "FETCH_HEAD"
+	if opts.NoFetch {
+		detachTarget = fmt.Sprintf("refs/remotes/%s", remoteBranch)
+		if err := requireFetchedRef(detachTarget); err != nil {
+			return nil, err
+		}
+	} else {
+		refSpec := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", pr.Head.Ref, remoteBranch)
+		if opts.Detach {
+			refSpec = fmt.Sprintf("+refs/heads/%s", pr.Head.Ref)
+		}
+		cmds = append(cmds, c.fetchCmd(opts, remote.Name, refSpec))
 	}
 
-	cmds = append(cmds, []string{"fetch", remote.Name, refSpec, "--no-tags"})
-
 	if opts.Detach {
-		cmds = append(cmds, []string{"worktree", "add", "--detach", worktreePath, "FETCH_HEAD"})
+		cmds = append(cmds, []string{"worktree", "add", "--detach", worktreePath, detachTarget})
 	} else {
 		if git.BranchExists(branchName) {
-			if opts.Force {
+			if branchExistsPolicy(opts) == BranchExistsReset {
 				cmds = append(cmds, []string{"worktree", "add", "--force", worktreePath, branchName})
 				cmds = append(cmds, []string{"-C", worktreePath, "reset", "--hard", fmt.Sprintf("refs/remotes/%s", remoteBranch)})
 			} else {
@@ -207,8 +838,8 @@ func (c *Creator) cmdsForExistingRemote(remote *git.Remote, pr *github.PullReque
 			cmds = append(cmds, []string{"-C", worktreePath, "config", fmt.Sprintf("branch.%s.remote", branchName), remoteValue})
 			cmds = append(cmds, []string{"-C", worktreePath, "config", fmt.Sprintf("branch.%s.merge", branchName), fmt.Sprintf("refs/heads/%s", pr.Head.Ref)})
 			
-			// For cross-repo PRs, also set pushRemote to the same URL
-			if c.isCrossRepoPR(pr) {
+			// For cross-repo PRs, also set pushRemote to the same URL unless overridden
+			if c.isCrossRepoPR(pr) && opts.PushRemote == "" {
 				cmds = append(cmds, []string{"-C", worktreePath, "config", fmt.Sprintf("branch.%s.pushremote", branchName), remoteValue})
 			}
 		}
@@ -230,36 +861,68 @@ func (c *Creator) cmdsForMissingRemote(pr *github.PullRequest, baseRemote *git.R
 	}
 
 	var cmds [][]string
-	ref := fmt.Sprintf("refs/pull/%d/head", pr.Number)
+	ref := c.pullRef(pr.Number)
 
-	if opts.Detach {
-		cmds = append(cmds, []string{"fetch", baseRemote.Name, ref, "--no-tags"})
-		cmds = append(cmds, []string{"worktree", "add", "--detach", worktreePath, "FETCH_HEAD"})
-		return cmds, nil
-	}
+	if opts.NoFetch {
+		// The pull ref is expected to have been fetched to the same local ref,
+		// e.g. with `git fetch origin +refs/pull/123/head:refs/pull/123/head`
+		if err := requireFetchedRef(ref); err != nil {
+			return nil, err
+		}
+		switch {
+		case opts.Detach:
+			return [][]string{{"worktree", "add", "--detach", worktreePath, ref}}, nil
+		case !git.BranchExists(branchName):
+			cmds = append(cmds, []string{"worktree", "add", "-b", branchName, worktreePath, ref})
+		case branchExistsPolicy(opts) == BranchExistsReset:
+			cmds = append(cmds, []string{"worktree", "add", "--force", worktreePath, branchName})
+			cmds = append(cmds, []string{"-C", worktreePath, "reset", "--hard", ref})
+		default:
+			cmds = append(cmds, []string{"worktree", "add", worktreePath, branchName})
+			cmds = append(cmds, []string{"-C", worktreePath, "merge", "--ff-only", ref})
+		}
+	} else {
+		if opts.Detach {
+			cmds = append(cmds, c.fetchCmd(opts, baseRemote.Name, ref))
+			cmds = append(cmds, []string{"worktree", "add", "--detach", worktreePath, "FETCH_HEAD"})
+			return cmds, nil
+		}
+
+		fetch := c.fetchCmd(opts, baseRemote.Name, fmt.Sprintf("%s:%s", ref, branchName))
+		if branchExistsPolicy(opts) == BranchExistsReset {
+			fetch = append(fetch, "--force")
+		}
+		cmds = append(cmds, fetch)
 
-	fetchCmd := []string{"fetch", baseRemote.Name, fmt.Sprintf("%s:%s", ref, branchName), "--no-tags"}
-	if opts.Force {
-		fetchCmd = append(fetchCmd, "--force")
+		cmds = append(cmds, []string{"worktree", "add", worktreePath, branchName})
 	}
-	cmds = append(cmds, fetchCmd)
 
-	cmds = append(cmds, []string{"worktree", "add", worktreePath, branchName})
+	tracking, err := c.trackingCmds(pr, baseRemote, opts, worktreePath, branchName, ref)
+	if err != nil {
+		return nil, err
+	}
+	return append(cmds, tracking...), nil
+}
 
-	// Configure remote settings for the new worktree
+// trackingCmds returns the commands that configure where the branch of a PR
+// fetched via its pull ref from baseRemote pulls from and pushes to
+func (c *Creator) trackingCmds(pr *github.PullRequest, baseRemote *git.Remote, opts *CheckoutOptions, worktreePath, branchName, ref string) ([][]string, error) {
+	var cmds [][]string
 	remoteValue := baseRemote.Name
 	mergeRef := ref
-	
+
 	// For cross-repo PRs, always use the fork's URL
 	if c.isCrossRepoPR(pr) && pr.Head.Repo.Name != "" {
 		forkURL, err := c.buildForkURL(pr)
 		if err != nil {
 			return nil, err
 		}
-		
+
 		remoteValue = forkURL
 		mergeRef = fmt.Sprintf("refs/heads/%s", pr.Head.Ref)
-		cmds = append(cmds, []string{"-C", worktreePath, "config", fmt.Sprintf("branch.%s.pushRemote", branchName), forkURL})
+		if opts.PushRemote == "" {
+			cmds = append(cmds, []string{"-C", worktreePath, "config", fmt.Sprintf("branch.%s.pushRemote", branchName), forkURL})
+		}
 	} else if pr.MaintainerCanModify && pr.Head.Repo.Name != "" {
 		// For same-repo PRs with maintainer can modify, just update merge ref
 		mergeRef = fmt.Sprintf("refs/heads/%s", pr.Head.Ref)
@@ -271,13 +934,75 @@ func (c *Creator) cmdsForMissingRemote(pr *github.PullRequest, baseRemote *git.R
 	return cmds, nil
 }
 
-func (c *Creator) storePRMetadata(worktreePath string, pr *github.PullRequest) error {
+// SkipHooks prefixes each git command with `-c core.hooksPath=/dev/null` so that
+// no hooks (e.g. post-checkout) run. `git worktree add` has no --no-verify option,
+// so this is the only way to bypass hooks for it.
+func SkipHooks(cmds [][]string) [][]string {
+	skipped := make([][]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		skipped = append(skipped, append([]string{"-c", "core.hooksPath=/dev/null"}, cmd...))
+	}
+	return skipped
+}
+
+// SparseCheckout makes the `git worktree add` command in cmds skip the checkout and
+// inserts commands after it that limit the worktree to the given directories with
+// cone-mode sparse-checkout before checking out. Requires git 2.25 or later.
+func SparseCheckout(cmds [][]string, worktreePath string, paths []string) [][]string {
+	sparse := make([][]string, 0, len(cmds)+3)
+	for _, cmd := range cmds {
+		if len(cmd) < 2 || cmd[0] != "worktree" || cmd[1] != "add" {
+			sparse = append(sparse, cmd)
+			continue
+		}
+		sparse = append(sparse,
+			append([]string{"worktree", "add", "--no-checkout"}, cmd[2:]...),
+			[]string{"-C", worktreePath, "sparse-checkout", "init", "--cone"},
+			append([]string{"-C", worktreePath, "sparse-checkout", "set"}, paths...),
+			[]string{"-C", worktreePath, "checkout"},
+		)
+	}
+	return sparse
+}
+
+// requireFetchedRef checks that a ref that would normally be fetched is already
+// present, since --no-fetch checks out whatever a previous step fetched
+func requireFetchedRef(ref string) error {
+	if !git.RefExists(ref) {
+		return fmt.Errorf("%s not found: fetch it first or run without --no-fetch", ref)
+	}
+	return nil
+}
+
+// fetchCmd builds a git fetch command for a single refspec honoring the checkout options
+func (c *Creator) fetchCmd(opts *CheckoutOptions, remoteName, refSpec string) []string {
+	cmd := []string{"fetch", remoteName, refSpec}
+	if !c.fetchTags(opts) {
+		cmd = append(cmd, "--no-tags")
+	}
+	if opts.ShallowSince != "" {
+		cmd = append(cmd, fmt.Sprintf("--shallow-since=%s", opts.ShallowSince))
+	}
+	return cmd
+}
+
+// fetchTags reports whether fetches follow tags: --tags or --no-tags if given,
+// otherwise fetch.tags from the config
+func (c *Creator) fetchTags(opts *CheckoutOptions) bool {
+	if opts.Tags != nil {
+		return *opts.Tags
+	}
+	return c.tags
+}
+
+// storePRMetadata records the worktree type, PR number and title under the local branch name,
+// which differs from the head ref when --branch or --title-branch is used.
+func (c *Creator) storePRMetadata(branchName string, pr *github.PullRequest) error {
 	// Validate and sanitize inputs
-	if err := validate.BranchName(pr.Head.Ref); err != nil {
+	if err := validate.BranchName(branchName); err != nil {
 		return fmt.Errorf("invalid branch name: %w", err)
 	}
 
-	branchName := pr.Head.Ref
 	sanitizedTitle := validate.SanitizeForGitConfig(pr.Title)
 
 	// Validate PR number
@@ -285,16 +1010,11 @@ func (c *Creator) storePRMetadata(worktreePath string, pr *github.PullRequest) e
 		return fmt.Errorf("invalid PR number: %w", err)
 	}
 
-	// Set PR metadata
-	err := git.SetConfig(worktreePath, fmt.Sprintf("branch.%s.gh-worktree-pr-number", branchName), strconv.Itoa(pr.Number))
-	if err != nil {
-		return fmt.Errorf("failed to set PR number config: %w", err)
-	}
-
-	err = git.SetConfig(worktreePath, fmt.Sprintf("branch.%s.gh-worktree-pr-title", branchName), sanitizedTitle)
-	if err != nil {
-		return fmt.Errorf("failed to set PR title config: %w", err)
-	}
-
-	return nil
+	// Set PR metadata all at once so that a failure doesn't leave the branch
+	// half-classified
+	return setMetadataFields(branchName, []metadataField{
+		{field: metaType, value: "pr"},
+		{field: metaPRNumber, value: strconv.Itoa(pr.Number)},
+		{field: metaPRTitle, value: sanitizedTitle},
+	})
 }
@@ -0,0 +1,684 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/repository"
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/github"
+)
+
+func newTestPR(number int, headRef, headOwner, headRepo string) *github.PullRequest {
+	pr := &github.PullRequest{Number: number}
+	pr.Head.Ref = headRef
+	pr.Head.Repo.Name = headRepo
+	pr.Head.Repo.Owner.Login = headOwner
+	return pr
+}
+
+func TestCmdsForMissingRemote(t *testing.T) {
+	origin := &git.Remote{Name: "origin", URL: "https://github.com/owner/repo.git"}
+	c := &Creator{
+		remotes: []*git.Remote{origin},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+
+	tests := []struct {
+		name string
+		pr   *github.PullRequest
+		opts *CheckoutOptions
+		want [][]string
+	}{
+		{
+			name: "same-repo PR",
+			pr:   newTestPR(123, "feature", "owner", "repo"),
+			opts: &CheckoutOptions{},
+			want: [][]string{
+				{"fetch", "origin", "refs/pull/123/head:feature", "--no-tags"},
+				{"worktree", "add", "/tmp/repo-pr123", "feature"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.remote", "origin"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.merge", "refs/pull/123/head"},
+			},
+		},
+		{
+			name: "shallow since",
+			pr:   newTestPR(123, "feature", "owner", "repo"),
+			opts: &CheckoutOptions{ShallowSince: "2024-01-01", Force: true},
+			want: [][]string{
+				{"fetch", "origin", "refs/pull/123/head:feature", "--no-tags", "--shallow-since=2024-01-01", "--force"},
+				{"worktree", "add", "/tmp/repo-pr123", "feature"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.remote", "origin"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.merge", "refs/pull/123/head"},
+			},
+		},
+		{
+			name: "detached with shallow since",
+			pr:   newTestPR(123, "feature", "owner", "repo"),
+			opts: &CheckoutOptions{Detach: true, ShallowSince: "2024-01-01"},
+			want: [][]string{
+				{"fetch", "origin", "refs/pull/123/head", "--no-tags", "--shallow-since=2024-01-01"},
+				{"worktree", "add", "--detach", "/tmp/repo-pr123", "FETCH_HEAD"},
+			},
+		},
+		{
+			name: "cross-repo PR with explicit push remote",
+			pr:   newTestPR(456, "fix", "contributor", "repo"),
+			opts: &CheckoutOptions{PushRemote: "origin"},
+			want: [][]string{
+				{"fetch", "origin", "refs/pull/456/head:fix", "--no-tags"},
+				{"worktree", "add", "/tmp/repo-pr456", "fix"},
+				{"-C", "/tmp/repo-pr456", "config", "branch.fix.remote", "https://github.com/contributor/repo"},
+				{"-C", "/tmp/repo-pr456", "config", "branch.fix.merge", "refs/heads/fix"},
+			},
+		},
+		{
+			name: "cross-repo PR",
+			pr:   newTestPR(456, "fix", "contributor", "repo"),
+			opts: &CheckoutOptions{},
+			want: [][]string{
+				{"fetch", "origin", "refs/pull/456/head:fix", "--no-tags"},
+				{"worktree", "add", "/tmp/repo-pr456", "fix"},
+				{"-C", "/tmp/repo-pr456", "config", "branch.fix.pushRemote", "https://github.com/contributor/repo"},
+				{"-C", "/tmp/repo-pr456", "config", "branch.fix.remote", "https://github.com/contributor/repo"},
+				{"-C", "/tmp/repo-pr456", "config", "branch.fix.merge", "refs/heads/fix"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worktreePath := fmt.Sprintf("/tmp/repo-pr%d", tt.pr.Number)
+			got, err := c.cmdsForMissingRemote(tt.pr, origin, tt.opts, worktreePath, tt.pr.Head.Ref)
+			if err != nil {
+				t.Fatalf("cmdsForMissingRemote() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cmdsForMissingRemote() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalBranchName(t *testing.T) {
+	pr := newTestPR(456, "patch-1", "contributor", "repo")
+	pr.Title = "Fix login"
+
+	tests := []struct {
+		name string
+		opts *CheckoutOptions
+		want string
+	}{
+		{name: "head ref", opts: &CheckoutOptions{}, want: "patch-1"},
+		{name: "head naming", opts: &CheckoutOptions{BranchNaming: BranchNamingHead}, want: "patch-1"},
+		{name: "number naming", opts: &CheckoutOptions{BranchNaming: BranchNamingNumber}, want: "pr-456"},
+		{name: "--branch wins over naming", opts: &CheckoutOptions{BranchName: "mine", BranchNaming: BranchNamingNumber}, want: "mine"},
+		{name: "--title-branch wins over naming", opts: &CheckoutOptions{TitleBranch: true, BranchNaming: BranchNamingNumber}, want: github.TitleBranchName(pr)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LocalBranchName(pr, tt.opts)
+			if err != nil {
+				t.Fatalf("LocalBranchName() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LocalBranchName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// pr-{N} still tracks the PR's real head branch
+	origin := &git.Remote{Name: "origin", URL: "https://github.com/owner/repo.git"}
+	c := &Creator{
+		remotes: []*git.Remote{origin},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+	cmds, err := c.cmdsForMissingRemote(pr, origin, &CheckoutOptions{BranchNaming: BranchNamingNumber}, "/tmp/repo-pr456", "pr-456")
+	if err != nil {
+		t.Fatalf("cmdsForMissingRemote() error = %v", err)
+	}
+	want := []string{"-C", "/tmp/repo-pr456", "config", "branch.pr-456.merge", "refs/heads/patch-1"}
+	if got := cmds[len(cmds)-1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("last command = %v, want %v", got, want)
+	}
+}
+
+func TestCmdsNoFetch(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+	runGit(t, "-C", mainPath, "update-ref", "refs/remotes/origin/topic", "HEAD")
+	runGit(t, "-C", mainPath, "update-ref", "refs/pull/123/head", "HEAD")
+
+	origin := &git.Remote{Name: "origin", URL: "https://github.com/owner/repo.git"}
+	c := &Creator{
+		remotes: []*git.Remote{origin},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+
+	tests := []struct {
+		name    string
+		pr      *github.PullRequest
+		missing bool
+		opts    *CheckoutOptions
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name: "existing remote",
+			pr:   newTestPR(1, "topic", "owner", "repo"),
+			opts: &CheckoutOptions{NoFetch: true},
+			want: [][]string{
+				{"worktree", "add", "-b", "topic", "/tmp/repo-pr1", "origin/topic"},
+				{"-C", "/tmp/repo-pr1", "config", "branch.topic.remote", "origin"},
+				{"-C", "/tmp/repo-pr1", "config", "branch.topic.merge", "refs/heads/topic"},
+			},
+		},
+		{
+			name: "existing remote detached",
+			pr:   newTestPR(1, "topic", "owner", "repo"),
+			opts: &CheckoutOptions{NoFetch: true, Detach: true},
+			want: [][]string{
+				{"worktree", "add", "--detach", "/tmp/repo-pr1", "refs/remotes/origin/topic"},
+			},
+		},
+		{
+			name:    "existing remote without fetched ref",
+			pr:      newTestPR(1, "other", "owner", "repo"),
+			opts:    &CheckoutOptions{NoFetch: true},
+			wantErr: true,
+		},
+		{
+			name:    "missing remote",
+			pr:      newTestPR(123, "fix", "owner", "repo"),
+			missing: true,
+			opts:    &CheckoutOptions{NoFetch: true},
+			want: [][]string{
+				{"worktree", "add", "-b", "fix", "/tmp/repo-pr123", "refs/pull/123/head"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.fix.remote", "origin"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.fix.merge", "refs/pull/123/head"},
+			},
+		},
+		{
+			name:    "missing remote with existing branch",
+			pr:      newTestPR(123, "feature", "owner", "repo"),
+			missing: true,
+			opts:    &CheckoutOptions{NoFetch: true},
+			want: [][]string{
+				{"worktree", "add", "/tmp/repo-pr123", "feature"},
+				{"-C", "/tmp/repo-pr123", "merge", "--ff-only", "refs/pull/123/head"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.remote", "origin"},
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.merge", "refs/pull/123/head"},
+			},
+		},
+		{
+			name:    "missing remote without fetched ref",
+			pr:      newTestPR(124, "fix", "owner", "repo"),
+			missing: true,
+			opts:    &CheckoutOptions{NoFetch: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worktreePath := fmt.Sprintf("/tmp/repo-pr%d", tt.pr.Number)
+			var got [][]string
+			var err error
+			if tt.missing {
+				got, err = c.cmdsForMissingRemote(tt.pr, origin, tt.opts, worktreePath, tt.pr.Head.Ref)
+			} else {
+				got, err = c.cmdsForExistingRemote(origin, tt.pr, tt.opts, worktreePath, tt.pr.Head.Ref)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePushRemote(t *testing.T) {
+	c := &Creator{
+		remotes: []*git.Remote{
+			{Name: "origin", URL: "https://github.com/owner/repo.git"},
+			{Name: "fork", URL: "https://github.com/me/repo.git"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "named remote",
+			value: "fork",
+			want:  "fork",
+		},
+		{
+			name:  "GitHub URL",
+			value: "https://github.com/shared/repo",
+			want:  "https://github.com/shared/repo",
+		},
+		{
+			name:    "unknown remote",
+			value:   "upstream",
+			wantErr: true,
+		},
+		{
+			name:    "non-GitHub URL",
+			value:   "https://example.com/shared/repo",
+			wantErr: true,
+		},
+		{
+			name:    "non-HTTPS URL",
+			value:   "ssh://git@github.com/shared/repo",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.resolvePushRemote(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolvePushRemote(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("resolvePushRemote(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipHooks(t *testing.T) {
+	cmds := [][]string{
+		{"fetch", "origin", "feature", "--no-tags"},
+		{"worktree", "add", "../repo-pr1", "feature"},
+	}
+
+	got := SkipHooks(cmds)
+	want := [][]string{
+		{"-c", "core.hooksPath=/dev/null", "fetch", "origin", "feature", "--no-tags"},
+		{"-c", "core.hooksPath=/dev/null", "worktree", "add", "../repo-pr1", "feature"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SkipHooks() = %v, want %v", got, want)
+	}
+
+	// The input queue must not be modified
+	if cmds[0][0] != "fetch" {
+		t.Errorf("SkipHooks() modified its input: %v", cmds)
+	}
+}
+
+func TestSparseCheckout(t *testing.T) {
+	cmds := [][]string{
+		{"fetch", "origin", "feature", "--no-tags"},
+		{"worktree", "add", "-b", "feature", "../repo-pr1", "origin/feature"},
+		{"-C", "../repo-pr1", "config", "branch.feature.remote", "origin"},
+	}
+
+	got := SparseCheckout(cmds, "../repo-pr1", []string{"services/api", "libs"})
+	want := [][]string{
+		{"fetch", "origin", "feature", "--no-tags"},
+		{"worktree", "add", "--no-checkout", "-b", "feature", "../repo-pr1", "origin/feature"},
+		{"-C", "../repo-pr1", "sparse-checkout", "init", "--cone"},
+		{"-C", "../repo-pr1", "sparse-checkout", "set", "services/api", "libs"},
+		{"-C", "../repo-pr1", "checkout"},
+		{"-C", "../repo-pr1", "config", "branch.feature.remote", "origin"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SparseCheckout() = %v, want %v", got, want)
+	}
+
+	// The input queue must not be modified
+	if len(cmds[1]) != 6 {
+		t.Errorf("SparseCheckout() modified its input: %v", cmds)
+	}
+}
+
+func TestBaseFetchCmd(t *testing.T) {
+	c := &Creator{
+		remotes: []*git.Remote{
+			{Name: "origin", URL: "https://github.com/me/repo.git"},
+			{Name: "upstream", URL: "https://github.com/owner/repo.git"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		baseRef      string
+		shallowSince string
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:    "fetches into the base remote's tracking ref",
+			baseRef: "main",
+			want:    []string{"fetch", "upstream", "+refs/heads/main:refs/remotes/upstream/main", "--no-tags"},
+		},
+		{
+			name:         "honors shallow-since",
+			baseRef:      "release/1.x",
+			shallowSince: "2024-01-01",
+			want:         []string{"fetch", "upstream", "+refs/heads/release/1.x:refs/remotes/upstream/release/1.x", "--no-tags", "--shallow-since=2024-01-01"},
+		},
+		{
+			name:    "invalid base ref",
+			baseRef: "-main",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := &github.PullRequest{}
+			pr.Base.Ref = tt.baseRef
+			got, err := c.baseFetchCmd(pr, &CheckoutOptions{FetchBase: true, ShallowSince: tt.shallowSince})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("baseFetchCmd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("baseFetchCmd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchCmd(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name       string
+		configTags bool
+		tags       *bool
+		want       []string
+	}{
+		{
+			name: "no tags by default",
+			want: []string{"fetch", "origin", "refs/pull/1/head", "--no-tags"},
+		},
+		{
+			name:       "fetch.tags follows tags",
+			configTags: true,
+			want:       []string{"fetch", "origin", "refs/pull/1/head"},
+		},
+		{
+			name: "--tags follows tags",
+			tags: &yes,
+			want: []string{"fetch", "origin", "refs/pull/1/head"},
+		},
+		{
+			name:       "--no-tags overrides fetch.tags",
+			configTags: true,
+			tags:       &no,
+			want:       []string{"fetch", "origin", "refs/pull/1/head", "--no-tags"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Creator{tags: tt.configTags}
+			got := c.fetchCmd(&CheckoutOptions{Tags: tt.tags}, "origin", "refs/pull/1/head")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fetchCmd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildForkURL(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{
+			name: "github.com",
+			host: "github.com",
+			want: "https://github.com/contributor/repo",
+		},
+		{
+			name: "GitHub Enterprise Server",
+			host: "ghe.example.com",
+			want: "https://ghe.example.com/contributor/repo",
+		},
+		{
+			name: "unknown host defaults to github.com",
+			host: "",
+			want: "https://github.com/contributor/repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Creator{repo: repository.Repository{Host: tt.host, Owner: "owner", Name: "repo"}}
+			got, err := c.buildForkURL(newTestPR(123, "patch-1", "contributor", "repo"))
+			if err != nil {
+				t.Fatalf("buildForkURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("buildForkURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateFromFork_Errors(t *testing.T) {
+	pullRefErr := fmt.Errorf("pull refs are disabled")
+	c := &Creator{
+		remotes: []*git.Remote{
+			{Name: "origin", URL: "https://github.com/owner/repo.git"},
+			{Name: "contributor", URL: "https://github.com/contributor/other.git"},
+		},
+		repo: repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+
+	tests := []struct {
+		name string
+		pr   *github.PullRequest
+	}{
+		{
+			name: "deleted fork",
+			pr:   newTestPR(123, "patch-1", "contributor", ""),
+		},
+		{
+			name: "remote name already taken",
+			pr:   newTestPR(123, "patch-1", "contributor", "repo"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.createFromFork("/tmp/repo-pr123", tt.pr, &CheckoutOptions{}, "patch-1", pullRefErr)
+			if err == nil {
+				t.Fatal("createFromFork() expected error, got nil")
+			}
+			if !errors.Is(err, pullRefErr) {
+				t.Errorf("createFromFork() error = %v, want it to wrap the pull ref error", err)
+			}
+		})
+	}
+}
+
+func TestCreate_RemoteBranchSkipsPullRef(t *testing.T) {
+	c := &Creator{
+		remotes: []*git.Remote{{Name: "origin", URL: "https://github.com/owner/repo.git"}},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+
+	// Without a remote for the fork, the overridden branch can only come from the
+	// fork itself, which no longer exists here
+	pr := newTestPR(123, "other-branch", "contributor", "")
+	err := c.Create("/tmp/repo-pr123", pr, &CheckoutOptions{RemoteBranch: "other-branch"})
+	if !errors.Is(err, errHeadRefOverridden) {
+		t.Errorf("Create() error = %v, want errHeadRefOverridden", err)
+	}
+}
+
+func TestCreate_RemoteBranchNoFetch(t *testing.T) {
+	c := &Creator{
+		remotes: []*git.Remote{{Name: "origin", URL: "https://github.com/owner/repo.git"}},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+
+	// Fetching from the fork would add a remote and fetch despite --no-fetch
+	pr := newTestPR(123, "other-branch", "contributor", "repo")
+	err := c.Create("/tmp/repo-pr123", pr, &CheckoutOptions{RemoteBranch: "other-branch", NoFetch: true})
+	if err == nil || !strings.Contains(err.Error(), "--no-fetch") {
+		t.Errorf("Create() error = %v, want --no-fetch error", err)
+	}
+}
+
+func TestCreate_Fork(t *testing.T) {
+	c := &Creator{
+		remotes: []*git.Remote{
+			{Name: "origin", URL: "https://github.com/owner/repo.git"},
+			{Name: "other", URL: "https://github.com/other/unrelated.git"},
+		},
+		repo: repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+
+	// The PR's own fork was deleted, so the fork is assumed to keep the
+	// repository's name. No remote matches other/repo and the remote name is
+	// taken, so the fork can't be fetched and the pull ref isn't tried.
+	pr := newTestPR(123, "patch-1", "contributor", "")
+	err := c.Create("/tmp/repo-pr123", pr, &CheckoutOptions{Fork: "other"})
+	if !errors.Is(err, errForkOverridden) {
+		t.Errorf("Create() error = %v, want errForkOverridden", err)
+	}
+	if pr.Head.Repo.Owner.Login != "other" || pr.Head.Repo.Name != "repo" {
+		t.Errorf("head repo = %s/%s, want other/repo", pr.Head.Repo.Owner.Login, pr.Head.Repo.Name)
+	}
+}
+
+func TestSeparateFetchAndPushRemotes(t *testing.T) {
+	c := &Creator{
+		remotes: []*git.Remote{
+			{Name: "upstream", URL: "https://github.com/owner/repo.git"},
+			{Name: "mirror", URL: "https://mirror.example.com/owner/repo.git"},
+		},
+		repo:        repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+		fetchRemote: "mirror",
+		pushRemote:  "upstream",
+	}
+
+	if got := c.findBaseRemote(); got == nil || got.Name != "mirror" {
+		t.Errorf("findBaseRemote() = %+v, want mirror", got)
+	}
+
+	tests := []struct {
+		name string
+		pr   *github.PullRequest
+		opts *CheckoutOptions
+		want [][]string
+	}{
+		{
+			name: "same-repo PR",
+			pr:   newTestPR(123, "feature", "owner", "repo"),
+			opts: &CheckoutOptions{},
+			want: [][]string{
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.pushRemote", "upstream"},
+			},
+		},
+		{
+			name: "explicit push remote",
+			pr:   newTestPR(123, "feature", "owner", "repo"),
+			opts: &CheckoutOptions{PushRemote: "mirror"},
+			want: [][]string{
+				{"-C", "/tmp/repo-pr123", "config", "branch.feature.pushRemote", "mirror"},
+			},
+		},
+		{
+			name: "cross-repo PR is pushed to the fork",
+			pr:   newTestPR(123, "feature", "contributor", "repo"),
+			opts: &CheckoutOptions{},
+			want: nil,
+		},
+		{
+			name: "detached",
+			pr:   newTestPR(123, "feature", "owner", "repo"),
+			opts: &CheckoutOptions{Detach: true},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.appendFinishingCmds(nil, tt.pr, tt.opts, "/tmp/repo-pr123", "feature")
+			if err != nil {
+				t.Fatalf("appendFinishingCmds() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCmdsForMissingRemote_PullRefTemplate(t *testing.T) {
+	origin := &git.Remote{Name: "origin", URL: "https://git.example.com/owner/repo.git"}
+	c := &Creator{
+		remotes:         []*git.Remote{origin},
+		repo:            repository.Repository{Host: "git.example.com", Owner: "owner", Name: "repo"},
+		pullRefTemplate: "refs/merge-requests/{number}/head",
+	}
+
+	got, err := c.cmdsForMissingRemote(newTestPR(123, "feature", "owner", "repo"), origin, &CheckoutOptions{}, "/tmp/repo-pr123", "feature")
+	if err != nil {
+		t.Fatalf("cmdsForMissingRemote() error = %v", err)
+	}
+	want := [][]string{
+		{"fetch", "origin", "refs/merge-requests/123/head:feature", "--no-tags"},
+		{"worktree", "add", "/tmp/repo-pr123", "feature"},
+		{"-C", "/tmp/repo-pr123", "config", "branch.feature.remote", "origin"},
+		{"-C", "/tmp/repo-pr123", "config", "branch.feature.merge", "refs/merge-requests/123/head"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commands =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestFindHeadRemote(t *testing.T) {
+	c := &Creator{
+		remotes: []*git.Remote{
+			git.NewRemote("origin", "https://github.com/owner/repo.git"),
+			git.NewRemote("alice", "git@github.com:alice/repo-tools.git"),
+			git.NewRemote("bob", "https://github.com/Bob/repo/"),
+			git.NewRemote("mirror", "https://mirror.example.com/carol/repo"),
+		},
+		repo: repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+
+	tests := []struct {
+		name string
+		pr   *github.PullRequest
+		want string
+	}{
+		{name: "trailing slash and case", pr: newTestPR(1, "fix", "bob", "repo"), want: "bob"},
+		{name: "owner substring is not a match", pr: newTestPR(1, "fix", "alice", "repo"), want: ""},
+		{name: "other host is not a match", pr: newTestPR(1, "fix", "carol", "repo"), want: ""},
+		{name: "scp-like URL", pr: newTestPR(1, "fix", "alice", "repo-tools"), want: "alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.findHeadRemote(tt.pr)
+			gotName := ""
+			if got != nil {
+				gotName = got.Name
+			}
+			if gotName != tt.want {
+				t.Errorf("findHeadRemote() = %q, want %q", gotName, tt.want)
+			}
+		})
+	}
+}
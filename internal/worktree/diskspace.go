@@ -0,0 +1,130 @@
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+)
+
+// checkDiskSpace returns an error if the filesystem that will hold
+// worktreePath has less free space than the worktree is estimated to need:
+// the size of the files at HEAD of the main worktree, plus the object
+// database for isolated clones, which copy it. The check is skipped on
+// platforms where free space can't be determined.
+func checkDiskSpace(worktreePath string, isolated bool) error {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+	required, err := checkoutSize(gitRoot)
+	if err != nil {
+		return err
+	}
+	if isolated {
+		commonDir, err := git.GetCommonDir()
+		if err != nil {
+			return err
+		}
+		objects, err := dirSize(filepath.Join(commonDir, "objects"))
+		if err != nil {
+			return fmt.Errorf("failed to measure object database: %w", err)
+		}
+		required += objects
+	}
+
+	dir := existingAncestor(filepath.Dir(worktreePath))
+	available, ok := freeSpace(dir)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Warning: can't determine free disk space in %s; skipping the check\n", dir)
+		return nil
+	}
+	if available < required {
+		return fmt.Errorf("not enough disk space in %s: the worktree needs about %s but only %s is available",
+			dir, formatBytes(required), formatBytes(available))
+	}
+	return nil
+}
+
+// checkoutSize returns the total size of the files at HEAD of the worktree at
+// path, as a rough estimate of what another checkout takes. Submodules are
+// not counted.
+func checkoutSize(path string) (uint64, error) {
+	output, err := git.Command("-C", path, "ls-tree", "-r", "-l", "-z", "HEAD").Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list files at HEAD: %w", err)
+	}
+
+	var total uint64
+	for _, entry := range bytes.Split(output, []byte{0}) {
+		// <mode> SP <type> SP <object> SP+ <size> TAB <path>
+		meta, _, ok := strings.Cut(string(entry), "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		size, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// dirSize returns the total size of the regular files under dir
+func dirSize(dir string) (uint64, error) {
+	var total uint64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += uint64(info.Size())
+		return nil
+	})
+	return total, err
+}
+
+// existingAncestor returns dir, or its closest ancestor that exists, since
+// the parent of a nested or label worktree may not have been created yet
+func existingAncestor(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// formatBytes formats n with a binary unit, e.g. "1.5 GiB"
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package worktree
+
+import "syscall"
+
+// freeSpace returns the space available to unprivileged users on the
+// filesystem containing dir
+func freeSpace(dir string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
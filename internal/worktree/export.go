@@ -0,0 +1,31 @@
+package worktree
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ShellExports returns `export` statements describing the worktree for a shell
+// function to eval: GH_WORKTREE_PATH, and GH_WORKTREE_BRANCH and GH_WORKTREE_PR
+// when the worktree has a branch or a PR. Values are single-quoted, so they are
+// safe to eval whatever they contain.
+func ShellExports(wt *Info) string {
+	var b strings.Builder
+	export := func(name, value string) {
+		b.WriteString("export " + name + "=" + shellQuote(value) + "\n")
+	}
+	export("GH_WORKTREE_PATH", wt.Path)
+	if wt.Branch != "" {
+		export("GH_WORKTREE_BRANCH", wt.Branch)
+	}
+	if wt.PRNumber > 0 {
+		export("GH_WORKTREE_PR", strconv.Itoa(wt.PRNumber))
+	}
+	return b.String()
+}
+
+// shellQuote quotes s for POSIX shells. Nothing is special inside single
+// quotes, so only single quotes themselves need to be closed, escaped and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
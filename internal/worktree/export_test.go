@@ -0,0 +1,60 @@
+package worktree
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestShellExports(t *testing.T) {
+	tests := []struct {
+		name string
+		wt   *Info
+		want string
+	}{
+		{
+			name: "PR worktree",
+			wt:   &Info{Path: "/src/repo-pr42", Branch: "fix", PRNumber: 42},
+			want: "export GH_WORKTREE_PATH='/src/repo-pr42'\nexport GH_WORKTREE_BRANCH='fix'\nexport GH_WORKTREE_PR='42'\n",
+		},
+		{
+			name: "branch worktree",
+			wt:   &Info{Path: "/src/repo-feature", Branch: "feature"},
+			want: "export GH_WORKTREE_PATH='/src/repo-feature'\nexport GH_WORKTREE_BRANCH='feature'\n",
+		},
+		{
+			name: "detached worktree",
+			wt:   &Info{Path: "/src/repo-pr7", PRNumber: 7, Detached: true},
+			want: "export GH_WORKTREE_PATH='/src/repo-pr7'\nexport GH_WORKTREE_PR='7'\n",
+		},
+		{
+			name: "quotes in the path",
+			wt:   &Info{Path: "/src/it's here"},
+			want: "export GH_WORKTREE_PATH='/src/it'\\''s here'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShellExports(tt.wt); got != tt.want {
+				t.Errorf("ShellExports() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellExports_Eval(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Values that would run commands or split words if they weren't quoted
+	path := "/tmp/$(echo pwned) `id` it's; \"x\"\n$HOME"
+	script := ShellExports(&Info{Path: path}) + `printf %s "$GH_WORKTREE_PATH"`
+	out, err := exec.Command("sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("sh failed: %v", err)
+	}
+	if got := string(out); got != path {
+		t.Errorf("GH_WORKTREE_PATH = %q, want %q", got, path)
+	}
+}
@@ -0,0 +1,173 @@
+package worktree
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/github"
+)
+
+// prSuffixPattern matches the "-pr<number>" suffix of PR worktree directories
+var prSuffixPattern = regexp.MustCompile(`-pr(\d+)$`)
+
+// FindByIdentifier finds a worktree by PR number, PR URL, or branch name.
+// PR numbers are matched first, then branch names across both PR and branch worktrees.
+// Returns nil if no worktree matches, and an error if the branch name is ambiguous.
+func FindByIdentifier(prWorktrees, branchWorktrees []*Info, identifier string) (*Info, error) {
+	// Try to parse as PR number
+	if prNum, err := github.ParsePRNumber(identifier); err == nil {
+		for _, wt := range prWorktrees {
+			if wt.PRNumber == prNum {
+				return wt, nil
+			}
+		}
+	}
+
+	// If not found as PR, try to find as branch name
+	var matches []*Info
+	for _, wt := range append(append([]*Info{}, prWorktrees...), branchWorktrees...) {
+		if wt.Branch == identifier {
+			matches = append(matches, wt)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	default:
+		var paths []string
+		for _, wt := range matches {
+			paths = append(paths, wt.Path)
+		}
+		return nil, fmt.Errorf("branch %s matches multiple worktrees: %s", identifier, strings.Join(paths, ", "))
+	}
+}
+
+// FindByBranch returns the worktree that has branch checked out, including the
+// main worktree and worktrees not created by gh worktree. Returns nil if the
+// branch isn't checked out anywhere.
+func FindByBranch(branch string) (*Info, error) {
+	worktrees, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == branch {
+			return wt, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindByPath returns the worktree containing path, with its PR and base metadata filled in.
+// path must belong to the repository of the current directory.
+// Returns nil if path is not inside any worktree.
+func FindByPath(path string) (*Info, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path %s: %w", path, err)
+	}
+
+	worktrees, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer the innermost worktree in case one is nested inside another
+	var found *Info
+	for _, wt := range worktrees {
+		if containsPath(wt.Path, absPath) && (found == nil || len(wt.Path) > len(found.Path)) {
+			found = wt
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+
+	// The first entry is the main worktree, whose name says nothing about a PR
+	isMain := found == worktrees[0]
+	if m := prSuffixPattern.FindStringSubmatch(filepath.Base(found.Path)); m != nil && !isMain {
+		found.PRNumber, _ = strconv.Atoi(m[1])
+	}
+	if found.Branch != "" {
+		if found.PRNumber == 0 {
+			if prNumberStr, err := getMetadata(found.Branch, metaPRNumber); err == nil {
+				found.PRNumber, _ = strconv.Atoi(strings.TrimSpace(prNumberStr))
+			}
+		}
+		found.Title = GetPRTitle(found.Branch)
+		found.BaseBranch, found.BaseCommit = GetBranchBase(found.Branch)
+	}
+
+	return found, nil
+}
+
+// FindDuplicate returns a linked worktree that already has the PR checked out,
+// so that a near-duplicate isn't created under another path: one on localBranch
+// (the branch the checkout would use, "" if detached), one recorded for the PR
+// number, or one on the PR's head branch. A branch named like the head branch
+// only counts for same-repository PRs or if it tracks the PR's head repository,
+// since fork branches such as patch-1 are often unrelated.
+// Returns nil if there is none.
+func FindDuplicate(pr *github.PullRequest, localBranch string) (*Info, error) {
+	worktrees, err := List()
+	if err != nil {
+		return nil, err
+	}
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git root: %w", err)
+	}
+
+	for _, wt := range worktrees {
+		if wt.Branch == "" || wt.Path == gitRoot {
+			continue
+		}
+		if localBranch != "" && wt.Branch == localBranch {
+			return wt, nil
+		}
+		if prNumber, err := getMetadata(wt.Branch, metaPRNumber); err == nil && prNumber == strconv.Itoa(pr.Number) {
+			return wt, nil
+		}
+		if wt.Branch == pr.Head.Ref && (isSameRepoPR(pr) || tracksHead(gitRoot, wt.Branch, pr)) {
+			return wt, nil
+		}
+	}
+	return nil, nil
+}
+
+// isSameRepoPR reports whether the PR's head branch is in its base repository
+func isSameRepoPR(pr *github.PullRequest) bool {
+	head := pr.Head.Repo.Owner.Login + "/" + pr.Head.Repo.Name
+	return pr.Base.Repo.FullName != "" && strings.EqualFold(head, pr.Base.Repo.FullName)
+}
+
+// tracksHead reports whether branch's upstream is the PR's head branch in the
+// PR's head repository
+func tracksHead(gitRoot, branch string, pr *github.PullRequest) bool {
+	merge, err := git.GetConfig(gitRoot, fmt.Sprintf("branch.%s.merge", branch))
+	if err != nil || merge != "refs/heads/"+pr.Head.Ref {
+		return false
+	}
+	remote, err := git.GetConfig(gitRoot, fmt.Sprintf("branch.%s.remote", branch))
+	if err != nil {
+		return false
+	}
+	// The remote is either a remote name or, for fork PRs, the fork's URL
+	remoteURL := remote
+	if remotes, err := git.GetRemotes(); err == nil {
+		for _, r := range remotes {
+			if r.Name == remote {
+				remoteURL = r.URL
+			}
+		}
+	}
+	repo, ok := git.ParseRemoteURL(remoteURL)
+	return ok && strings.EqualFold(repo.Owner, pr.Head.Repo.Owner.Login) && strings.EqualFold(repo.Name, pr.Head.Repo.Name)
+}
@@ -0,0 +1,83 @@
+package worktree
+
+import (
+	"testing"
+)
+
+func TestFindByIdentifier(t *testing.T) {
+	prWorktrees := []*Info{
+		{Path: "/src/repo-pr123", Branch: "feature-auth", PRNumber: 123},
+		{Path: "/src/repo-pr456", Branch: "fix-login", PRNumber: 456},
+		{Path: "/src/repo-pr789", Branch: "shared", PRNumber: 789},
+	}
+	branchWorktrees := []*Info{
+		{Path: "/src/repo-experiment", Branch: "experiment"},
+		{Path: "/src/repo-shared", Branch: "shared"},
+		{Path: "/src/repo-42", Branch: "42"},
+	}
+
+	tests := []struct {
+		name       string
+		identifier string
+		wantPath   string
+		wantErr    bool
+	}{
+		{
+			name:       "PR number",
+			identifier: "123",
+			wantPath:   "/src/repo-pr123",
+		},
+		{
+			name:       "PR URL",
+			identifier: "https://github.com/owner/repo/pull/456",
+			wantPath:   "/src/repo-pr456",
+		},
+		{
+			name:       "branch name of PR worktree",
+			identifier: "fix-login",
+			wantPath:   "/src/repo-pr456",
+		},
+		{
+			name:       "branch name of branch worktree",
+			identifier: "experiment",
+			wantPath:   "/src/repo-experiment",
+		},
+		{
+			name:       "numeric branch name without matching PR",
+			identifier: "42",
+			wantPath:   "/src/repo-42",
+		},
+		{
+			name:       "ambiguous branch name",
+			identifier: "shared",
+			wantErr:    true,
+		},
+		{
+			name:       "PR number takes precedence over ambiguous branch",
+			identifier: "789",
+			wantPath:   "/src/repo-pr789",
+		},
+		{
+			name:       "not found",
+			identifier: "unknown",
+			wantPath:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindByIdentifier(prWorktrees, branchWorktrees, tt.identifier)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FindByIdentifier(%q) error = %v, wantErr %v", tt.identifier, err, tt.wantErr)
+				return
+			}
+			gotPath := ""
+			if got != nil {
+				gotPath = got.Path
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("FindByIdentifier(%q) = %q, want %q", tt.identifier, gotPath, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// HistoryFile is the name of the file in the git common directory that holds
+// the paths of recently switched-to worktrees, most recent first.
+const HistoryFile = "gh-worktree-history"
+
+// historySize is the number of paths kept in the history file.
+const historySize = 10
+
+// RecordSwitch moves worktreePath to the front of the history file at path,
+// creating the file if it doesn't exist and dropping the oldest entries
+// beyond the history size.
+func RecordSwitch(path, worktreePath string) error {
+	history, err := readHistory(path)
+	if err != nil {
+		return err
+	}
+
+	entries := []string{worktreePath}
+	for _, entry := range history {
+		if entry != worktreePath && len(entries) < historySize {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Join(entries, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write switch history: %w", err)
+	}
+	return nil
+}
+
+// RecentPaths returns the paths in the history file at path, most recent
+// first, skipping those that no longer exist. A missing file is an empty
+// history.
+func RecentPaths(path string) ([]string, error) {
+	history, err := readHistory(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range history {
+		if _, err := os.Stat(entry); err == nil {
+			paths = append(paths, entry)
+		}
+	}
+	return paths, nil
+}
+
+func readHistory(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read switch history: %w", err)
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,75 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecentPaths(t *testing.T) {
+	dir := t.TempDir()
+	var existing []string
+	for i := 0; i < historySize+2; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("repo-pr%d", i))
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		existing = append(existing, path)
+	}
+	removed := filepath.Join(dir, "repo-removed")
+
+	tests := []struct {
+		name     string
+		switches []string
+		want     []string
+	}{
+		{
+			name: "no history",
+			want: nil,
+		},
+		{
+			name:     "most recent first",
+			switches: []string{existing[0], existing[1], existing[2]},
+			want:     []string{existing[2], existing[1], existing[0]},
+		},
+		{
+			name:     "switching again moves the path to the front",
+			switches: []string{existing[0], existing[1], existing[0]},
+			want:     []string{existing[0], existing[1]},
+		},
+		{
+			name:     "missing paths are skipped",
+			switches: []string{existing[0], removed, existing[1]},
+			want:     []string{existing[1], existing[0]},
+		},
+		{
+			name:     "oldest entries are dropped",
+			switches: existing,
+			want: []string{
+				existing[11], existing[10], existing[9], existing[8], existing[7],
+				existing[6], existing[5], existing[4], existing[3], existing[2],
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyPath := filepath.Join(t.TempDir(), HistoryFile)
+			for _, path := range tt.switches {
+				if err := RecordSwitch(historyPath, path); err != nil {
+					t.Fatalf("RecordSwitch() error = %v", err)
+				}
+			}
+
+			got, err := RecentPaths(historyPath)
+			if err != nil {
+				t.Fatalf("RecentPaths() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecentPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/github"
+	"github.com/knqyf263/gh-worktree/internal/validate"
+)
+
+// isolatedKey lists the paths of isolated clones (--isolate-git-dir) in the
+// repository config of the main worktree. git worktree list doesn't know about
+// them, since they have their own .git directory.
+const isolatedKey = "gh-worktree.isolated"
+
+// createIsolated creates a standalone clone of the repository at worktreePath
+// instead of a linked worktree. Objects are copied rather than hardlinked, so
+// gc or corruption in the main repository doesn't affect the clone. The PR's
+// metadata is recorded in the clone's own config.
+func (c *Creator) createIsolated(worktreePath string, pr *github.PullRequest, opts *CheckoutOptions) error {
+	baseRemote := c.findBaseRemote()
+	if baseRemote == nil {
+		return fmt.Errorf("no suitable remote found")
+	}
+	if err := validate.PRNumber(pr.Number); err != nil {
+		return fmt.Errorf("invalid PR number: %w", err)
+	}
+	branchName, err := LocalBranchName(pr, opts)
+	if err != nil {
+		return err
+	}
+	if err := validate.BranchName(branchName); err != nil {
+		return fmt.Errorf("invalid branch name: %w", err)
+	}
+
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+
+	// The clone starts without a checkout, so the PR is checked out with
+	// --force to populate the working tree even if it has the same branch
+	ref := c.pullRef(pr.Number)
+	cmdQueue := [][]string{
+		{"clone", "--no-hardlinks", "--no-checkout", "--origin", baseRemote.Name, gitRoot, worktreePath},
+		{"-C", worktreePath, "remote", "set-url", baseRemote.Name, baseRemote.URL},
+		append([]string{"-C", worktreePath}, c.fetchCmd(opts, baseRemote.Name, ref)...),
+		{"-C", worktreePath, "checkout", "--force", "-B", branchName, "FETCH_HEAD"},
+	}
+	tracking, err := c.trackingCmds(pr, baseRemote, opts, worktreePath, branchName, ref)
+	if err != nil {
+		return err
+	}
+	cmdQueue = append(cmdQueue, tracking...)
+	if opts.NoVerify {
+		cmdQueue = SkipHooks(cmdQueue)
+	}
+	if err := git.ExecuteCommandsContext(c.context(), cmdQueue); err != nil {
+		return err
+	}
+
+	metadata := map[string]string{
+		metaType:     "pr",
+		metaPRNumber: strconv.Itoa(pr.Number),
+		metaPRTitle:  validate.SanitizeForGitConfig(pr.Title),
+	}
+	for field, value := range metadata {
+		if err := git.SetConfig(worktreePath, metadataKey(branchName, field), value); err != nil {
+			return fmt.Errorf("failed to store PR metadata: %w", err)
+		}
+	}
+	if err := c.lock(worktreePath); err != nil {
+		return err
+	}
+	if err := git.AddConfig(gitRoot, isolatedKey, worktreePath); err != nil {
+		return fmt.Errorf("failed to register isolated clone: %w", err)
+	}
+
+	return c.updateSubmodules(worktreePath, opts)
+}
+
+// listIsolated returns the isolated clones registered in the main worktree at
+// gitRoot with their PR metadata. Clones whose directory is gone are skipped.
+func listIsolated(gitRoot string) ([]*Info, error) {
+	paths, err := git.GetConfigAll(gitRoot, isolatedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read isolated clones: %w", err)
+	}
+
+	var clones []*Info
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		wt := &Info{Path: path, Commit: git.GetHeadCommit(path), Isolated: true}
+		branch := git.GetBranchName(path)
+		if branch == "" || branch == "HEAD" {
+			wt.Detached = true
+			clones = append(clones, wt)
+			continue
+		}
+		wt.Branch = branch
+		if prNumber, err := git.GetConfig(path, metadataKey(branch, metaPRNumber)); err == nil {
+			wt.PRNumber, _ = strconv.Atoi(prNumber)
+		}
+		wt.Title, _ = git.GetConfig(path, metadataKey(branch, metaPRTitle))
+		clones = append(clones, wt)
+	}
+	return clones, nil
+}
+
+// IsIsolated reports whether path is an isolated clone created with --isolate-git-dir
+func IsIsolated(path string) bool {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return false
+	}
+	_, ok := isolatedEntry(gitRoot, path)
+	return ok
+}
+
+// isolatedEntry returns the registered path of the isolated clone at path
+func isolatedEntry(gitRoot, path string) (string, bool) {
+	paths, err := git.GetConfigAll(gitRoot, isolatedKey)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range paths {
+		if sameFile(entry, path) {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// sameFile reports whether a and b are the same path after resolving symlinks
+func sameFile(a, b string) bool {
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// removeIsolated deletes an isolated clone and its registration. Unless force
+// is set, it refuses to delete a clone with uncommitted changes.
+func removeIsolated(gitRoot, entry string, force bool) error {
+	if _, err := os.Stat(entry); err == nil && !force {
+		output, err := git.Command("-C", entry, "status", "--porcelain").Output()
+		if err != nil {
+			return fmt.Errorf("failed to check status of %s: %w", entry, err)
+		}
+		if strings.TrimSpace(string(output)) != "" {
+			return fmt.Errorf("isolated clone %s has uncommitted changes; use --force to remove it anyway", entry)
+		}
+	}
+
+	if err := os.RemoveAll(entry); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", entry, err)
+	}
+	return git.UnsetConfigValue(gitRoot, isolatedKey, entry)
+}
@@ -0,0 +1,1602 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/repository"
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/github"
+)
+
+// setupLinkedRepo creates a repository "repo" with a PR worktree "repo-pr42"
+// and a branch worktree "repo-feature" next to it, and returns the main worktree path.
+func setupLinkedRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	mainPath := filepath.Join(dir, "repo")
+
+	run := func(args ...string) { runGit(t, args...) }
+
+	run("init", "-q", mainPath)
+	run("-C", mainPath, "commit", "-q", "--allow-empty", "-m", "init")
+	run("-C", mainPath, "worktree", "add", "-q", "-b", "pr-branch", filepath.Join(dir, "repo-pr42"))
+	run("-C", mainPath, "config", "branch.pr-branch.gh-worktree-type", "pr")
+	run("-C", mainPath, "config", "branch.pr-branch.gh-worktree-pr-number", "42")
+	run("-C", mainPath, "worktree", "add", "-q", "-b", "feature", filepath.Join(dir, "repo-feature"))
+	run("-C", mainPath, "config", "branch.feature.gh-worktree-type", "branch")
+
+	return mainPath
+}
+
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestLinkedWorktreeResolution(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{name: "main worktree", dir: mainPath},
+		{name: "PR worktree", dir: filepath.Join(parent, "repo-pr42")},
+		{name: "branch worktree", dir: filepath.Join(parent, "repo-feature")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(tt.dir)
+
+			root, err := git.GetRoot()
+			if err != nil {
+				t.Fatalf("GetRoot() error = %v", err)
+			}
+			if root != mainPath {
+				t.Errorf("GetRoot() = %s, want %s", root, mainPath)
+			}
+
+			// list
+			prWorktrees, branchWorktrees, err := ListAllWorktrees("repo")
+			if err != nil {
+				t.Fatalf("ListAllWorktrees() error = %v", err)
+			}
+			if len(prWorktrees) != 1 || prWorktrees[0].PRNumber != 42 {
+				t.Fatalf("ListAllWorktrees() PR worktrees = %+v, want only #42", prWorktrees)
+			}
+			if len(branchWorktrees) != 1 || branchWorktrees[0].Branch != "feature" {
+				t.Fatalf("ListAllWorktrees() branch worktrees = %+v, want only feature", branchWorktrees)
+			}
+
+			// switch
+			wt, err := FindByIdentifier(prWorktrees, branchWorktrees, "42")
+			if err != nil || wt == nil {
+				t.Fatalf("FindByIdentifier(42) = %v, %v", wt, err)
+			}
+
+			// remove resolves the same paths that list reports
+			prPath, err := GeneratePath("repo", 42)
+			if err != nil {
+				t.Fatalf("GeneratePath() error = %v", err)
+			}
+			if prPath != wt.Path {
+				t.Errorf("GeneratePath() = %s, want %s", prPath, wt.Path)
+			}
+			branchPath, err := GeneratePathForBranch("repo", "feature")
+			if err != nil {
+				t.Fatalf("GeneratePathForBranch() error = %v", err)
+			}
+			if branchPath != branchWorktrees[0].Path {
+				t.Errorf("GeneratePathForBranch() = %s, want %s", branchPath, branchWorktrees[0].Path)
+			}
+
+			if got := DetectWorktreeType(mainPath); got != "main" {
+				t.Errorf("DetectWorktreeType(%s) = %s, want main", mainPath, got)
+			}
+		})
+	}
+}
+
+func TestRemoveFromInsideWorktree(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	prPath := filepath.Join(filepath.Dir(mainPath), "repo-pr42")
+
+	t.Chdir(prPath)
+
+	if err := Remove(prPath, false); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(prPath); !os.IsNotExist(err) {
+		t.Errorf("worktree %s still exists", prPath)
+	}
+
+	// The branch can still be deleted even though the original working directory is gone
+	if err := DeleteBranch("pr-branch"); err != nil {
+		t.Fatalf("DeleteBranch() error = %v", err)
+	}
+	if git.BranchExists("pr-branch") {
+		t.Error("branch pr-branch still exists")
+	}
+}
+
+func TestRemoveMissingDirectory(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	prPath := filepath.Join(filepath.Dir(mainPath), "repo-pr42")
+	t.Chdir(mainPath)
+
+	if err := os.RemoveAll(prPath); err != nil {
+		t.Fatalf("failed to delete worktree directory: %v", err)
+	}
+	if registered, _ := IsRegistered(prPath); !registered {
+		t.Fatalf("IsRegistered(%s) = false, want the stale entry to remain", prPath)
+	}
+
+	if err := Remove(prPath, false); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if registered, _ := IsRegistered(prPath); registered {
+		t.Errorf("worktree %s is still registered", prPath)
+	}
+	// The branch is no longer checked out and can be deleted
+	if err := DeleteBranch("pr-branch"); err != nil {
+		t.Errorf("DeleteBranch() error = %v", err)
+	}
+}
+
+func TestFindByPath(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+
+	prSubdir := filepath.Join(parent, "repo-pr42", "sub")
+	if err := os.Mkdir(prSubdir, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantPath   string
+		wantPR     int
+		wantBranch string
+	}{
+		{
+			name:       "PR worktree subdirectory",
+			path:       prSubdir,
+			wantPath:   filepath.Join(parent, "repo-pr42"),
+			wantPR:     42,
+			wantBranch: "pr-branch",
+		},
+		{
+			name:       "branch worktree",
+			path:       filepath.Join(parent, "repo-feature"),
+			wantPath:   filepath.Join(parent, "repo-feature"),
+			wantBranch: "feature",
+		},
+		{
+			name:     "main worktree",
+			path:     mainPath,
+			wantPath: mainPath,
+		},
+		{
+			name: "outside any worktree",
+			path: parent,
+		},
+	}
+
+	t.Chdir(filepath.Join(parent, "repo-feature"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindByPath(tt.path)
+			if err != nil {
+				t.Fatalf("FindByPath(%s) error = %v", tt.path, err)
+			}
+			if tt.wantPath == "" {
+				if got != nil {
+					t.Errorf("FindByPath(%s) = %+v, want nil", tt.path, got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("FindByPath(%s) = nil, want %s", tt.path, tt.wantPath)
+			}
+			if got.Path != tt.wantPath {
+				t.Errorf("FindByPath(%s).Path = %s, want %s", tt.path, got.Path, tt.wantPath)
+			}
+			if got.PRNumber != tt.wantPR {
+				t.Errorf("FindByPath(%s).PRNumber = %d, want %d", tt.path, got.PRNumber, tt.wantPR)
+			}
+			if tt.wantBranch != "" && got.Branch != tt.wantBranch {
+				t.Errorf("FindByPath(%s).Branch = %s, want %s", tt.path, got.Branch, tt.wantBranch)
+			}
+		})
+	}
+}
+
+func TestListRelocatedWorktrees(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+
+	// Worktrees created with --into-current live outside the main worktree's parent
+	toolsDir := filepath.Join(mainPath, "tools")
+	if err := os.Mkdir(toolsDir, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	t.Chdir(toolsDir)
+
+	prPath, err := GeneratePathIn(toolsDir, "repo", 7)
+	if err != nil {
+		t.Fatalf("GeneratePathIn() error = %v", err)
+	}
+	branchPath, err := GeneratePathForBranchIn(toolsDir, "repo", "scratch")
+	if err != nil {
+		t.Fatalf("GeneratePathForBranchIn() error = %v", err)
+	}
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "relocated-pr", prPath)
+	runGit(t, "-C", mainPath, "config", "branch.relocated-pr.gh-worktree-pr-number", "7")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "scratch", branchPath)
+	runGit(t, "-C", mainPath, "config", "branch.scratch.gh-worktree-type", "branch")
+	// A foreign worktree without metadata is still ignored
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "other", filepath.Join(toolsDir, "repo-other"))
+
+	prWorktrees, branchWorktrees, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+
+	wt, err := FindByIdentifier(prWorktrees, nil, "7")
+	if err != nil || wt == nil || wt.Path != prPath {
+		t.Errorf("FindByIdentifier(7) = %+v, %v, want worktree at %s", wt, err, prPath)
+	}
+	wt, err = FindByIdentifier(nil, branchWorktrees, "scratch")
+	if err != nil || wt == nil || wt.Path != branchPath {
+		t.Errorf("FindByIdentifier(scratch) = %+v, %v, want worktree at %s", wt, err, branchPath)
+	}
+	if wt, _ := FindByIdentifier(prWorktrees, branchWorktrees, "other"); wt != nil {
+		t.Errorf("FindByIdentifier(other) = %+v, want nil", wt)
+	}
+}
+
+func TestListWorktreesInUnrelatedDirectory(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	elsewhere, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	prPath := filepath.Join(elsewhere, "review")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "review", prPath)
+	runGit(t, "-C", mainPath, "config", "branch.review.gh-worktree-type", "pr")
+	runGit(t, "-C", mainPath, "config", "branch.review.gh-worktree-pr-number", "9")
+	branchPath := filepath.Join(elsewhere, "experiment")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "experiment", branchPath)
+	runGit(t, "-C", mainPath, "config", "branch.experiment.gh-worktree-type", "branch")
+	// Without metadata, only the directory name next to the main worktree counts
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "legacy", filepath.Join(filepath.Dir(mainPath), "repo-pr8"))
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "foreign", filepath.Join(elsewhere, "repo-pr10"))
+
+	prWorktrees, branchWorktrees, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+
+	var gotPRs []string
+	for _, wt := range prWorktrees {
+		gotPRs = append(gotPRs, fmt.Sprintf("%d:%s", wt.PRNumber, wt.Branch))
+	}
+	wantPRs := []string{"8:legacy", "9:review", "42:pr-branch"}
+	if !reflect.DeepEqual(gotPRs, wantPRs) {
+		t.Errorf("PR worktrees = %v, want %v", gotPRs, wantPRs)
+	}
+
+	var gotBranches []string
+	for _, wt := range branchWorktrees {
+		gotBranches = append(gotBranches, wt.Branch)
+	}
+	wantBranches := []string{"experiment", "feature"}
+	if !reflect.DeepEqual(gotBranches, wantBranches) {
+		t.Errorf("branch worktrees = %v, want %v", gotBranches, wantBranches)
+	}
+}
+
+func TestCurrentBaseDir(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	sub := filepath.Join(dir, "tools")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	t.Chdir(sub)
+
+	got, err := CurrentBaseDir()
+	if err != nil {
+		t.Fatalf("CurrentBaseDir() error = %v", err)
+	}
+	if got != dir {
+		t.Errorf("CurrentBaseDir() = %s, want %s", got, dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("CurrentBaseDir() left files behind: %v", entries)
+	}
+}
+
+func TestCleanUntrackedFiles(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	wtPath := filepath.Join(filepath.Dir(mainPath), "repo-feature")
+
+	if err := os.WriteFile(filepath.Join(wtPath, "stale.o"), nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(wtPath, "build", "out"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, "build", "out", "bin"), nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	files, err := UntrackedFiles(wtPath)
+	if err != nil {
+		t.Fatalf("UntrackedFiles() error = %v", err)
+	}
+	want := []string{"build/", "stale.o"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("UntrackedFiles() = %v, want %v", files, want)
+	}
+
+	if err := Clean(wtPath); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	files, err = UntrackedFiles(wtPath)
+	if err != nil {
+		t.Fatalf("UntrackedFiles() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("UntrackedFiles() after Clean() = %v, want none", files)
+	}
+}
+
+func TestIsRegistered(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	orphan := filepath.Join(parent, "repo-pr99")
+	if err := os.Mkdir(orphan, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{name: "main worktree", path: mainPath, want: true},
+		{name: "linked worktree", path: filepath.Join(parent, "repo-pr42"), want: true},
+		{name: "orphaned directory", path: orphan, want: false},
+		{name: "missing path", path: filepath.Join(parent, "repo-pr100"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsRegistered(tt.path)
+			if err != nil {
+				t.Fatalf("IsRegistered(%s) error = %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("IsRegistered(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	wtPath := filepath.Join(filepath.Dir(mainPath), "repo-pr42")
+	run := func(args ...string) { runGit(t, args...) }
+	commitFile := func(dir, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		run("-C", dir, "add", name)
+		run("-C", dir, "commit", "-q", "-m", name)
+	}
+
+	// Rebase runs git without the test environment, so it needs an identity in config
+	run("-C", mainPath, "config", "user.name", "test")
+	run("-C", mainPath, "config", "user.email", "test@example.com")
+
+	commitFile(wtPath, "pr.txt", "pr\n")
+	run("-C", mainPath, "checkout", "-q", "-b", "base")
+	commitFile(mainPath, "base.txt", "base\n")
+
+	if err := Rebase(wtPath, "missing-ref", false); err == nil {
+		t.Error("Rebase() onto missing ref expected error")
+	}
+	if err := Rebase(wtPath, "--exec=sh", false); err == nil {
+		t.Error("Rebase() onto invalid ref expected error")
+	}
+
+	if err := Rebase(wtPath, "base", false); err != nil {
+		t.Fatalf("Rebase() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "base.txt")); err != nil {
+		t.Errorf("expected base commit in rebased worktree: %v", err)
+	}
+
+	commitFile(wtPath, "conflict.txt", "pr\n")
+	commitFile(mainPath, "conflict.txt", "base\n")
+
+	err := Rebase(wtPath, "base", false)
+	var conflictErr *RebaseConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Rebase() error = %v, want *RebaseConflictError", err)
+	}
+	if !reflect.DeepEqual(conflictErr.Files, []string{"conflict.txt"}) {
+		t.Errorf("RebaseConflictError.Files = %v, want [conflict.txt]", conflictErr.Files)
+	}
+	if !rebaseInProgress(wtPath) {
+		t.Error("expected worktree to be left in the rebase state")
+	}
+}
+
+func TestListPromotedWorktrees(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	// A branch whose name looks like a PR directory once sanitized
+	prLikePath, err := GeneratePathForBranch("repo", "pr5")
+	if err != nil {
+		t.Fatalf("GeneratePathForBranch() error = %v", err)
+	}
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "pr5", prLikePath)
+	runGit(t, "-C", mainPath, "config", "branch.pr5.gh-worktree-type", "branch")
+
+	if err := PromoteToPR("feature", 77, "Add feature"); err != nil {
+		t.Fatalf("PromoteToPR() error = %v", err)
+	}
+
+	prWorktrees, branchWorktrees, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+
+	prNumbers := make(map[string]int)
+	for _, wt := range prWorktrees {
+		prNumbers[filepath.Base(wt.Path)] = wt.PRNumber
+	}
+	want := map[string]int{"repo-pr42": 42, "repo-feature": 77}
+	if !reflect.DeepEqual(prNumbers, want) {
+		t.Errorf("PR worktrees = %v, want %v", prNumbers, want)
+	}
+
+	var branches []string
+	for _, wt := range branchWorktrees {
+		branches = append(branches, wt.Branch)
+	}
+	if !reflect.DeepEqual(branches, []string{"pr5"}) {
+		t.Errorf("branch worktrees = %v, want [pr5]", branches)
+	}
+
+	wt, err := FindByIdentifier(prWorktrees, branchWorktrees, "77")
+	if err != nil || wt == nil || wt.Path != filepath.Join(parent, "repo-feature") {
+		t.Errorf("FindByIdentifier(77) = %+v, %v, want promoted worktree", wt, err)
+	}
+}
+
+func TestDemoteToBranch(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	if err := PromoteToPR("feature", 77, "Add feature"); err != nil {
+		t.Fatalf("PromoteToPR() error = %v", err)
+	}
+	// Demote both the promoted worktree and one created for a PR
+	for _, branch := range []string{"feature", "pr-branch"} {
+		if err := DemoteToBranch(branch); err != nil {
+			t.Fatalf("DemoteToBranch(%s) error = %v", branch, err)
+		}
+		if got, _ := GetWorktreeType(branch); got != "branch" {
+			t.Errorf("GetWorktreeType(%s) = %q, want branch", branch, got)
+		}
+	}
+
+	prWorktrees, branchWorktrees, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+	if len(prWorktrees) != 0 {
+		t.Errorf("PR worktrees = %+v, want none", prWorktrees)
+	}
+
+	var branches []string
+	for _, wt := range branchWorktrees {
+		branches = append(branches, wt.Branch)
+		if wt.PRNumber != 0 || wt.Title != "" {
+			t.Errorf("branch worktree %s still has PR metadata: %+v", wt.Branch, wt)
+		}
+	}
+	sort.Strings(branches)
+	if !reflect.DeepEqual(branches, []string{"feature", "pr-branch"}) {
+		t.Errorf("branch worktrees = %v, want [feature pr-branch]", branches)
+	}
+
+	// Demoting again is harmless
+	if err := DemoteToBranch("feature"); err != nil {
+		t.Errorf("DemoteToBranch() on a branch worktree error = %v", err)
+	}
+}
+
+func TestMetadataSurvivesWorktreeReAdd(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	featurePath := filepath.Join(parent, "repo-feature")
+
+	// With per-worktree config enabled, metadata written from a linked worktree
+	// must still go to the shared repository config
+	runGit(t, "-C", mainPath, "config", "extensions.worktreeConfig", "true")
+	t.Chdir(filepath.Join(parent, "repo-pr42"))
+
+	if err := PromoteToPR("feature", 77, "Add feature"); err != nil {
+		t.Fatalf("PromoteToPR() error = %v", err)
+	}
+	c := &Creator{}
+	pr := newTestPR(77, "feature", "owner", "repo")
+	pr.Title = "Add feature, updated"
+	if err := c.storePRMetadata("feature", pr); err != nil {
+		t.Fatalf("storePRMetadata() error = %v", err)
+	}
+
+	if err := Remove(featurePath, false); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", featurePath, "feature")
+
+	t.Chdir(mainPath)
+	prWorktrees, err := ListPRWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListPRWorktrees() error = %v", err)
+	}
+	wt, err := FindByIdentifier(prWorktrees, nil, "77")
+	if err != nil || wt == nil {
+		t.Fatalf("FindByIdentifier(77) = %+v, %v, want the re-added worktree", wt, err)
+	}
+	if wt.Path != featurePath || wt.Title != "Add feature, updated" {
+		t.Errorf("re-added worktree = %+v, want %s with the stored title", wt, featurePath)
+	}
+}
+
+func TestStorePRMetadataRollback(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	// The branch was a branch worktree before; its type must survive the failure
+	if err := setMetadata("feature", metaBase, "main"); err != nil {
+		t.Fatalf("setMetadata() error = %v", err)
+	}
+
+	injected := errors.New("injected failure")
+	calls := 0
+	setConfig = func(path, key, value string) error {
+		calls++
+		if calls == 2 {
+			return injected
+		}
+		return git.SetConfig(path, key, value)
+	}
+	t.Cleanup(func() { setConfig = git.SetConfig })
+
+	pr := newTestPR(9, "feature", "owner", "repo")
+	pr.Title = "Partial metadata"
+	err := (&Creator{}).storePRMetadata("feature", pr)
+	if !errors.Is(err, injected) {
+		t.Fatalf("storePRMetadata() error = %v, want the injected error", err)
+	}
+
+	if got, _ := GetWorktreeType("feature"); got != "branch" {
+		t.Errorf("GetWorktreeType() = %q, want the previous type branch", got)
+	}
+	for _, field := range []string{metaPRNumber, metaPRTitle} {
+		if value, err := getMetadata("feature", field); err == nil {
+			t.Errorf("%s = %q, want it unset", field, value)
+		}
+	}
+	if base, _ := getMetadata("feature", metaBase); base != "main" {
+		t.Errorf("base = %q, want untouched main", base)
+	}
+}
+
+func TestDemoteToBranchRollback(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	if err := PromoteToPR("feature", 77, "Add feature"); err != nil {
+		t.Fatalf("PromoteToPR() error = %v", err)
+	}
+
+	// Fail removing the title after the type and number were updated
+	injected := errors.New("injected failure")
+	calls := 0
+	unsetConfig = func(path, key string) error {
+		calls++
+		if calls == 2 {
+			return injected
+		}
+		return git.UnsetConfig(path, key)
+	}
+	t.Cleanup(func() { unsetConfig = git.UnsetConfig })
+
+	if err := DemoteToBranch("feature"); !errors.Is(err, injected) {
+		t.Fatalf("DemoteToBranch() error = %v, want the injected error", err)
+	}
+
+	if got, _ := GetWorktreeType("feature"); got != "pr" {
+		t.Errorf("GetWorktreeType() = %q, want the previous type pr", got)
+	}
+	if number, _ := getMetadata("feature", metaPRNumber); number != "77" {
+		t.Errorf("pr-number = %q, want the restored 77", number)
+	}
+	if title := GetPRTitle("feature"); title != "Add feature" {
+		t.Errorf("pr-title = %q, want untouched Add feature", title)
+	}
+}
+
+func TestListAllWorktreesClassifiesOnce(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	// A promoted worktree matches the branch naming pattern but is a PR worktree
+	if err := PromoteToPR("feature", 77, "Add feature"); err != nil {
+		t.Fatalf("PromoteToPR() error = %v", err)
+	}
+	// A PR-named directory recorded as a branch worktree, and a PR worktree
+	// with a lower number than the existing ones
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "pr9-branch", filepath.Join(parent, "repo-pr9"))
+	runGit(t, "-C", mainPath, "config", "branch.pr9-branch.gh-worktree-type", "branch")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "fix", filepath.Join(parent, "repo-pr3"))
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "another", filepath.Join(parent, "repo-another"))
+
+	prWorktrees, branchWorktrees, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+
+	var prNumbers []int
+	for _, wt := range prWorktrees {
+		prNumbers = append(prNumbers, wt.PRNumber)
+	}
+	if want := []int{3, 42, 77}; !reflect.DeepEqual(prNumbers, want) {
+		t.Errorf("PR worktrees = %v, want %v", prNumbers, want)
+	}
+
+	var branches []string
+	for _, wt := range branchWorktrees {
+		branches = append(branches, wt.Branch)
+	}
+	if want := []string{"another", "pr9-branch"}; !reflect.DeepEqual(branches, want) {
+		t.Errorf("branch worktrees = %v, want %v", branches, want)
+	}
+
+	seen := make(map[string]bool)
+	for _, wt := range append(prWorktrees, branchWorktrees...) {
+		if seen[wt.Path] {
+			t.Errorf("worktree %s is listed twice", wt.Path)
+		}
+		seen[wt.Path] = true
+	}
+}
+
+func TestNestedLocation(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	configYAML := "worktree:\n  location: nested\n"
+	if err := os.WriteFile(filepath.Join(mainPath, ".gh-worktree.yml"), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	prPath, err := GeneratePath("repo", 7)
+	if err != nil {
+		t.Fatalf("GeneratePath() error = %v", err)
+	}
+	if want := filepath.Join(mainPath, NestedDirName, "pr7"); prPath != want {
+		t.Errorf("GeneratePath() = %s, want %s", prPath, want)
+	}
+	branchPath, err := GeneratePathForBranch("repo", "team/scratch")
+	if err != nil {
+		t.Fatalf("GeneratePathForBranch() error = %v", err)
+	}
+	if want := filepath.Join(mainPath, NestedDirName, "team-scratch"); branchPath != want {
+		t.Errorf("GeneratePathForBranch() = %s, want %s", branchPath, want)
+	}
+
+	// Nested worktrees are recognized by name without metadata
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "nested-pr", prPath)
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "team/scratch", branchPath)
+
+	prWorktrees, branchWorktrees, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+	wt, err := FindByIdentifier(prWorktrees, nil, "7")
+	if err != nil || wt == nil || wt.Path != prPath {
+		t.Errorf("FindByIdentifier(7) = %+v, %v, want worktree at %s", wt, err, prPath)
+	}
+	wt, err = FindByIdentifier(nil, branchWorktrees, "team/scratch")
+	if err != nil || wt == nil || wt.Path != branchPath {
+		t.Errorf("FindByIdentifier(team/scratch) = %+v, %v, want worktree at %s", wt, err, branchPath)
+	}
+	// Sibling worktrees are still listed
+	if wt, _ := FindByIdentifier(prWorktrees, nil, "42"); wt == nil {
+		t.Error("FindByIdentifier(42) = nil, want sibling worktree")
+	}
+
+	if err := os.WriteFile(filepath.Join(mainPath, ".gh-worktree.yml"), []byte("worktree:\n  location: inside\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := GeneratePath("repo", 7); err == nil {
+		t.Error("GeneratePath() with invalid location expected error")
+	}
+}
+
+func TestLabelDir(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	enabled, err := ResolveLabelDir(false)
+	if err != nil || enabled {
+		t.Errorf("ResolveLabelDir(false) = %v, %v, want false without config", enabled, err)
+	}
+	if err := os.WriteFile(filepath.Join(mainPath, ".gh-worktree.yml"), []byte("worktree:\n  label_dir: true\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	enabled, err = ResolveLabelDir(false)
+	if err != nil || !enabled {
+		t.Errorf("ResolveLabelDir(false) = %v, %v, want true with worktree.label_dir", enabled, err)
+	}
+
+	pr := &github.PullRequest{Number: 7, Labels: []github.Label{{Name: "kind/bug"}, {Name: "docs"}}}
+	prPath, err := GeneratePathForPRByLabel("repo", pr, NameFromNumber)
+	if err != nil {
+		t.Fatalf("GeneratePathForPRByLabel() error = %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(mainPath), "repo-worktrees", "kind-bug", "pr7"); prPath != want {
+		t.Errorf("GeneratePathForPRByLabel() = %s, want %s", prPath, want)
+	}
+
+	// PRs without labels get the usual path
+	unlabeledPath, err := GeneratePathForPRByLabel("repo", &github.PullRequest{Number: 8}, NameFromNumber)
+	if err != nil {
+		t.Fatalf("GeneratePathForPRByLabel() error = %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(mainPath), "repo-pr8"); unlabeledPath != want {
+		t.Errorf("GeneratePathForPRByLabel() = %s, want %s", unlabeledPath, want)
+	}
+
+	// Label directories are searched by name without metadata
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "--detach", prPath)
+	prWorktrees, _, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+	wt, err := FindByIdentifier(prWorktrees, nil, "7")
+	if err != nil || wt == nil || wt.Path != prPath {
+		t.Errorf("FindByIdentifier(7) = %+v, %v, want worktree at %s", wt, err, prPath)
+	}
+
+	if err := os.WriteFile(filepath.Join(mainPath, ".gh-worktree.yml"), []byte("worktree:\n  location: nested\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	nestedPath, err := GeneratePathForPRByLabel("repo", pr, NameFromNumber)
+	if err != nil {
+		t.Fatalf("GeneratePathForPRByLabel() error = %v", err)
+	}
+	if want := filepath.Join(mainPath, NestedDirName, "kind-bug", "pr7"); nestedPath != want {
+		t.Errorf("GeneratePathForPRByLabel() = %s, want %s", nestedPath, want)
+	}
+}
+
+func TestIsolatedClone(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	server := filepath.Join(filepath.Dir(mainPath), "server.git")
+	runGit(t, "init", "-q", "--bare", server)
+	runGit(t, "-C", mainPath, "push", "-q", server, "HEAD:refs/pull/7/head")
+	runGit(t, "-C", mainPath, "remote", "add", "origin", server)
+
+	c := &Creator{
+		remotes: []*git.Remote{{Name: "origin", URL: server}},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+	clonePath := filepath.Join(filepath.Dir(mainPath), "repo-pr7")
+	pr := newTestPR(7, "isolated-branch", "owner", "repo")
+	pr.Title = "Risky history surgery"
+	if err := c.Create(clonePath, pr, &CheckoutOptions{IsolateGitDir: true}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The clone has its own .git directory and isn't a linked worktree
+	if info, err := os.Stat(filepath.Join(clonePath, ".git")); err != nil || !info.IsDir() {
+		t.Errorf(".git of the clone is not a directory: %v", err)
+	}
+	if registered, _ := IsRegistered(clonePath); registered {
+		t.Error("IsRegistered() = true, want the clone not to be a linked worktree")
+	}
+	if got := git.GetBranchName(clonePath); got != "isolated-branch" {
+		t.Errorf("branch of the clone = %s, want isolated-branch", got)
+	}
+	if git.BranchExists("isolated-branch") {
+		t.Error("branch isolated-branch was created in the main repository")
+	}
+	if !IsIsolated(clonePath) {
+		t.Error("IsIsolated() = false, want true")
+	}
+
+	prWorktrees, _, err := ListAllWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListAllWorktrees() error = %v", err)
+	}
+	wt, err := FindByIdentifier(prWorktrees, nil, "7")
+	if err != nil || wt == nil {
+		t.Fatalf("FindByIdentifier(7) = %+v, %v, want the isolated clone", wt, err)
+	}
+	if !wt.Isolated || wt.Path != clonePath || wt.Title != pr.Title {
+		t.Errorf("listed clone = %+v, want isolated clone at %s titled %q", wt, clonePath, pr.Title)
+	}
+
+	// Uncommitted changes keep the clone unless forced
+	if err := os.WriteFile(filepath.Join(clonePath, "wip.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := Remove(clonePath, false); err == nil {
+		t.Error("Remove() of a dirty clone expected error")
+	}
+	if err := Remove(clonePath, true); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(clonePath); !os.IsNotExist(err) {
+		t.Errorf("clone %s still exists", clonePath)
+	}
+	if IsIsolated(clonePath) {
+		t.Error("IsIsolated() = true after removal")
+	}
+}
+
+func TestBranchExistsPolicy(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	if got := freeBranchName("feature"); got != "feature-2" {
+		t.Errorf("freeBranchName(feature) = %s, want feature-2", got)
+	}
+	runGit(t, "-C", mainPath, "branch", "feature-2")
+	if got := freeBranchName("feature"); got != "feature-3" {
+		t.Errorf("freeBranchName(feature) = %s, want feature-3", got)
+	}
+
+	origin := &git.Remote{Name: "origin", URL: "https://github.com/owner/repo.git"}
+	c := &Creator{
+		remotes: []*git.Remote{origin},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+	pr := newTestPR(5, "feature", "owner", "repo")
+
+	err := c.Create(filepath.Join(filepath.Dir(mainPath), "repo-pr5"), pr, &CheckoutOptions{BranchExistsPolicy: BranchExistsError})
+	if err == nil {
+		t.Error("Create() with --branch-exists-policy error expected error for existing branch")
+	}
+
+	tests := []struct {
+		name      string
+		opts      *CheckoutOptions
+		wantForce bool
+	}{
+		{name: "default fast-forwards", opts: &CheckoutOptions{}},
+		{name: "--force resets", opts: &CheckoutOptions{Force: true}, wantForce: true},
+		{name: "reset", opts: &CheckoutOptions{BranchExistsPolicy: BranchExistsReset}, wantForce: true},
+		{name: "ff overrides --force", opts: &CheckoutOptions{Force: true, BranchExistsPolicy: BranchExistsFF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, err := c.cmdsForMissingRemote(pr, origin, tt.opts, "/tmp/repo-pr5", "feature")
+			if err != nil {
+				t.Fatalf("cmdsForMissingRemote() error = %v", err)
+			}
+			fetch := cmds[0]
+			if gotForce := fetch[len(fetch)-1] == "--force"; gotForce != tt.wantForce {
+				t.Errorf("fetch = %v, want --force %v", fetch, tt.wantForce)
+			}
+		})
+	}
+}
+
+func TestRefreshBranch(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	runGit(t, "-C", mainPath, "branch", "behind")
+	runGit(t, "-C", mainPath, "branch", "ahead")
+	scratch := filepath.Join(t.TempDir(), "scratch")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "diverged", scratch)
+	runGit(t, "-C", scratch, "commit", "-q", "--allow-empty", "-m", "local")
+	runGit(t, "-C", mainPath, "worktree", "remove", scratch)
+	runGit(t, "-C", mainPath, "commit", "-q", "--allow-empty", "-m", "upstream")
+	runGit(t, "-C", mainPath, "branch", "-f", "ahead")
+	runGit(t, "-C", mainPath, "update-ref", "refs/remotes/origin/topic", "HEAD")
+	runGit(t, "-C", mainPath, "update-ref", "refs/remotes/origin/old", "HEAD~1")
+
+	resolve := func(ref string) string {
+		t.Helper()
+		sha, err := git.ResolveCommit(ref)
+		if err != nil {
+			t.Fatalf("ResolveCommit(%s) error = %v", ref, err)
+		}
+		return sha
+	}
+	upstream := resolve("HEAD")
+
+	tests := []struct {
+		name   string
+		branch string
+		target string
+		want   string
+	}{
+		{name: "behind is fast-forwarded", branch: "behind", target: "refs/remotes/origin/topic", want: upstream},
+		{name: "ahead is left alone", branch: "ahead", target: "refs/remotes/origin/old", want: upstream},
+		{name: "diverged is left alone", branch: "diverged", target: "refs/remotes/origin/topic", want: resolve("diverged")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := refreshBranch(tt.branch, tt.target); err != nil {
+				t.Fatalf("refreshBranch() error = %v", err)
+			}
+			if got := resolve("refs/heads/" + tt.branch); got != tt.want {
+				t.Errorf("%s = %s, want %s", tt.branch, got, tt.want)
+			}
+		})
+	}
+
+	if err := refreshBranch("behind", "refs/remotes/origin/missing"); err == nil {
+		t.Error("refreshBranch() expected error for a missing target")
+	}
+
+	// The existing branch is fast-forwarded before the worktree is added
+	runGit(t, "-C", mainPath, "branch", "stale", "HEAD~1")
+	runGit(t, "-C", mainPath, "update-ref", "refs/remotes/origin/stale", "HEAD")
+	c := &Creator{
+		remotes: []*git.Remote{{Name: "origin", URL: "https://github.com/owner/repo.git"}},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+	worktreePath := filepath.Join(filepath.Dir(mainPath), "repo-pr9")
+	if err := c.Create(worktreePath, newTestPR(9, "stale", "owner", "repo"), &CheckoutOptions{NoFetch: true, RefreshExistingBranch: true}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := git.GetHeadCommit(worktreePath); got != upstream {
+		t.Errorf("HEAD of new worktree = %s, want %s", got, upstream)
+	}
+}
+
+func TestCreate_NoMetadata(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+	runGit(t, "-C", mainPath, "update-ref", "refs/remotes/origin/topic", "HEAD")
+
+	c := &Creator{
+		remotes: []*git.Remote{{Name: "origin", URL: "https://github.com/owner/repo.git"}},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+	worktreePath := filepath.Join(filepath.Dir(mainPath), "repo-pr9")
+	pr := newTestPR(9, "topic", "owner", "repo")
+	pr.Title = "Add topic"
+	if err := c.Create(worktreePath, pr, &CheckoutOptions{NoFetch: true, NoMetadata: true}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	keys, err := git.GetConfigRegexp(mainPath, `^branch\.topic\.gh-worktree-`)
+	if err != nil {
+		t.Fatalf("GetConfigRegexp() error = %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Create() with NoMetadata stored %v", keys)
+	}
+
+	// The worktree is still listed, classified by its directory name
+	prWorktrees, err := ListPRWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListPRWorktrees() error = %v", err)
+	}
+	wt, err := FindByIdentifier(prWorktrees, nil, "9")
+	if err != nil || wt == nil {
+		t.Fatalf("FindByIdentifier(9) = %+v, %v, want the new worktree", wt, err)
+	}
+	if wt.Path != worktreePath || wt.Title != "" {
+		t.Errorf("worktree = %+v, want %s without a title", wt, worktreePath)
+	}
+}
+
+func TestCreate_DetachedStoresNoMetadata(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+	runGit(t, "-C", mainPath, "update-ref", "refs/remotes/origin/feature", "HEAD")
+
+	c := &Creator{
+		remotes: []*git.Remote{{Name: "origin", URL: "https://github.com/owner/repo.git"}},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+	}
+	// The PR's head has the same name as the local feature branch
+	worktreePath := filepath.Join(filepath.Dir(mainPath), "repo-pr9")
+	pr := newTestPR(9, "feature", "owner", "repo")
+	if err := c.Create(worktreePath, pr, &CheckoutOptions{NoFetch: true, Detach: true}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	keys, err := git.GetConfigRegexp(mainPath, `^branch\.feature\.gh-worktree-`)
+	if err != nil {
+		t.Fatalf("GetConfigRegexp() error = %v", err)
+	}
+	want := []git.ConfigEntry{{Key: "branch.feature.gh-worktree-type", Value: "branch"}}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("branch metadata after detached Create() = %v, want %v", keys, want)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	worktreePath := filepath.Join(filepath.Dir(mainPath), "repo-pr42")
+	t.Chdir(mainPath)
+
+	c := &Creator{repo: repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"}}
+	pr := newTestPR(42, "pr-branch", "owner", "repo")
+	pr.Title = "Fix the frobnicator"
+
+	if err := c.Annotate(worktreePath, pr, &CheckoutOptions{}); err != nil {
+		t.Fatalf("Annotate() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(worktreePath, AnnotationFile)); !os.IsNotExist(err) {
+		t.Fatalf("Annotate() without --annotate wrote %s", AnnotationFile)
+	}
+
+	// Annotating twice must not add the pattern to info/exclude twice
+	for range 2 {
+		if err := c.Annotate(worktreePath, pr, &CheckoutOptions{Annotate: true}); err != nil {
+			t.Fatalf("Annotate() error = %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(worktreePath, AnnotationFile))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", AnnotationFile, err)
+	}
+	for _, want := range []string{"# #42 Fix the frobnicator", "https://github.com/owner/repo/pull/42", "Created: "} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("%s = %q, want it to contain %q", AnnotationFile, content, want)
+		}
+	}
+
+	status, err := exec.Command("git", "-C", worktreePath, "status", "--porcelain").Output()
+	if err != nil {
+		t.Fatalf("git status failed: %v", err)
+	}
+	if len(status) != 0 {
+		t.Errorf("git status = %q, want %s to be excluded", status, AnnotationFile)
+	}
+	exclude, err := os.ReadFile(filepath.Join(mainPath, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatalf("failed to read info/exclude: %v", err)
+	}
+	if n := strings.Count(string(exclude), "/"+AnnotationFile+"\n"); n != 1 {
+		t.Errorf("info/exclude contains the pattern %d times, want 1:\n%s", n, exclude)
+	}
+}
+
+func TestRenameBranch(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	prPath := filepath.Join(filepath.Dir(mainPath), "repo-pr42")
+	t.Chdir(mainPath)
+
+	runGit(t, "-C", mainPath, "config", "branch.pr-branch.remote", "origin")
+	runGit(t, "-C", mainPath, "config", "branch.pr-branch.merge", "refs/heads/fix")
+	runGit(t, "-C", mainPath, "config", "extensions.worktreeConfig", "true")
+	runGit(t, "-C", prPath, "config", "--worktree", "branch.pr-branch.description", "local notes")
+
+	wt, err := FindByPath(prPath)
+	if err != nil || wt == nil {
+		t.Fatalf("FindByPath() = %v, %v", wt, err)
+	}
+
+	for _, newName := range []string{"feature", "pr-branch", "bad..name"} {
+		if err := RenameBranch(wt, newName); err == nil {
+			t.Errorf("RenameBranch(%s) expected error", newName)
+		}
+	}
+
+	if err := RenameBranch(wt, "fix-login"); err != nil {
+		t.Fatalf("RenameBranch() error = %v", err)
+	}
+	if wt.Branch != "fix-login" {
+		t.Errorf("Branch = %s, want fix-login", wt.Branch)
+	}
+	if got := git.GetBranchName(prPath); got != "fix-login" {
+		t.Errorf("checked out branch = %s, want fix-login", got)
+	}
+	if git.BranchExists("pr-branch") {
+		t.Error("old branch still exists")
+	}
+
+	checks := []struct {
+		key  string
+		want string
+	}{
+		{key: "branch.fix-login.gh-worktree-pr-number", want: "42"},
+		{key: "branch.fix-login.gh-worktree-type", want: "pr"},
+		{key: "branch.fix-login.remote", want: "origin"},
+		{key: "branch.fix-login.merge", want: "refs/heads/fix"},
+	}
+	for _, c := range checks {
+		if got, err := git.GetConfig(prPath, c.key); err != nil || got != c.want {
+			t.Errorf("%s = %q, %v, want %q", c.key, got, err, c.want)
+		}
+	}
+	// config.worktree isn't moved by git branch -m
+	entries, err := git.GetWorktreeConfigRegexp(prPath, "^branch\\.")
+	if err != nil {
+		t.Fatalf("GetWorktreeConfigRegexp() error = %v", err)
+	}
+	wantEntries := []git.ConfigEntry{{Key: "branch.fix-login.description", Value: "local notes"}}
+	if !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("worktree config = %+v, want %+v", entries, wantEntries)
+	}
+
+	prWorktrees, err := ListPRWorktrees("repo")
+	if err != nil {
+		t.Fatalf("ListPRWorktrees() error = %v", err)
+	}
+	found, _ := FindByIdentifier(prWorktrees, nil, "42")
+	if found == nil || found.Branch != "fix-login" {
+		t.Errorf("FindByIdentifier(42) = %+v, want the worktree on fix-login", found)
+	}
+}
+
+func TestUpdateSubmodules(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	// Record a submodule whose URL can't be cloned
+	gitmodules := "[submodule \"broken\"]\n\tpath = broken\n\turl = " + filepath.Join(parent, "missing.git") + "\n"
+	if err := os.WriteFile(filepath.Join(mainPath, ".gitmodules"), []byte(gitmodules), 0644); err != nil {
+		t.Fatalf("failed to write .gitmodules: %v", err)
+	}
+	head := git.GetHeadCommit(mainPath)
+	runGit(t, "-C", mainPath, "update-index", "--add", "--cacheinfo", "160000,"+head+",broken")
+	runGit(t, "-C", mainPath, "add", ".gitmodules")
+	runGit(t, "-C", mainPath, "commit", "-q", "-m", "add submodule")
+	wtPath := filepath.Join(parent, "repo-submodules")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "--detach", wtPath)
+
+	// Failures are fatal by default
+	c := &Creator{}
+	if err := c.updateSubmodules(wtPath, &CheckoutOptions{RecurseSubmodules: true}); err == nil {
+		t.Error("updateSubmodules() expected error")
+	}
+	if len(c.SetupWarnings()) != 0 {
+		t.Errorf("SetupWarnings() = %v, want none", c.SetupWarnings())
+	}
+
+	// IgnoreSubmoduleErrors updates submodules on its own and only warns
+	for _, opts := range []*CheckoutOptions{
+		{IgnoreSubmoduleErrors: true},
+		{RecurseSubmodules: true, IgnoreSubmoduleErrors: true},
+	} {
+		c = &Creator{}
+		if err := c.updateSubmodules(wtPath, opts); err != nil {
+			t.Fatalf("updateSubmodules(%+v) error = %v, want a warning", *opts, err)
+		}
+		if len(c.SetupWarnings()) != 1 {
+			t.Errorf("updateSubmodules(%+v) warnings = %v, want 1 warning", *opts, c.SetupWarnings())
+		}
+	}
+}
+
+func TestFindByBranch(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	// A branch checked out outside gh worktree's naming scheme is found too
+	elsewhere := filepath.Join(parent, "scratch-checkout")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "elsewhere", elsewhere)
+	runGit(t, "-C", mainPath, "branch", "unused")
+
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{branch: "feature", want: filepath.Join(parent, "repo-feature")},
+		{branch: "elsewhere", want: elsewhere},
+		{branch: git.GetBranchName(mainPath), want: mainPath},
+		{branch: "unused", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			wt, err := FindByBranch(tt.branch)
+			if err != nil {
+				t.Fatalf("FindByBranch() error = %v", err)
+			}
+			got := ""
+			if wt != nil {
+				got = wt.Path
+			}
+			if got != tt.want {
+				t.Errorf("FindByBranch(%s) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	// Fork branches tracking the PR head, by URL as gh pr checkout does and by remote name
+	tracked := filepath.Join(parent, "repo-tracked")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "patch-1", tracked)
+	runGit(t, "-C", mainPath, "config", "branch.patch-1.remote", "https://github.com/fork/repo.git")
+	runGit(t, "-C", mainPath, "config", "branch.patch-1.merge", "refs/heads/patch-1")
+	named := filepath.Join(parent, "repo-named")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "-b", "fix", named)
+	runGit(t, "-C", mainPath, "remote", "add", "other", "https://github.com/other/repo.git")
+	runGit(t, "-C", mainPath, "config", "branch.fix.remote", "other")
+	runGit(t, "-C", mainPath, "config", "branch.fix.merge", "refs/heads/fix")
+
+	sameRepo := func(number int, headRef string) *github.PullRequest {
+		pr := newTestPR(number, headRef, "owner", "repo")
+		pr.Base.Repo.FullName = "owner/repo"
+		return pr
+	}
+	fork := func(number int, headRef, owner string) *github.PullRequest {
+		pr := newTestPR(number, headRef, owner, "repo")
+		pr.Base.Repo.FullName = "owner/repo"
+		return pr
+	}
+
+	tests := []struct {
+		name        string
+		pr          *github.PullRequest
+		localBranch string
+		want        string
+	}{
+		{name: "recorded PR number", pr: sameRepo(42, "renamed"), localBranch: "renamed", want: filepath.Join(parent, "repo-pr42")},
+		{name: "local branch", pr: fork(7, "topic", "fork"), localBranch: "feature", want: filepath.Join(parent, "repo-feature")},
+		{name: "same-repo head branch", pr: sameRepo(7, "feature"), localBranch: "pr-7", want: filepath.Join(parent, "repo-feature")},
+		{name: "fork head branch with the same name", pr: fork(7, "feature", "fork"), localBranch: "pr-7", want: ""},
+		{name: "fork head branch tracked by URL", pr: fork(7, "patch-1", "fork"), want: tracked},
+		{name: "fork head branch of another fork", pr: fork(7, "patch-1", "someone"), want: ""},
+		{name: "fork head branch tracked by remote name", pr: fork(7, "fix", "other"), want: named},
+		{name: "main worktree branch", pr: fork(7, "topic", "fork"), localBranch: git.GetBranchName(mainPath), want: ""},
+		{name: "not checked out", pr: sameRepo(7, "topic"), localBranch: "topic", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wt, err := FindDuplicate(tt.pr, tt.localBranch)
+			if err != nil {
+				t.Fatalf("FindDuplicate() error = %v", err)
+			}
+			got := ""
+			if wt != nil {
+				got = wt.Path
+			}
+			if got != tt.want {
+				t.Errorf("FindDuplicate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRepoName(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+
+	tests := []struct {
+		name    string
+		config  string
+		assumed string
+		want    string
+		wantErr bool
+	}{
+		{name: "directory name", want: "repo"},
+		{name: "config", config: "repo_name: project\n", want: "project"},
+		{name: "assumed overrides config", config: "repo_name: project\n", assumed: "other", want: "other"},
+		{name: "invalid assumed", assumed: "../evil", wantErr: true},
+		{name: "invalid config", config: "repo_name: a/b\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(mainPath, ".gh-worktree.yml")
+			if err := os.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			got, err := ResolveRepoName(mainPath, tt.assumed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRepoName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveRepoName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	if err := os.WriteFile(filepath.Join(mainPath, "data.bin"), make([]byte, 3000), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mainPath, "README"), []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	runGit(t, "-C", mainPath, "add", ".")
+	runGit(t, "-C", mainPath, "commit", "-q", "-m", "add files")
+
+	size, err := checkoutSize(mainPath)
+	if err != nil {
+		t.Fatalf("checkoutSize() error = %v", err)
+	}
+	if size != 3006 {
+		t.Errorf("checkoutSize() = %d, want 3006", size)
+	}
+
+	// The parent of a nested worktree doesn't exist yet
+	wtPath := filepath.Join(mainPath, "worktrees", "label", "pr7")
+	if got := existingAncestor(filepath.Dir(wtPath)); got != mainPath {
+		t.Errorf("existingAncestor() = %s, want %s", got, mainPath)
+	}
+	if err := checkDiskSpace(wtPath, true); err != nil {
+		t.Errorf("checkDiskSpace() error = %v", err)
+	}
+}
+
+func TestRenormalize(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	files := map[string]string{
+		".gitattributes": "*.txt filter=upper\n",
+		"a.txt":          "hello\n",
+		"b.txt":          "world\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(mainPath, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	runGit(t, "-C", mainPath, "add", ".")
+	runGit(t, "-C", mainPath, "commit", "-q", "-m", "add filtered files")
+	wtPath := filepath.Join(filepath.Dir(mainPath), "repo-pr7")
+	runGit(t, "-C", mainPath, "worktree", "add", "-q", "--detach", wtPath)
+
+	// The filter is configured after the checkout, e.g. by a setup command
+	runGit(t, "-C", mainPath, "config", "filter.upper.smudge", "tr a-z A-Z")
+	runGit(t, "-C", mainPath, "config", "filter.upper.clean", "tr A-Z a-z")
+	if err := os.WriteFile(filepath.Join(wtPath, "b.txt"), []byte("local edit\n"), 0644); err != nil {
+		t.Fatalf("failed to modify b.txt: %v", err)
+	}
+
+	c := &Creator{}
+	if err := c.Renormalize(wtPath, &CheckoutOptions{}); err != nil {
+		t.Fatalf("Renormalize() without --renormalize error = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(wtPath, "a.txt")); string(got) != "hello\n" {
+		t.Errorf("a.txt = %q without --renormalize, want it untouched", got)
+	}
+
+	if err := c.Renormalize(wtPath, &CheckoutOptions{Renormalize: true}); err != nil {
+		t.Fatalf("Renormalize() error = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(wtPath, "a.txt")); string(got) != "HELLO\n" {
+		t.Errorf("a.txt = %q, want the smudged content", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(wtPath, "b.txt")); string(got) != "local edit\n" {
+		t.Errorf("b.txt = %q, want the local modification kept", got)
+	}
+	if len(c.SetupWarnings()) != 1 {
+		t.Errorf("SetupWarnings() = %v, want a warning about the skipped file", c.SetupWarnings())
+	}
+	if err := exec.Command("git", "-C", wtPath, "diff", "--cached", "--quiet").Run(); err != nil {
+		t.Errorf("Renormalize() staged changes: %v", err)
+	}
+}
+
+func TestCheckBaseDir(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	tests := []struct {
+		name    string
+		baseDir string
+		wantErr bool
+	}{
+		{name: "parent of the main worktree", baseDir: parent},
+		{name: "main worktree", baseDir: mainPath, wantErr: true},
+		{name: "inside the main worktree", baseDir: filepath.Join(mainPath, "tools"), wantErr: true},
+		{name: "inside a linked worktree", baseDir: filepath.Join(parent, "repo-pr42", "src"), wantErr: true},
+		{name: "sibling with a common prefix", baseDir: mainPath + "-tools"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBaseDir(tt.baseDir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkBaseDir(%s) error = %v, wantErr %v", tt.baseDir, err, tt.wantErr)
+			}
+		})
+	}
+
+	// Nested mode puts worktrees inside the main worktree on purpose
+	if err := os.WriteFile(filepath.Join(mainPath, ".gh-worktree.yml"), []byte("worktree:\n  location: nested\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := checkBaseDir(filepath.Join(mainPath, "tools")); err != nil {
+		t.Errorf("checkBaseDir() with nested location error = %v", err)
+	}
+}
+
+func TestRollBack(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	t.Chdir(mainPath)
+
+	// A registered worktree and the branch made for it
+	prPath := filepath.Join(parent, "repo-pr42")
+	RollBack(prPath, "pr-branch")
+	if _, err := os.Stat(prPath); !os.IsNotExist(err) {
+		t.Errorf("worktree %s still exists", prPath)
+	}
+	if registered, _ := IsRegistered(prPath); registered {
+		t.Errorf("worktree %s is still registered", prPath)
+	}
+	if git.BranchExists("pr-branch") {
+		t.Error("branch pr-branch still exists")
+	}
+
+	// A directory git didn't register yet, e.g. an interrupted clone
+	clonePath := filepath.Join(parent, "repo-pr7")
+	if err := os.MkdirAll(filepath.Join(clonePath, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	RollBack(clonePath, "")
+	if _, err := os.Stat(clonePath); !os.IsNotExist(err) {
+		t.Errorf("partial clone %s still exists", clonePath)
+	}
+
+	// Branches that existed before are kept
+	if !git.BranchExists("feature") {
+		t.Error("branch feature was deleted")
+	}
+}
+
+func TestIsMain(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(mainPath, link); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Join(parent, "repo-pr42"))
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{name: "main worktree", path: mainPath, want: true},
+		{name: "relative path", path: "../repo", want: true},
+		{name: "through a symlink", path: link, want: true},
+		{name: "trailing separator", path: mainPath + string(filepath.Separator), want: true},
+		{name: "linked worktree", path: filepath.Join(parent, "repo-feature")},
+		{name: "inside the main worktree", path: filepath.Join(mainPath, "sub")},
+		{name: "missing path", path: filepath.Join(parent, "repo-pr7")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsMain(tt.path)
+			if err != nil {
+				t.Fatalf("IsMain() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsMain(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectMain(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+	marker := filepath.Join(mainPath, "keep")
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Remove(mainPath, true); err == nil {
+		t.Error("Remove() of the main worktree expected error")
+	}
+	RollBack(mainPath, "")
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("main worktree was modified: %v", err)
+	}
+
+	branch := git.GetBranchName(mainPath)
+	if err := RenameBranch(&Info{Path: mainPath, Branch: branch}, "renamed"); err == nil {
+		t.Error("RenameBranch() of the main worktree expected error")
+	}
+	if got := git.GetBranchName(mainPath); got != branch {
+		t.Errorf("branch of the main worktree = %s, want %s", got, branch)
+	}
+}
+
+func TestCreate_Interrupted(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	t.Chdir(mainPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &Creator{
+		remotes: []*git.Remote{{Name: "origin", URL: "https://github.com/owner/repo.git"}},
+		repo:    repository.Repository{Host: "github.com", Owner: "owner", Name: "repo"},
+		ctx:     ctx,
+	}
+	worktreePath := filepath.Join(filepath.Dir(mainPath), "repo-pr5")
+
+	err := c.Create(worktreePath, newTestPR(5, "new-feature", "owner", "repo"), &CheckoutOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Create() error = %v, want context.Canceled", err)
+	}
+	if !Interrupted(ctx, err) {
+		t.Error("Interrupted() = false, want true")
+	}
+	if _, err := os.Stat(worktreePath); !os.IsNotExist(err) {
+		t.Errorf("worktree %s was left behind", worktreePath)
+	}
+	if git.BranchExists("new-feature") {
+		t.Error("branch new-feature was left behind")
+	}
+}
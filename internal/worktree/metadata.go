@@ -0,0 +1,102 @@
+package worktree
+
+import (
+	"fmt"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+)
+
+// Worktree metadata is stored in the repository config of the main worktree,
+// keyed by branch (branch.<name>.gh-worktree-<field>). That config is shared by
+// all worktrees even with extensions.worktreeConfig, so the metadata reads the
+// same from every worktree and survives removing and re-adding a worktree for
+// the branch.
+const (
+	metaType       = "type"
+	metaPRNumber   = "pr-number"
+	metaPRTitle    = "pr-title"
+	metaBase       = "base"
+	metaBaseCommit = "base-commit"
+)
+
+// setConfig and unsetConfig are git.SetConfig and git.UnsetConfig, replaced
+// in tests to inject failures
+var (
+	setConfig   = git.SetConfig
+	unsetConfig = git.UnsetConfig
+)
+
+func metadataKey(branchName, field string) string {
+	return fmt.Sprintf("branch.%s.gh-worktree-%s", branchName, field)
+}
+
+// getMetadata returns a metadata field of the branch. Like git.GetConfig, it
+// returns an error if the field isn't set.
+func getMetadata(branchName, field string) (string, error) {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git root: %w", err)
+	}
+	return git.GetConfig(gitRoot, metadataKey(branchName, field))
+}
+
+// setMetadata sets a metadata field of the branch
+func setMetadata(branchName, field, value string) error {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+	return setConfig(gitRoot, metadataKey(branchName, field), value)
+}
+
+// metadataField is a metadata field and the value to set it to, or the field
+// to remove if unset is true
+type metadataField struct {
+	field string
+	value string
+	unset bool
+}
+
+// setMetadataFields sets or removes several metadata fields of the branch. If
+// updating one fails, the fields already updated are restored to their
+// previous values (or removed if they weren't set), so that the branch isn't
+// left with partial metadata, and the original error is returned.
+func setMetadataFields(branchName string, fields []metadataField) error {
+	type previous struct {
+		field string
+		value string
+		isSet bool
+	}
+	var written []previous
+	for _, f := range fields {
+		value, err := getMetadata(branchName, f.field)
+		prev := previous{field: f.field, value: value, isSet: err == nil}
+		if f.unset {
+			err = unsetMetadata(branchName, f.field)
+		} else {
+			err = setMetadata(branchName, f.field, f.value)
+		}
+		if err != nil {
+			for i := len(written) - 1; i >= 0; i-- {
+				w := written[i]
+				if w.isSet {
+					_ = setMetadata(branchName, w.field, w.value)
+				} else {
+					_ = unsetMetadata(branchName, w.field)
+				}
+			}
+			return fmt.Errorf("failed to update %s: %w", metadataKey(branchName, f.field), err)
+		}
+		written = append(written, prev)
+	}
+	return nil
+}
+
+// unsetMetadata removes a metadata field of the branch if it is set
+func unsetMetadata(branchName, field string) error {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+	return unsetConfig(gitRoot, metadataKey(branchName, field))
+}
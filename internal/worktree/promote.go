@@ -4,48 +4,34 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-
-	"github.com/knqyf263/gh-worktree/internal/git"
 )
 
 // PromoteToPR promotes a branch worktree to a PR worktree by updating its metadata.
 func PromoteToPR(branchName string, prNumber int, prTitle string) error {
-	// Get git root for config path
-	gitRoot, err := git.GetRoot()
-	if err != nil {
-		return fmt.Errorf("failed to get git root: %w", err)
-	}
-
-	// Set worktree type to "pr"
-	if err := git.SetConfig(gitRoot, fmt.Sprintf("branch.%s.gh-worktree-type", branchName), "pr"); err != nil {
-		return fmt.Errorf("failed to set worktree type: %w", err)
-	}
-
-	// Set PR number
-	if err := git.SetConfig(gitRoot, fmt.Sprintf("branch.%s.gh-worktree-pr-number", branchName), strconv.Itoa(prNumber)); err != nil {
-		return fmt.Errorf("failed to set PR number: %w", err)
-	}
-
-	// Set PR title
-	if err := git.SetConfig(gitRoot, fmt.Sprintf("branch.%s.gh-worktree-pr-title", branchName), prTitle); err != nil {
-		return fmt.Errorf("failed to set PR title: %w", err)
-	}
+	return setMetadataFields(branchName, []metadataField{
+		{field: metaType, value: "pr"},
+		{field: metaPRNumber, value: strconv.Itoa(prNumber)},
+		{field: metaPRTitle, value: prTitle},
+	})
+}
 
-	return nil
+// DemoteToBranch reverts a promoted PR worktree to a branch worktree by
+// recording it as a branch worktree and removing its PR metadata.
+func DemoteToBranch(branchName string) error {
+	return setMetadataFields(branchName, []metadataField{
+		{field: metaType, value: "branch"},
+		{field: metaPRNumber, unset: true},
+		{field: metaPRTitle, unset: true},
+	})
 }
 
 // GetWorktreeType returns the type of the worktree for the given branch.
 // Returns "pr", "branch", or "" if not set.
 func GetWorktreeType(branchName string) (string, error) {
-	gitRoot, err := git.GetRoot()
-	if err != nil {
-		return "", fmt.Errorf("failed to get git root: %w", err)
-	}
-
-	worktreeType, err := git.GetConfig(gitRoot, fmt.Sprintf("branch.%s.gh-worktree-type", branchName))
+	worktreeType, err := getMetadata(branchName, metaType)
 	if err != nil {
 		// If config doesn't exist, try to detect from PR number
-		prNumber, err := git.GetConfig(gitRoot, fmt.Sprintf("branch.%s.gh-worktree-pr-number", branchName))
+		prNumber, err := getMetadata(branchName, metaPRNumber)
 		if err == nil && prNumber != "" {
 			return "pr", nil
 		}
@@ -56,10 +42,24 @@ func GetWorktreeType(branchName string) (string, error) {
 
 // SetWorktreeType sets the worktree type metadata for a branch.
 func SetWorktreeType(branchName string, worktreeType string) error {
-	gitRoot, err := git.GetRoot()
-	if err != nil {
-		return fmt.Errorf("failed to get git root: %w", err)
+	return setMetadata(branchName, metaType, worktreeType)
+}
+
+// SetBranchBase records the branch and commit a branch worktree was created from.
+func SetBranchBase(branchName, baseBranch, baseCommit string) error {
+	if err := setMetadata(branchName, metaBase, baseBranch); err != nil {
+		return fmt.Errorf("failed to set base branch: %w", err)
+	}
+	if err := setMetadata(branchName, metaBaseCommit, baseCommit); err != nil {
+		return fmt.Errorf("failed to set base commit: %w", err)
 	}
+	return nil
+}
 
-	return git.SetConfig(gitRoot, fmt.Sprintf("branch.%s.gh-worktree-type", branchName), worktreeType)
+// GetBranchBase returns the branch and commit a branch worktree was created from.
+// Both are empty if the base wasn't recorded.
+func GetBranchBase(branchName string) (baseBranch, baseCommit string) {
+	baseBranch, _ = getMetadata(branchName, metaBase)
+	baseCommit, _ = getMetadata(branchName, metaBaseCommit)
+	return baseBranch, baseCommit
 }
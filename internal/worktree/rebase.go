@@ -0,0 +1,68 @@
+package worktree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/validate"
+)
+
+// RebaseConflictError reports a rebase that stopped on conflicts.
+// The worktree is left in the rebase state for manual resolution.
+type RebaseConflictError struct {
+	Path  string
+	Ref   string
+	Files []string
+}
+
+func (e *RebaseConflictError) Error() string {
+	msg := fmt.Sprintf("rebase onto %s stopped with conflicts", e.Ref)
+	if len(e.Files) > 0 {
+		msg += " in " + strings.Join(e.Files, ", ")
+	}
+	return msg + fmt.Sprintf("\nResolve them in %s and run `git rebase --continue`, or run `git rebase --abort` to return to the PR head", e.Path)
+}
+
+// Rebase rebases the branch checked out in a worktree onto ref.
+// If the rebase stops on conflicts, a *RebaseConflictError is returned.
+func Rebase(worktreePath, ref string, skipHooks bool) error {
+	if err := validate.BranchName(ref); err != nil {
+		return fmt.Errorf("invalid ref %q: %w", ref, err)
+	}
+	if err := git.Command("-C", worktreePath, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run(); err != nil {
+		return fmt.Errorf("ref %s does not exist (fetch it first?)", ref)
+	}
+
+	cmds := [][]string{{"-C", worktreePath, "rebase", ref}}
+	if skipHooks {
+		cmds = SkipHooks(cmds)
+	}
+	err := git.ExecuteCommands(cmds)
+	if err == nil {
+		return nil
+	}
+
+	conflicts, diffErr := conflictedFiles(worktreePath)
+	if diffErr != nil || (len(conflicts) == 0 && !rebaseInProgress(worktreePath)) {
+		return fmt.Errorf("failed to rebase onto %s: %w", ref, err)
+	}
+	return &RebaseConflictError{Path: worktreePath, Ref: ref, Files: conflicts}
+}
+
+// conflictedFiles returns the unmerged paths in a worktree
+func conflictedFiles(worktreePath string) ([]string, error) {
+	output, err := git.Command("-C", worktreePath, "diff", "--name-only", "--diff-filter=U").Output()
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(output))) == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n"), nil
+}
+
+// rebaseInProgress reports whether a rebase is stopped in a worktree
+func rebaseInProgress(worktreePath string) bool {
+	return git.Command("-C", worktreePath, "rev-parse", "--quiet", "--verify", "REBASE_HEAD").Run() == nil
+}
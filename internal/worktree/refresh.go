@@ -0,0 +1,80 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/github"
+)
+
+// refreshTarget returns the ref that --refresh-existing-branch fast-forwards
+// the existing local branch to before `worktree add`, or "" if the branch isn't
+// checked out with `merge --ff-only` after adding the worktree
+func (c *Creator) refreshTarget(pr *github.PullRequest, headRemote *git.Remote, opts *CheckoutOptions, branchName string) string {
+	if !opts.RefreshExistingBranch || opts.Detach || c.newBranch == branchName || branchExistsPolicy(opts) != BranchExistsFF {
+		return ""
+	}
+	switch {
+	case headRemote != nil:
+		return fmt.Sprintf("refs/remotes/%s/%s", headRemote.Name, pr.Head.Ref)
+	case opts.NoFetch && opts.RemoteBranch == "" && opts.Fork == "":
+		// Without --no-fetch the pull ref is fetched into the branch, which
+		// already refuses anything but a fast-forward
+		return c.pullRef(pr.Number)
+	}
+	return ""
+}
+
+// executeRefreshing runs cmdQueue, fast-forwarding branchName to target once
+// the fetch (the first command unless noFetch) has run. Like execute, it takes
+// the creation lock after the fetch.
+func (c *Creator) executeRefreshing(worktreePath string, cmdQueue [][]string, branchName, target string, noFetch bool) error {
+	fetched := 1
+	if noFetch {
+		fetched = 0
+	}
+	if err := git.ExecuteCommandsContext(c.context(), cmdQueue[:fetched]); err != nil {
+		return err
+	}
+	if err := c.lock(worktreePath); err != nil {
+		return err
+	}
+	if err := refreshBranch(branchName, target); err != nil {
+		return err
+	}
+	return git.ExecuteCommandsContext(c.context(), cmdQueue[fetched:])
+}
+
+// refreshBranch moves branchName to target with update-ref if that is a
+// fast-forward, without checking it out. If the branch has diverged it is
+// left alone and the `merge --ff-only` after `worktree add` reports it as usual.
+func refreshBranch(branchName, target string) error {
+	branchRef := "refs/heads/" + branchName
+	current, err := git.ResolveCommit(branchRef)
+	if err != nil {
+		return err
+	}
+	updated, err := git.ResolveCommit(target)
+	if err != nil {
+		return err
+	}
+	if current == updated {
+		return nil
+	}
+
+	fastForward, err := git.IsAncestor(current, updated)
+	if err != nil {
+		return err
+	}
+	if !fastForward {
+		if ahead, err := git.IsAncestor(updated, current); err == nil && !ahead {
+			fmt.Fprintf(os.Stderr, "Local branch %s has diverged from %s; not refreshing it\n", branchName, target)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "→ Fast-forwarding local branch %s to %s...\n", branchName, target)
+	// The old value guards against the branch moving since it was checked
+	return git.ExecuteCommands([][]string{{"update-ref", "-m", "gh worktree: fast-forward to " + target, branchRef, updated, current}})
+}
@@ -0,0 +1,55 @@
+package worktree
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/validate"
+)
+
+// RenameBranch renames the branch checked out in a worktree, keeping its
+// tracking config and metadata. The worktree directory keeps its name.
+func RenameBranch(wt *Info, newName string) error {
+	if wt.Isolated {
+		return fmt.Errorf("cannot rename the branch of an isolated clone")
+	}
+	if wt.Branch == "" {
+		return fmt.Errorf("worktree at %s has a detached HEAD", wt.Path)
+	}
+	if err := CheckNotMain(wt.Path, "rename the branch of"); err != nil {
+		return err
+	}
+	if err := validate.BranchName(newName); err != nil {
+		return fmt.Errorf("invalid branch name: %w", err)
+	}
+	if newName == wt.Branch {
+		return fmt.Errorf("branch is already named %s", newName)
+	}
+	if git.BranchExists(newName) {
+		return fmt.Errorf("branch %s already exists", newName)
+	}
+
+	// git moves the branch.<name> section of the repository config, which holds
+	// the tracking config and the metadata, along with the branch
+	if err := git.ExecuteCommands([][]string{{"-C", wt.Path, "branch", "-m", wt.Branch, newName}}); err != nil {
+		return fmt.Errorf("failed to rename branch: %w", err)
+	}
+
+	// but not the section in the worktree's own config.worktree
+	if git.WorktreeConfigEnabled(wt.Path) {
+		entries, err := git.GetWorktreeConfigRegexp(wt.Path, "^branch\\."+regexp.QuoteMeta(wt.Branch)+"\\.")
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			err := git.ExecuteCommands([][]string{{"-C", wt.Path, "config", "--worktree", "--rename-section", "branch." + wt.Branch, "branch." + newName}})
+			if err != nil {
+				return fmt.Errorf("failed to move worktree config of branch %s: %w", wt.Branch, err)
+			}
+		}
+	}
+
+	wt.Branch = newName
+	return nil
+}
@@ -0,0 +1,139 @@
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+)
+
+// filterAttributes are the .gitattributes that transform file content on checkout
+var filterAttributes = []string{"filter", "text", "eol", "working-tree-encoding"}
+
+// Renormalize checks out the filtered files of a new worktree again if
+// opts.Renormalize is set, so that .gitattributes filters (LFS, custom
+// clean/smudge, eol conversion) configured after the checkout, e.g. by setup
+// commands, are applied. Files with local modifications are left alone and
+// nothing is staged; files that differ from the index after the rewrite are
+// reported as a warning instead.
+func (c *Creator) Renormalize(worktreePath string, opts *CheckoutOptions) error {
+	if !opts.Renormalize {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "→ Reapplying .gitattributes filters...")
+
+	modified, err := modifiedFiles(worktreePath)
+	if err != nil {
+		return err
+	}
+	paths, err := filteredFiles(worktreePath)
+	if err != nil {
+		return err
+	}
+
+	// git skips files whose stat info matches the index even with --force, so
+	// they are removed first
+	var input bytes.Buffer
+	skipped, rewritten := 0, 0
+	for _, path := range paths {
+		if modified[path] {
+			skipped++
+			continue
+		}
+		rewritten++
+		if err := os.Remove(filepath.Join(worktreePath, path)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rewrite %s: %w", path, err)
+		}
+		input.WriteString(path)
+		input.WriteByte(0)
+	}
+	cmd := git.Command("-C", worktreePath, "checkout-index", "--force", "-u", "-z", "--stdin")
+	cmd.Stdin = &input
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to check out filtered files in %s (run `git checkout -- .` there to restore them): %w\n%s", worktreePath, err, output)
+	}
+
+	if skipped > 0 {
+		c.warn(fmt.Sprintf("Skipped %d locally modified file(s) when reapplying filters", skipped))
+	}
+	after, err := modifiedFiles(worktreePath)
+	if err != nil {
+		return err
+	}
+	differing := 0
+	for path := range after {
+		if !modified[path] {
+			differing++
+		}
+	}
+	if differing > 0 {
+		c.warn(fmt.Sprintf("%d file(s) differ from the index after reapplying filters and were not staged; run `git add --renormalize .` to stage them", differing))
+	} else {
+		fmt.Fprintf(os.Stderr, "  ✓ Reapplied filters to %d file(s)\n", rewritten)
+	}
+	return nil
+}
+
+// filteredFiles returns the checked-out files in the worktree at worktreePath
+// that have one of the filterAttributes. Files outside a sparse checkout are
+// not included.
+func filteredFiles(worktreePath string) ([]string, error) {
+	// -t tags entries outside a sparse checkout with S, which must stay absent
+	output, err := git.Command("-C", worktreePath, "ls-files", "-z", "-t").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", worktreePath, err)
+	}
+	var input bytes.Buffer
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if tag, path, ok := strings.Cut(entry, " "); ok && tag != "S" {
+			input.WriteString(path)
+			input.WriteByte(0)
+		}
+	}
+
+	cmd := git.Command(append([]string{"-C", worktreePath, "check-attr", "-z", "--stdin"}, filterAttributes...)...)
+	cmd.Stdin = &input
+	output, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attributes in %s: %w", worktreePath, err)
+	}
+
+	// The output is <path> NUL <attribute> NUL <value> NUL for each pair
+	var paths []string
+	seen := make(map[string]bool)
+	fields := strings.Split(string(output), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		path, value := fields[i], fields[i+2]
+		if value == "unspecified" || value == "unset" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// warn prints a non-fatal problem and records it for SetupWarnings
+func (c *Creator) warn(warning string) {
+	fmt.Fprintf(os.Stderr, "  ⚠ %s\n", warning)
+	c.setupWarnings = append(c.setupWarnings, warning)
+}
+
+// modifiedFiles returns the tracked files whose content differs from the
+// index in the worktree at worktreePath
+func modifiedFiles(worktreePath string) (map[string]bool, error) {
+	output, err := git.Command("-C", worktreePath, "diff", "--name-only", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for modified files in %s: %w", worktreePath, err)
+	}
+	modified := make(map[string]bool)
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			modified[path] = true
+		}
+	}
+	return modified, nil
+}
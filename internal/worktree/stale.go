@@ -0,0 +1,56 @@
+package worktree
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/knqyf263/gh-worktree/internal/git"
+)
+
+// SortLastCommit orders worktrees by the date of their last commit, oldest first
+const SortLastCommit = "last-commit"
+
+// maxConcurrentReads limits how many git processes LoadLastCommitTimes runs at once
+const maxConcurrentReads = 8
+
+// LoadLastCommitTimes sets LastCommit of each worktree to the committer date of
+// its HEAD. The dates are read concurrently, since each takes a git process.
+// Worktrees whose date can't be read keep the zero time.
+func LoadLastCommitTimes(worktrees []*Info) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentReads)
+	for _, wt := range worktrees {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if t, err := git.GetHeadCommitTime(wt.Path); err == nil {
+				wt.LastCommit = t
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// FilterStale returns the worktrees whose last commit is more than age before
+// now. Worktrees without a known last commit are kept, so that they aren't
+// hidden by mistake.
+func FilterStale(worktrees []*Info, age time.Duration, now time.Time) []*Info {
+	var stale []*Info
+	for _, wt := range worktrees {
+		if wt.LastCommit.IsZero() || now.Sub(wt.LastCommit) > age {
+			stale = append(stale, wt)
+		}
+	}
+	return stale
+}
+
+// SortByLastCommit sorts worktrees by their last commit, oldest first.
+// Worktrees without a known last commit come first.
+func SortByLastCommit(worktrees []*Info) {
+	sort.SliceStable(worktrees, func(i, j int) bool {
+		return worktrees[i].LastCommit.Before(worktrees[j].LastCommit)
+	})
+}
@@ -0,0 +1,55 @@
+package worktree
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilterStale(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	fresh := &Info{Path: "/repo-fresh", LastCommit: now.Add(-time.Hour)}
+	old := &Info{Path: "/repo-old", LastCommit: now.Add(-60 * 24 * time.Hour)}
+	older := &Info{Path: "/repo-older", LastCommit: now.Add(-90 * 24 * time.Hour)}
+	unknown := &Info{Path: "/repo-unknown"}
+
+	got := FilterStale([]*Info{fresh, old, unknown, older}, 30*24*time.Hour, now)
+	want := []*Info{old, unknown, older}
+	if len(got) != len(want) {
+		t.Fatalf("FilterStale() returned %d worktrees, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("FilterStale()[%d] = %s, want %s", i, got[i].Path, want[i].Path)
+		}
+	}
+
+	SortByLastCommit(got)
+	want = []*Info{unknown, older, old}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SortByLastCommit()[%d] = %s, want %s", i, got[i].Path, want[i].Path)
+		}
+	}
+}
+
+func TestLoadLastCommitTimes(t *testing.T) {
+	mainPath := setupLinkedRepo(t)
+	parent := filepath.Dir(mainPath)
+
+	worktrees := []*Info{
+		{Path: mainPath},
+		{Path: filepath.Join(parent, "repo-pr42")},
+		{Path: filepath.Join(parent, "missing")},
+	}
+	LoadLastCommitTimes(worktrees)
+
+	for _, wt := range worktrees[:2] {
+		if wt.LastCommit.IsZero() || time.Since(wt.LastCommit) > time.Hour {
+			t.Errorf("LastCommit of %s = %v, want the time of the initial commit", wt.Path, wt.LastCommit)
+		}
+	}
+	if !worktrees[2].LastCommit.IsZero() {
+		t.Errorf("LastCommit of missing worktree = %v, want zero", worktrees[2].LastCommit)
+	}
+}
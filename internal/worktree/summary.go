@@ -0,0 +1,40 @@
+package worktree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SummaryRecord is the line appended to the --summary-file manifest for each
+// created worktree. Like the post_create payload, fields are only ever added.
+type SummaryRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	// PRNumber is omitted for branch worktrees
+	PRNumber int `json:"prNumber,omitempty"`
+	// Branch is omitted for detached worktrees
+	Branch string `json:"branch,omitempty"`
+	Path   string `json:"path"`
+	Commit string `json:"commit"`
+}
+
+// AppendSummary appends record to the manifest at path as a line of JSON,
+// creating the file if it doesn't exist. The line is written with a single
+// write, so records of concurrent checkouts don't interleave.
+func AppendSummary(path string, record SummaryRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode summary record: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open summary file: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,55 @@
+package worktree
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worktrees.jsonl")
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []SummaryRecord{
+		{Timestamp: timestamp, PRNumber: 42, Branch: "feature", Path: "/work/repo-pr42", Commit: "abc123"},
+		{Timestamp: timestamp, Branch: "local", Path: "/work/repo-local", Commit: "def456"},
+		{Timestamp: timestamp, PRNumber: 7, Path: "/work/repo-pr7", Commit: "0123abc"},
+	}
+	for _, record := range records {
+		if err := AppendSummary(path, record); err != nil {
+			t.Fatalf("AppendSummary() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open summary file: %v", err)
+	}
+	defer f.Close()
+
+	want := []string{
+		`{"timestamp":"2024-05-01T12:00:00Z","prNumber":42,"branch":"feature","path":"/work/repo-pr42","commit":"abc123"}`,
+		`{"timestamp":"2024-05-01T12:00:00Z","branch":"local","path":"/work/repo-local","commit":"def456"}`,
+		`{"timestamp":"2024-05-01T12:00:00Z","prNumber":7,"path":"/work/repo-pr7","commit":"0123abc"}`,
+	}
+	scanner := bufio.NewScanner(f)
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("summary file has %d lines, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %s, want %s", i, got[i], want[i])
+		}
+		var record SummaryRecord
+		if err := json.Unmarshal([]byte(got[i]), &record); err != nil {
+			t.Errorf("line %d is not valid JSON: %v", i, err)
+		}
+	}
+}
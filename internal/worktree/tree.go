@@ -0,0 +1,59 @@
+package worktree
+
+import (
+	"sort"
+	"strings"
+)
+
+// Group is a set of worktrees whose branches share a prefix segment
+type Group struct {
+	// Prefix is the shared segment, or "" for worktrees that aren't grouped
+	Prefix    string
+	Worktrees []*Info
+}
+
+// GroupByPrefix groups worktrees by the first segment of their branch name,
+// split on "/" or "-" (e.g. "team-a/login" and "team-a/logout" share "team-a").
+// Only prefixes shared by at least two worktrees form a group; the rest are
+// returned first in a group with an empty prefix. Groups are sorted by prefix
+// and worktrees keep their original order within a group.
+func GroupByPrefix(worktrees []*Info) []Group {
+	byPrefix := make(map[string][]*Info)
+	for _, wt := range worktrees {
+		prefix := branchPrefix(wt.Branch)
+		byPrefix[prefix] = append(byPrefix[prefix], wt)
+	}
+
+	ungrouped := Group{}
+	var groups []Group
+	for _, wt := range worktrees {
+		prefix := branchPrefix(wt.Branch)
+		members := byPrefix[prefix]
+		if prefix == "" || len(members) < 2 {
+			ungrouped.Worktrees = append(ungrouped.Worktrees, wt)
+			continue
+		}
+		if members[0] == wt {
+			groups = append(groups, Group{Prefix: prefix, Worktrees: members})
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Prefix < groups[j].Prefix
+	})
+
+	if len(ungrouped.Worktrees) > 0 {
+		groups = append([]Group{ungrouped}, groups...)
+	}
+	return groups
+}
+
+// branchPrefix returns the part of the branch name before the first "/" or "-",
+// or "" if there is no separator
+func branchPrefix(branch string) string {
+	i := strings.IndexAny(branch, "/-")
+	if i <= 0 {
+		return ""
+	}
+	return branch[:i]
+}
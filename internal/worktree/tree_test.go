@@ -0,0 +1,71 @@
+package worktree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByPrefix(t *testing.T) {
+	worktrees := func(branches ...string) []*Info {
+		var infos []*Info
+		for _, b := range branches {
+			infos = append(infos, &Info{Branch: b})
+		}
+		return infos
+	}
+	summarize := func(groups []Group) [][]string {
+		var got [][]string
+		for _, g := range groups {
+			line := []string{g.Prefix}
+			for _, wt := range g.Worktrees {
+				line = append(line, wt.Branch)
+			}
+			got = append(got, line)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name     string
+		branches []string
+		want     [][]string
+	}{
+		{
+			name:     "groups shared prefixes and sorts them",
+			branches: []string{"team/login", "fix-typo", "alice/search", "team/logout", "alice/auth"},
+			want: [][]string{
+				{"", "fix-typo"},
+				{"alice", "alice/search", "alice/auth"},
+				{"team", "team/login", "team/logout"},
+			},
+		},
+		{
+			name:     "dash and slash separators",
+			branches: []string{"team-login", "team/logout"},
+			want: [][]string{
+				{"team", "team-login", "team/logout"},
+			},
+		},
+		{
+			name:     "no separators or detached",
+			branches: []string{"feature", ""},
+			want: [][]string{
+				{"", "feature", ""},
+			},
+		},
+		{
+			name:     "empty",
+			branches: nil,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(GroupByPrefix(worktrees(tt.branches...)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupByPrefix(%v) = %v, want %v", tt.branches, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,73 @@
 package worktree
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/knqyf263/gh-worktree/internal/git"
+	"github.com/knqyf263/gh-worktree/internal/github"
+	"github.com/knqyf263/gh-worktree/internal/setup"
+	"github.com/knqyf263/gh-worktree/internal/validate"
 )
 
 // Info represents information about a git worktree
 type Info struct {
-	Path     string
-	Commit   string
-	Branch   string
-	PRNumber int
-	Title    string
+	Path   string `json:"path"`
+	Commit string `json:"commit"`
+	Branch string `json:"branch"`
+	// Detached is set for worktrees with a detached HEAD, which have no Branch
+	Detached bool   `json:"detached,omitempty"`
+	PRNumber int    `json:"prNumber,omitempty"`
+	Title    string `json:"title,omitempty"`
+	// BaseBranch and BaseCommit record what a branch worktree was created from
+	BaseBranch string `json:"baseBranch,omitempty"`
+	BaseCommit string `json:"baseCommit,omitempty"`
+	// Isolated is set for standalone clones created with --isolate-git-dir
+	Isolated bool `json:"isolated,omitempty"`
+	// LastCommit is the committer date of HEAD, only set by LoadLastCommitTimes
+	LastCommit time.Time `json:"lastCommit,omitzero"`
+}
+
+// ShortCommit returns the commit SHA abbreviated to n characters.
+// If n is zero or negative, or longer than the SHA, the full SHA is returned.
+func (i *Info) ShortCommit(n int) string {
+	return abbrevCommit(i.Commit, n)
+}
+
+// Base describes what a branch worktree was created from, e.g. "main@3f2a9c1d".
+// Returns "" if the base wasn't recorded.
+func (i *Info) Base(n int) string {
+	if i.BaseCommit == "" {
+		return i.BaseBranch
+	}
+	return fmt.Sprintf("%s@%s", i.BaseBranch, abbrevCommit(i.BaseCommit, n))
+}
+
+// BranchLabel returns the branch name for display, or "(detached at <commit>)"
+// with the commit abbreviated to n characters for a detached worktree
+func (i *Info) BranchLabel(n int) string {
+	if i.Detached || i.Branch == "" {
+		return fmt.Sprintf("(detached at %s)", i.ShortCommit(n))
+	}
+	return i.Branch
+}
+
+func abbrevCommit(commit string, n int) string {
+	if n <= 0 || n >= len(commit) {
+		return commit
+	}
+	return commit[:n]
 }
 
 // List returns all configured worktrees
@@ -28,14 +77,19 @@ func List() ([]*Info, error) {
 		return nil, fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	cmd := exec.Command("git", "-C", gitRoot, "worktree", "list", "--porcelain")
+	cmd := git.Command("-C", gitRoot, "worktree", "list", "--porcelain")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get worktree list: %w", err)
 	}
 
+	return parseWorktreeList(string(output)), nil
+}
+
+// parseWorktreeList parses the output of `git worktree list --porcelain`
+func parseWorktreeList(output string) []*Info {
 	var worktrees []*Info
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
 
 	var currentWorktree *Info
 	for _, line := range lines {
@@ -65,6 +119,10 @@ func List() ([]*Info, error) {
 					currentWorktree.Branch = branchRef
 				}
 			}
+		} else if line == "detached" {
+			if currentWorktree != nil {
+				currentWorktree.Detached = true
+			}
 		}
 	}
 
@@ -73,19 +131,36 @@ func List() ([]*Info, error) {
 		worktrees = append(worktrees, currentWorktree)
 	}
 
-	return worktrees, nil
+	return worktrees
 }
 
 // ListPRWorktrees returns only PR worktrees
 func ListPRWorktrees(repoName string) ([]*Info, error) {
+	prWorktrees, _, err := ListAllWorktrees(repoName)
+	return prWorktrees, err
+}
+
+// ListBranchWorktrees lists all branch worktrees (non-PR worktrees).
+func ListBranchWorktrees(repoName string) ([]*Info, error) {
+	_, branchWorktrees, err := ListAllWorktrees(repoName)
+	return branchWorktrees, err
+}
+
+// ListAllWorktrees lists all worktrees (PR and branch worktrees).
+// Each worktree is classified exactly once, so the two lists never overlap.
+// Worktrees with recorded metadata are listed wherever they live; the
+// directory name is only used for worktrees without it (see classifyWorktree).
+// Isolated clones (--isolate-git-dir) are listed with the PR worktrees.
+// PR worktrees are sorted by PR number and branch worktrees by branch name.
+func ListAllWorktrees(repoName string) (prWorktrees []*Info, branchWorktrees []*Info, err error) {
 	allWorktrees, err := List()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	gitRoot, err := git.GetRoot()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get git root: %w", err)
+		return nil, nil, fmt.Errorf("failed to get git root: %w", err)
 	}
 
 	// Resolve symlinks in parent directory for comparison
@@ -95,8 +170,11 @@ func ListPRWorktrees(repoName string) ([]*Info, error) {
 		// If EvalSymlinks fails, use the original path
 		parentDir = filepath.Dir(gitRoot)
 	}
+	nested, err := nestedDir(gitRoot)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	var prWorktrees []*Info
 	for _, wt := range allWorktrees {
 		// Skip main worktree
 		if wt.Path == gitRoot {
@@ -110,174 +188,625 @@ func ListPRWorktrees(repoName string) ([]*Info, error) {
 			// If EvalSymlinks fails, use the original path
 			wtParentDir = filepath.Dir(wt.Path)
 		}
+		namePrefix, inWorktreeDir := dirNamePrefix(wtParentDir, parentDir, nested, repoName)
 
-		// Skip if not in parent directory
-		if wtParentDir != parentDir {
-			continue
+		switch classifyWorktree(wt, namePrefix, inWorktreeDir) {
+		case "pr":
+			prWorktrees = append(prWorktrees, wt)
+		case "branch":
+			wt.BaseBranch, wt.BaseCommit = GetBranchBase(wt.Branch)
+			branchWorktrees = append(branchWorktrees, wt)
 		}
+	}
 
-		baseName := filepath.Base(wt.Path)
-
-		// Check if this is a PR worktree based on naming pattern (repo-pr###)
-		isPRByName := false
-		if strings.HasPrefix(baseName, repoName+"-pr") {
-			prPrefix := repoName + "-pr"
-			if len(baseName) > len(prPrefix) {
-				prNumberStr := baseName[len(prPrefix):]
-				var prNumber int
-				if n, err := fmt.Sscanf(prNumberStr, "%d", &prNumber); err == nil && n == 1 {
-					wt.PRNumber = prNumber
-					isPRByName = true
-				}
-			}
-		}
+	isolated, err := listIsolated(gitRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	prWorktrees = append(prWorktrees, isolated...)
 
-		// Also check metadata for worktree type
-		worktreeType, _ := GetWorktreeType(wt.Branch)
-		isPRByMetadata := worktreeType == "pr"
-
-		// Include if it's a PR worktree by either naming or metadata
-		if isPRByName || isPRByMetadata {
-			// Get PR title from git config
-			wt.Title = GetPRTitle(wt.Path, wt.Branch)
-			
-			// If PR number not set yet, try to get it from git config
-			if wt.PRNumber == 0 {
-				prNumberStr, err := git.GetConfig(gitRoot, fmt.Sprintf("branch.%s.gh-worktree-pr-number", wt.Branch))
-				if err == nil && prNumberStr != "" {
-					if prNum, err := strconv.Atoi(strings.TrimSpace(prNumberStr)); err == nil {
-						wt.PRNumber = prNum
-					}
-				}
+	sort.SliceStable(prWorktrees, func(i, j int) bool {
+		return prWorktrees[i].PRNumber < prWorktrees[j].PRNumber
+	})
+	sort.SliceStable(branchWorktrees, func(i, j int) bool {
+		return branchWorktrees[i].Branch < branchWorktrees[j].Branch
+	})
+
+	return prWorktrees, branchWorktrees, nil
+}
+
+// classifyWorktree returns "pr" or "branch" for a linked worktree, or "" if it
+// isn't managed by gh-worktree, and fills in the PR number and title of PR
+// worktrees. Recorded metadata takes precedence; worktrees without metadata
+// are classified by their directory name (<prefix>pr<N> or <prefix><branch>)
+// if they are in the parent (or nested) directory.
+func classifyWorktree(wt *Info, namePrefix string, inWorktreeDir bool) string {
+	worktreeType, _ := GetWorktreeType(wt.Branch)
+	baseName := filepath.Base(wt.Path)
+
+	var prNumberByName int
+	isPRByName := false
+	if inWorktreeDir {
+		prNumberByName, isPRByName = prNumberFromDirName(baseName, namePrefix)
+	}
+
+	switch {
+	case worktreeType == "branch":
+		return "branch"
+	case worktreeType == "pr" || isPRByName:
+		wt.Title = GetPRTitle(wt.Branch)
+
+		// Prefer the PR number from git config: a branch worktree promoted to a
+		// PR keeps its branch-named directory
+		wt.PRNumber = prNumberByName
+		if prNumberStr, err := getMetadata(wt.Branch, metaPRNumber); err == nil && prNumberStr != "" {
+			if prNum, err := strconv.Atoi(strings.TrimSpace(prNumberStr)); err == nil {
+				wt.PRNumber = prNum
 			}
-			
-			prWorktrees = append(prWorktrees, wt)
 		}
+		return "pr"
+	case inWorktreeDir && strings.HasPrefix(baseName, namePrefix):
+		return "branch"
+	default:
+		return ""
 	}
+}
 
-	return prWorktrees, nil
+// dirNamePrefix returns the prefix of worktree directory names in wtParentDir:
+// "<repo>-" next to the main worktree, and "" in the nested worktrees directory
+// and in label subdirectories (--label-dir). It returns false otherwise.
+func dirNamePrefix(wtParentDir, parentDir, nested, repoName string) (string, bool) {
+	switch {
+	case wtParentDir == parentDir:
+		return repoName + "-", true
+	case nested != "" && wtParentDir == nested:
+		return "", true
+	case filepath.Dir(wtParentDir) == labelRoot(parentDir, nested, repoName):
+		return "", true
+	default:
+		return "", false
+	}
 }
 
-// ListBranchWorktrees lists all branch worktrees (non-PR worktrees).
-func ListBranchWorktrees(repoName string) ([]*Info, error) {
-	allWorktrees, err := List()
-	if err != nil {
-		return nil, err
+// prNumberFromDirName returns the PR number of a worktree directory named
+// "<prefix>pr<N>", or false if the name doesn't follow that pattern
+func prNumberFromDirName(baseName, prefix string) (int, bool) {
+	suffix, ok := strings.CutPrefix(baseName, prefix+"pr")
+	if !ok {
+		return 0, false
 	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil || n <= 0 || strconv.Itoa(n) != suffix {
+		return 0, false
+	}
+	return n, true
+}
 
-	gitRoot, err := git.GetRoot()
+// GetPRTitle retrieves the PR title recorded for the branch
+func GetPRTitle(branchName string) string {
+	if branchName == "" {
+		return ""
+	}
+
+	title, err := getMetadata(branchName, metaPRTitle)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get git root: %w", err)
+		return ""
 	}
+	return title
+}
 
-	// Resolve symlinks in parent directory for comparison
-	parentDir := filepath.Dir(gitRoot)
-	parentDir, err = filepath.EvalSymlinks(parentDir)
+// Remove removes a worktree.
+// When invoked from inside the worktree being removed, it first moves to the main worktree
+// so that later git commands (e.g. DeleteBranch) don't run in a deleted directory.
+func Remove(worktreePath string, force bool) error {
+	return RemoveContext(context.Background(), worktreePath, force)
+}
+
+// RemoveContext is like Remove, but `git worktree remove` is interrupted when ctx is done
+func RemoveContext(ctx context.Context, worktreePath string, force bool) error {
+	if err := CheckNotMain(worktreePath, "remove"); err != nil {
+		return err
+	}
+	gitRoot, err := git.GetRoot()
 	if err != nil {
-		// If EvalSymlinks fails, use the original path
-		parentDir = filepath.Dir(gitRoot)
+		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	var branchWorktrees []*Info
-	for _, wt := range allWorktrees {
-		// Skip main worktree
-		if wt.Path == gitRoot {
-			continue
+	if isWithin(worktreePath) {
+		if err := os.Chdir(gitRoot); err != nil {
+			return fmt.Errorf("failed to change directory to %s: %w", gitRoot, err)
 		}
+	}
 
-		baseName := filepath.Base(wt.Path)
-		
-		// Check if it's NOT a PR worktree (doesn't match repo-pr### pattern)
-		if strings.HasPrefix(baseName, repoName+"-pr") {
-			// Check if it's actually a PR worktree
-			prPrefix := repoName + "-pr"
-			if len(baseName) > len(prPrefix) {
-				prNumberStr := baseName[len(prPrefix):]
-				var prNumber int
-				if n, err := fmt.Sscanf(prNumberStr, "%d", &prNumber); err == nil && n == 1 {
-					// This is a PR worktree, skip it
-					continue
-				}
-			}
-		}
+	if entry, ok := isolatedEntry(gitRoot, worktreePath); ok {
+		return removeIsolated(gitRoot, entry, force)
+	}
 
-		// Resolve symlinks in worktree path for comparison
-		wtParentDir := filepath.Dir(wt.Path)
-		wtParentDir, err = filepath.EvalSymlinks(wtParentDir)
-		if err != nil {
-			// If EvalSymlinks fails, use the original path
-			wtParentDir = filepath.Dir(wt.Path)
+	// A worktree whose directory was deleted by hand stays registered until pruned
+	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
+		if registered, _ := IsRegistered(worktreePath); registered {
+			return pruneMissing(gitRoot, worktreePath)
 		}
+	}
 
-		// Check if it starts with repo name and is in parent directory
-		if strings.HasPrefix(baseName, repoName+"-") && wtParentDir == parentDir {
-			// Check worktree type from git config
-			worktreeType, _ := GetWorktreeType(wt.Branch)
-			if worktreeType == "branch" || worktreeType == "" {
-				branchWorktrees = append(branchWorktrees, wt)
-			}
+	args := []string{"-C", gitRoot, "worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, worktreePath)
+
+	cmd := git.CommandContext(ctx, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// IsMain reports whether path is the main worktree, also when it is reached
+// through a symlink or given relative to the current directory
+func IsMain(path string) (bool, error) {
+	mainWorktree, err := git.GetMainWorktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get main worktree: %w", err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	return sameFile(absPath, mainWorktree), nil
+}
+
+// CheckNotMain refuses to operate on the main worktree, however path was
+// arrived at, e.g. through a crafted identifier or a colliding name
+func CheckNotMain(path, operation string) error {
+	isMain, err := IsMain(path)
+	if err != nil {
+		return err
+	}
+	if isMain {
+		return fmt.Errorf("refusing to %s the main worktree at %s", operation, path)
+	}
+	return nil
+}
+
+// Interrupted reports whether err comes from a creation that was stopped by
+// canceling ctx or by a signal that killed git, e.g. Ctrl-C reaching it first
+func Interrupted(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var exitErr *exec.ExitError
+	// ExitCode is -1 for a process terminated by a signal
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == -1
+}
+
+// RollBack removes what an interrupted creation left behind: the worktree or
+// clone at worktreePath, which was free before, and newBranch unless it is "".
+// Failures are printed as warnings.
+func RollBack(worktreePath, newBranch string) {
+	fmt.Fprintln(os.Stderr, "→ Rolling back the partial checkout...")
+	if err := CheckNotMain(worktreePath, "roll back"); err != nil {
+		fmt.Fprintf(os.Stderr, "  ⚠ %v\n", err)
+		return
+	}
+	registered, _ := IsRegistered(worktreePath)
+	if registered || IsIsolated(worktreePath) {
+		if err := Remove(worktreePath, true); err != nil {
+			fmt.Fprintf(os.Stderr, "  ⚠ Failed to remove %s: %v\n", worktreePath, err)
+		}
+	}
+	// Whatever is left wasn't registered yet, e.g. a clone interrupted before it was recorded
+	if err := os.RemoveAll(worktreePath); err != nil {
+		fmt.Fprintf(os.Stderr, "  ⚠ Failed to remove %s: %v\n", worktreePath, err)
+	}
+	if newBranch != "" && git.BranchExists(newBranch) {
+		if err := DeleteBranch(newBranch); err != nil {
+			fmt.Fprintf(os.Stderr, "  ⚠ Failed to delete branch %s: %v\n", newBranch, err)
 		}
 	}
+}
+
+// pruneMissing clears the registration of a worktree whose directory no longer
+// exists. git worktree prune also clears other worktrees whose directories are
+// gone, which are equally stale.
+func pruneMissing(gitRoot, worktreePath string) error {
+	cmd := git.Command("-C", gitRoot, "worktree", "prune")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to prune worktree %s: %w", worktreePath, err)
+	}
 
-	return branchWorktrees, nil
+	registered, err := IsRegistered(worktreePath)
+	if err != nil {
+		return err
+	}
+	if registered {
+		return fmt.Errorf("worktree %s is still registered after pruning; it may be locked (see git worktree unlock)", worktreePath)
+	}
+	return nil
 }
 
-// ListAllWorktrees lists all worktrees (PR and branch worktrees).
-func ListAllWorktrees(repoName string) (prWorktrees []*Info, branchWorktrees []*Info, err error) {
-	prWorktrees, err = ListPRWorktrees(repoName)
+// Update fetches and fast-forwards the branch checked out in a worktree
+func Update(worktreePath string) error {
+	return git.ExecuteCommands([][]string{{"-C", worktreePath, "pull", "--ff-only", "--no-tags"}})
+}
+
+// IsRegistered reports whether path is a worktree known to git
+func IsRegistered(path string) (bool, error) {
+	worktrees, err := List()
 	if err != nil {
-		return nil, nil, err
+		return false, err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	for _, wt := range worktrees {
+		wtPath := wt.Path
+		if resolved, err := filepath.EvalSymlinks(wtPath); err == nil {
+			wtPath = resolved
+		}
+		if wtPath == path {
+			return true, nil
+		}
 	}
+	return false, nil
+}
 
-	branchWorktrees, err = ListBranchWorktrees(repoName)
+// UntrackedFiles returns the untracked files and directories that Clean would remove.
+// Ignored files are not included.
+func UntrackedFiles(worktreePath string) ([]string, error) {
+	output, err := git.Command("-C", worktreePath, "clean", "-n", "-d").Output()
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
 	}
 
-	return prWorktrees, branchWorktrees, nil
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if file, ok := strings.CutPrefix(line, "Would remove "); ok {
+			files = append(files, file)
+		}
+	}
+	return files, nil
 }
 
-// GetPRTitle retrieves the PR title from git config
-func GetPRTitle(worktreePath, branchName string) string {
-	if branchName == "" {
-		return ""
+// Clean removes untracked files and directories from a worktree
+func Clean(worktreePath string) error {
+	return git.ExecuteCommands([][]string{{"-C", worktreePath, "clean", "-f", "-d"}})
+}
+
+// isWithin reports whether the current directory is dir or one of its subdirectories
+func isWithin(dir string) bool {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	return containsPath(dir, cwd)
+}
+
+// containsPath reports whether path is dir or inside it, resolving symlinks in both
+func containsPath(dir, path string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
 	}
 
-	title, err := git.GetConfig(worktreePath, fmt.Sprintf("branch.%s.gh-worktree-pr-title", branchName))
+	rel, err := filepath.Rel(dir, path)
 	if err != nil {
-		return ""
+		return false
 	}
-	return title
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
 }
 
-// Remove removes a worktree
-func Remove(worktreePath string, force bool) error {
-	args := []string{"worktree", "remove"}
-	if force {
-		args = append(args, "--force")
+// RelativePath returns path relative to cwd for output, e.g. for the shell
+// function to cd into. Symlinks are resolved first so that a symlinked cwd
+// doesn't produce a path climbing through the symlink target. If there is no
+// relative path (such as across drives on Windows), the absolute path is
+// returned with forward slashes, which shells on every platform accept.
+func RelativePath(cwd, path string) string {
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+	resolvedPath := path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		resolvedPath = resolved
 	}
-	args = append(args, worktreePath)
 
-	cmd := exec.Command("git", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	rel, err := filepath.Rel(cwd, resolvedPath)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+	return rel
 }
 
 // DeleteBranch deletes a git branch
 func DeleteBranch(branchName string) error {
-	cmd := exec.Command("git", "branch", "-D", branchName)
+	cmd := git.Command("branch", "-D", branchName)
 	return cmd.Run()
 }
 
-// GeneratePath generates the path for a PR worktree
+// NestedDirName is the directory inside the main worktree that holds the
+// worktrees when worktree.location is "nested"
+const NestedDirName = "worktrees"
+
+// NestedDir returns the directory holding the worktrees if worktree.location
+// is "nested", or "" if worktrees are placed next to the main worktree
+func NestedDir() (string, error) {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git root: %w", err)
+	}
+	return nestedDir(gitRoot)
+}
+
+func nestedDir(gitRoot string) (string, error) {
+	config, err := setup.LoadConfig(gitRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	nested, err := config.Worktree.Nested()
+	if err != nil || !nested {
+		return "", err
+	}
+	return filepath.Join(gitRoot, NestedDirName), nil
+}
+
+// GeneratePath generates the path for a PR worktree: ../repo-pr{N}, or
+// worktrees/pr{N} inside the main worktree when worktree.location is "nested"
 func GeneratePath(repoName string, prNumber int) (string, error) {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
 		return "", fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	return filepath.Join(filepath.Dir(gitRoot), fmt.Sprintf("%s-pr%d", repoName, prNumber)), nil
+	nested, err := nestedDir(gitRoot)
+	if err != nil {
+		return "", err
+	}
+	if nested != "" {
+		return checkedPath(filepath.Join(nested, fmt.Sprintf("pr%d", prNumber)))
+	}
+
+	return GeneratePathIn(filepath.Dir(gitRoot), repoName, prNumber)
+}
+
+// Sources of PR worktree directory names for --name-from
+const (
+	// NameFromNumber names the directory repo-pr{N} (the default)
+	NameFromNumber = "number"
+	// NameFromHead names the directory after the PR's head branch
+	NameFromHead = "head"
+	// NameFromTitle names the directory after a slug of the PR title
+	NameFromTitle = "title"
+	// NameFromBranch names the directory after the local branch, which differs
+	// from the head branch with --branch or --title-branch. Callers pass the PR
+	// with its head ref replaced by the local branch (see LocalBranchName).
+	NameFromBranch = "branch"
+)
+
+// ResolveRepoName returns the repository name used in worktree names such as
+// repo-pr{N}: assumed if set, otherwise repo_name from the config, defaulting
+// to the name of the main worktree's directory at gitRoot. Pinning the name
+// keeps existing worktrees recognized after the main worktree is renamed.
+func ResolveRepoName(gitRoot, assumed string) (string, error) {
+	repoName := assumed
+	if repoName == "" {
+		config, err := setup.LoadConfig(gitRoot)
+		if err != nil {
+			return "", fmt.Errorf("failed to load config: %w", err)
+		}
+		repoName = config.RepoName
+	}
+	if repoName == "" {
+		repoName = filepath.Base(gitRoot)
+	}
+	if err := validate.RepoName(repoName); err != nil {
+		return "", err
+	}
+	return repoName, nil
+}
+
+// ResolveNameFrom returns nameFrom, or the configured worktree.name_from if it
+// is empty, defaulting to NameFromNumber
+func ResolveNameFrom(nameFrom string) (string, error) {
+	if nameFrom == "" {
+		gitRoot, err := git.GetRoot()
+		if err != nil {
+			return "", fmt.Errorf("failed to get git root: %w", err)
+		}
+		config, err := setup.LoadConfig(gitRoot)
+		if err != nil {
+			return "", fmt.Errorf("failed to load config: %w", err)
+		}
+		nameFrom = config.Worktree.NameFrom
+	}
+	if nameFrom == "" {
+		return NameFromNumber, nil
+	}
+	if err := validate.NameFrom(nameFrom); err != nil {
+		return "", err
+	}
+	return nameFrom, nil
+}
+
+// GeneratePathForPR generates the path for a PR worktree named according to
+// nameFrom. Worktrees not named after the PR number are recognized as PR
+// worktrees through their metadata.
+func GeneratePathForPR(repoName string, pr *github.PullRequest, nameFrom string) (string, error) {
+	if nameFrom == NameFromNumber {
+		return GeneratePath(repoName, pr.Number)
+	}
+	name, err := PRDirName(pr, nameFrom)
+	if err != nil {
+		return "", err
+	}
+	return GeneratePathForBranch(repoName, name)
+}
+
+// GeneratePathForPRIn is like GeneratePathForPR but creates the worktree inside baseDir
+func GeneratePathForPRIn(baseDir, repoName string, pr *github.PullRequest, nameFrom string) (string, error) {
+	if nameFrom == NameFromNumber {
+		return GeneratePathIn(baseDir, repoName, pr.Number)
+	}
+	name, err := PRDirName(pr, nameFrom)
+	if err != nil {
+		return "", err
+	}
+	return GeneratePathForBranchIn(baseDir, repoName, name)
+}
+
+// ResolveLabelDir reports whether PR worktrees are grouped by label: always
+// with --label-dir (labelDir), otherwise if worktree.label_dir is set
+func ResolveLabelDir(labelDir bool) (bool, error) {
+	if labelDir {
+		return true, nil
+	}
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return false, fmt.Errorf("failed to get git root: %w", err)
+	}
+	config, err := setup.LoadConfig(gitRoot)
+	if err != nil {
+		return false, fmt.Errorf("failed to load config: %w", err)
+	}
+	return config.Worktree.LabelDir != nil && *config.Worktree.LabelDir, nil
+}
+
+// GeneratePathForPRByLabel is like GeneratePathForPR but nests the worktree in
+// a subdirectory named after the PR's primary label:
+// ../repo-worktrees/{label}/pr{N}, or worktrees/{label}/pr{N} when
+// worktree.location is "nested". PRs without labels get the usual path.
+func GeneratePathForPRByLabel(repoName string, pr *github.PullRequest, nameFrom string) (string, error) {
+	label := labelDirName(pr.PrimaryLabel())
+	if label == "" {
+		return GeneratePathForPR(repoName, pr, nameFrom)
+	}
+
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git root: %w", err)
+	}
+	nested, err := nestedDir(gitRoot)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("pr%d", pr.Number)
+	if nameFrom != NameFromNumber {
+		branchName, err := PRDirName(pr, nameFrom)
+		if err != nil {
+			return "", err
+		}
+		name = sanitizeBranchNameForPath(branchName)
+	}
+	return checkedPath(filepath.Join(labelRoot(filepath.Dir(gitRoot), nested, repoName), label, name))
+}
+
+// labelRoot returns the directory holding the label subdirectories: the
+// nested worktrees directory, or repo-worktrees next to the main worktree
+func labelRoot(parentDir, nested, repoName string) string {
+	if nested != "" {
+		return nested
+	}
+	return filepath.Join(parentDir, repoName+"-worktrees")
+}
+
+// labelDirName converts a label to a safe directory name. It is sanitized like
+// a branch name, and whitespace and characters other than letters, digits,
+// '.', '_' and '-' are replaced with '-'. It returns "" if nothing is left.
+func labelDirName(label string) string {
+	sanitized := sanitizeBranchNameForPath(strings.TrimSpace(label))
+	sanitized = unsafeLabelCharsPattern.ReplaceAllString(sanitized, "-")
+	return strings.Trim(sanitized, "-.")
+}
+
+var unsafeLabelCharsPattern = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// PRDirName derives the name of a PR worktree directory from the PR's head
+// branch or title. The name is sanitized like a branch worktree's.
+func PRDirName(pr *github.PullRequest, nameFrom string) (string, error) {
+	var name string
+	switch nameFrom {
+	case NameFromHead, NameFromBranch:
+		name = pr.Head.Ref
+	case NameFromTitle:
+		name = github.TitleBranchName(pr)
+	default:
+		return "", validate.NameFrom(nameFrom)
+	}
+	if err := validate.BranchName(name); err != nil {
+		return "", fmt.Errorf("cannot name the worktree after the PR %s: %w", nameFrom, err)
+	}
+	return name, nil
+}
+
+// GeneratePathIn generates the path for a PR worktree inside baseDir
+func GeneratePathIn(baseDir, repoName string, prNumber int) (string, error) {
+	return checkedPath(filepath.Join(baseDir, fmt.Sprintf("%s-pr%d", repoName, prNumber)))
+}
+
+// checkedPath returns path unless it collides with an existing entry on a
+// case-insensitive filesystem
+func checkedPath(path string) (string, error) {
+	if err := checkCaseCollision(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// CurrentBaseDir returns the parent of the current directory as the directory
+// to create worktrees in, so that they become siblings of the current directory.
+// It fails if the directory isn't writable or is inside a worktree (see checkBaseDir).
+func CurrentBaseDir() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	baseDir := filepath.Dir(cwd)
+	if err := checkBaseDir(baseDir); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(baseDir, ".gh-worktree-write-test-*")
+	if err != nil {
+		return "", fmt.Errorf("cannot create worktrees in %s: %w", baseDir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return baseDir, nil
+}
+
+// checkBaseDir returns an error if baseDir is inside the main worktree or a
+// linked worktree of the repository, where new worktrees would show up as
+// untracked files and be picked up by git add or recursive tools. This is
+// allowed when worktree.location is "nested", which opts into worktrees inside
+// the main worktree.
+func checkBaseDir(baseDir string) error {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		// Outside a repository there is nothing to pollute
+		return nil
+	}
+	nested, err := nestedDir(gitRoot)
+	if err != nil {
+		return err
+	}
+	if nested != "" {
+		return nil
+	}
+
+	worktrees, err := List()
+	if err != nil {
+		return err
+	}
+	for _, wt := range worktrees {
+		if containsPath(wt.Path, baseDir) {
+			return fmt.Errorf("cannot create worktrees in %s: it is inside the worktree %s (run from a directory whose parent is outside the repository, or set worktree.location to nested)", baseDir, wt.Path)
+		}
+	}
+	return nil
 }
 
 // sanitizeBranchNameForPath converts a git branch name to a safe directory name.
@@ -300,18 +829,58 @@ func sanitizeBranchNameForPath(branchName string) string {
 	return sanitized
 }
 
+// dirHashPrefixLen is the length of the part of a sanitized branch name kept
+// by ShortDirName; the hash and its separator add 7 more characters.
+const dirHashPrefixLen = 24
+
+// ShortDirName shortens a branch name used as a worktree directory name for
+// --dir-hash: if its sanitized form is longer than dirHashPrefixLen plus the
+// hash, it is truncated and a short hash of the full branch name is appended,
+// e.g. feature-very-long-descri-a1b2c3, so that branches sharing a long prefix
+// still get distinct directories. Shorter names are returned unchanged.
+func ShortDirName(branchName string) string {
+	sanitized := sanitizeBranchNameForPath(branchName)
+	if len(sanitized) <= dirHashPrefixLen+7 {
+		return branchName
+	}
+
+	prefix := sanitized[:dirHashPrefixLen]
+	// Don't cut a multi-byte character in half
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	prefix = strings.TrimRight(prefix, "-.")
+	sum := sha256.Sum256([]byte(branchName))
+	return fmt.Sprintf("%s-%x", prefix, sum[:3])
+}
+
 // GeneratePathForBranch generates the path for a branch worktree.
-// Format: ../repo-name-{branch-name}
+// Format: ../repo-name-{branch-name}, or worktrees/{branch-name} inside the
+// main worktree when worktree.location is "nested"
 // Branch names are sanitized to avoid filesystem issues while preserving readability.
+// On case-insensitive filesystems, a path differing only in case from an existing entry is an error.
 func GeneratePathForBranch(repoName string, branchName string) (string, error) {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
 		return "", fmt.Errorf("failed to get git root: %w", err)
 	}
 
+	nested, err := nestedDir(gitRoot)
+	if err != nil {
+		return "", err
+	}
+	if nested != "" {
+		return checkedPath(filepath.Join(nested, sanitizeBranchNameForPath(branchName)))
+	}
+
+	return GeneratePathForBranchIn(filepath.Dir(gitRoot), repoName, branchName)
+}
+
+// GeneratePathForBranchIn generates the path for a branch worktree inside baseDir
+func GeneratePathForBranchIn(baseDir, repoName, branchName string) (string, error) {
 	sanitizedBranchName := sanitizeBranchNameForPath(branchName)
 
-	return filepath.Join(filepath.Dir(gitRoot), fmt.Sprintf("%s-%s", repoName, sanitizedBranchName)), nil
+	return checkedPath(filepath.Join(baseDir, fmt.Sprintf("%s-%s", repoName, sanitizedBranchName)))
 }
 
 // DetectWorktreeType detects the type of worktree based on its path.
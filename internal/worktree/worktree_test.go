@@ -2,7 +2,11 @@ package worktree
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/knqyf263/gh-worktree/internal/github"
 )
 
 func TestGeneratePath(t *testing.T) {
@@ -51,6 +55,43 @@ func TestGeneratePath(t *testing.T) {
 	}
 }
 
+func TestGeneratePathForPRIn(t *testing.T) {
+	baseDir := t.TempDir()
+	pr := &github.PullRequest{Number: 42, Title: "Fix: login/logout race"}
+	pr.Head.Ref = "alice/fix-race"
+
+	tests := []struct {
+		name     string
+		nameFrom string
+		title    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "number", nameFrom: NameFromNumber, want: "repo-pr42"},
+		{name: "head branch is sanitized", nameFrom: NameFromHead, want: "repo-alice-fix-race"},
+		{name: "title slug", nameFrom: NameFromTitle, want: "repo-fix-login-logout-race-42"},
+		{name: "title without slug characters", nameFrom: NameFromTitle, title: "修正", want: "repo-pr-42"},
+		{name: "local branch", nameFrom: NameFromBranch, want: "repo-alice-fix-race"},
+		{name: "invalid source", nameFrom: "author", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := *pr
+			if tt.title != "" {
+				pr.Title = tt.title
+			}
+			got, err := GeneratePathForPRIn(baseDir, "repo", &pr, tt.nameFrom)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GeneratePathForPRIn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != filepath.Join(baseDir, tt.want) {
+				t.Errorf("GeneratePathForPRIn() = %s, want %s", got, filepath.Join(baseDir, tt.want))
+			}
+		})
+	}
+}
+
 func TestSanitizeBranchNameForPath(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -119,6 +160,58 @@ func TestSanitizeBranchNameForPath(t *testing.T) {
 	}
 }
 
+func TestLabelDirName(t *testing.T) {
+	tests := []struct {
+		name  string
+		label string
+		want  string
+	}{
+		{name: "simple label", label: "bug", want: "bug"},
+		{name: "label with slash", label: "kind/bug", want: "kind-bug"},
+		{name: "label with spaces", label: "good first issue", want: "good-first-issue"},
+		{name: "label with punctuation", label: "priority: high!", want: "priority-high"},
+		{name: "parent directory reference", label: "../etc", want: "etc"},
+		{name: "non-ASCII label", label: "バグ", want: "バグ"},
+		{name: "only unsafe characters", label: "???", want: ""},
+		{name: "no label", label: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := labelDirName(tt.label); got != tt.want {
+				t.Errorf("labelDirName(%q) = %q, want %q", tt.label, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortDirName(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		want   string
+	}{
+		{name: "short name is unchanged", branch: "feature/auth", want: "feature/auth"},
+		{name: "name at the limit is unchanged", branch: "feature/exactly-31-characters-x", want: "feature/exactly-31-characters-x"},
+		{name: "long name is truncated and hashed", branch: "feature/very-long-descriptive-name-12345", want: "feature-very-long-descri-06af0e"},
+		{name: "shared prefix gets a different hash", branch: "feature/very-long-descriptive-name-67890", want: "feature-very-long-descri-e39b85"},
+		{name: "multi-byte characters are not split", branch: "fix/日本語のとても長いブランチ名です", want: "fix-日本語のとて-b534ac"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ShortDirName(tt.branch)
+			if got != tt.want {
+				t.Errorf("ShortDirName(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+			// The same branch must always map to the same directory
+			if again := ShortDirName(tt.branch); again != got {
+				t.Errorf("ShortDirName(%q) = %q, then %q", tt.branch, got, again)
+			}
+		})
+	}
+}
+
 func TestGeneratePathForBranch(t *testing.T) {
 	// Skip if not in a git repository
 	if _, err := os.Stat(".git"); os.IsNotExist(err) {
@@ -191,36 +284,27 @@ func TestGeneratePathForBranch(t *testing.T) {
 
 func TestGetPRTitle(t *testing.T) {
 	tests := []struct {
-		name         string
-		worktreePath string
-		branchName   string
-		want         string
+		name       string
+		branchName string
+		want       string
 	}{
 		{
-			name:         "empty branch name",
-			worktreePath: ".",
-			branchName:   "",
-			want:         "",
-		},
-		{
-			name:         "invalid path",
-			worktreePath: "/non/existent/path",
-			branchName:   "test-branch",
-			want:         "",
+			name:       "empty branch name",
+			branchName: "",
+			want:       "",
 		},
 		{
-			name:         "non-existent config",
-			worktreePath: ".",
-			branchName:   "non-existent-branch",
-			want:         "",
+			name:       "non-existent config",
+			branchName: "non-existent-branch",
+			want:       "",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := GetPRTitle(tt.worktreePath, tt.branchName)
+			result := GetPRTitle(tt.branchName)
 			if result != tt.want {
-				t.Errorf("GetPRTitle(%s, %s) = %q, want %q", tt.worktreePath, tt.branchName, result, tt.want)
+				t.Errorf("GetPRTitle(%s) = %q, want %q", tt.branchName, result, tt.want)
 			}
 		})
 	}
@@ -283,6 +367,59 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestParseWorktreeList(t *testing.T) {
+	output := `worktree /src/repo
+HEAD 0123456789abcdef0123456789abcdef01234567
+branch refs/heads/main
+
+worktree /src/repo-pr42
+HEAD 89abcdef0123456789abcdef0123456789abcdef
+detached
+
+worktree /src/repo-feature
+HEAD fedcba9876543210fedcba9876543210fedcba98
+branch refs/heads/feature
+locked
+`
+
+	got := parseWorktreeList(output)
+	want := []*Info{
+		{Path: "/src/repo", Commit: "0123456789abcdef0123456789abcdef01234567", Branch: "main"},
+		{Path: "/src/repo-pr42", Commit: "89abcdef0123456789abcdef0123456789abcdef", Detached: true},
+		{Path: "/src/repo-feature", Commit: "fedcba9876543210fedcba9876543210fedcba98", Branch: "feature"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWorktreeList() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestInfoBranchLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		info Info
+		want string
+	}{
+		{
+			name: "branch",
+			info: Info{Branch: "feature", Commit: "0123456789abcdef"},
+			want: "feature",
+		},
+		{
+			name: "detached",
+			info: Info{Detached: true, Commit: "0123456789abcdef"},
+			want: "(detached at 0123456)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.BranchLabel(7); got != tt.want {
+				t.Errorf("BranchLabel(7) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRemove(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -328,3 +465,152 @@ func TestDeleteBranch(t *testing.T) {
 		})
 	}
 }
+
+func TestInfoShortCommit(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		name   string
+		commit string
+		n      int
+		want   string
+	}{
+		{
+			name:   "default abbreviation",
+			commit: commit,
+			n:      8,
+			want:   "01234567",
+		},
+		{
+			name:   "zero shows full SHA",
+			commit: commit,
+			n:      0,
+			want:   commit,
+		},
+		{
+			name:   "negative shows full SHA",
+			commit: commit,
+			n:      -1,
+			want:   commit,
+		},
+		{
+			name:   "longer than SHA",
+			commit: commit,
+			n:      100,
+			want:   commit,
+		},
+		{
+			name:   "empty commit",
+			commit: "",
+			n:      8,
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wt := &Info{Commit: tt.commit}
+			got := wt.ShortCommit(tt.n)
+			if got != tt.want {
+				t.Errorf("ShortCommit(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfoBase(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseBranch string
+		baseCommit string
+		n          int
+		want       string
+	}{
+		{
+			name:       "branch and commit",
+			baseBranch: "main",
+			baseCommit: "0123456789abcdef0123456789abcdef01234567",
+			n:          8,
+			want:       "main@01234567",
+		},
+		{
+			name:       "detached base",
+			baseBranch: "HEAD",
+			baseCommit: "0123456789abcdef0123456789abcdef01234567",
+			n:          0,
+			want:       "HEAD@0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			name: "not recorded",
+			n:    8,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wt := &Info{BaseBranch: tt.baseBranch, BaseCommit: tt.baseCommit}
+			got := wt.Base(tt.n)
+			if got != tt.want {
+				t.Errorf("Base(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	repo := filepath.Join(dir, "src", "repo")
+	worktreePath := filepath.Join(dir, "src", "repo-pr1")
+	for _, d := range []string{repo, worktreePath} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(repo, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cwd  string
+		path string
+		want string
+	}{
+		{name: "sibling", cwd: repo, path: worktreePath, want: filepath.Join("..", "repo-pr1")},
+		{name: "symlinked cwd", cwd: link, path: worktreePath, want: filepath.Join("..", "repo-pr1")},
+		{name: "same directory", cwd: worktreePath, path: worktreePath, want: "."},
+		// filepath.Rel fails for a relative path against an absolute cwd, like across Windows drives
+		{name: "no relative path", cwd: repo, path: filepath.Join("other", "..", "repo-pr2"), want: "repo-pr2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RelativePath(tt.cwd, tt.path); got != tt.want {
+				t.Errorf("RelativePath(%s, %s) = %s, want %s", tt.cwd, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
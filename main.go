@@ -1,30 +1,87 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/cli/go-gh/v2"
 	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
 	"github.com/cli/go-gh/v2/pkg/prompter"
 	"github.com/cli/go-gh/v2/pkg/repository"
+	"github.com/knqyf263/gh-worktree/internal/color"
 	"github.com/knqyf263/gh-worktree/internal/git"
 	"github.com/knqyf263/gh-worktree/internal/github"
+	"github.com/knqyf263/gh-worktree/internal/notify"
 	"github.com/knqyf263/gh-worktree/internal/setup"
 	"github.com/knqyf263/gh-worktree/internal/validate"
 	"github.com/knqyf263/gh-worktree/internal/worktree"
 	"github.com/spf13/cobra"
 )
 
+// version is the extension version, set at build time with
+// -ldflags "-X main.version=v1.2.3". If unset, the module version
+// stamped by the Go toolchain is used.
+var version = ""
+
+// assumeYes answers yes/no confirmations with yes, set by the persistent --yes flag.
+// Selections, text input and hard errors are not affected.
+var assumeYes bool
+
+// assumeRepoName overrides the repository name used in worktree names, set by
+// the persistent --assume-repo-name flag
+var assumeRepoName string
+
 func main() {
 	var opts worktree.CheckoutOptions
 	var shellMode bool
 
+	var verbose bool
+	var showVersion bool
+
 	rootCmd := &cobra.Command{
 		Use:   "gh-worktree",
 		Short: "A gh extension for git worktree operations",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			git.SetVerbose(verbose)
+		},
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if showVersion {
+				return versionRun()
+			}
+			return cmd.Help()
+		},
+	}
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print git commands to stderr as they run")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to all confirmation prompts")
+	rootCmd.PersistentFlags().StringVarP(&assumeRepoName, "assume-repo-name", "", "", "Repository name used in worktree names (default: repo_name from the config, or the main worktree's directory name)")
+	rootCmd.Flags().BoolVarP(&showVersion, "version", "", false, "Show the extension, git and gh versions")
+
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show the extension, git and gh versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return versionRun()
+		},
 	}
+	rootCmd.AddCommand(versionCmd)
 
 	prCmd := &cobra.Command{
 		Use:   "pr",
@@ -32,7 +89,7 @@ func main() {
 	}
 
 	checkoutCmd := &cobra.Command{
-		Use:   "checkout [<number> | <url> | <branch>]",
+		Use:   "checkout [<number> | <url> | <branch> | -]",
 		Short: "Check out a pull request in a new git worktree",
 		Example: `  # Interactively select a PR to check out
   $ gh worktree pr checkout
@@ -47,6 +104,27 @@ func main() {
   $ gh worktree pr checkout --create feature-auth
   $ gh worktree pr checkout -c feature-auth
 
+  # Interactively select from closed PRs
+  $ gh worktree pr checkout --state closed
+
+  # Interactively select from PRs assigned to you
+  $ gh worktree pr checkout --assignee @me
+
+  # Check out a specific commit of the PR in a detached worktree
+  $ gh worktree pr checkout 32 --at-commit 3f2a9c1
+
+  # Run a command in the new worktree after creation
+  $ gh worktree pr checkout 32 --after "make build"
+
+  # Build the PR in a throwaway worktree, exiting with the command's status
+  $ gh worktree pr checkout 32 --ephemeral --run "make dist"
+
+  # Read the PR from stdin (e.g. a line selected with fzf)
+  $ gh pr list | fzf | gh worktree pr checkout -
+
+  # Reuse an existing worktree and fast-forward it
+  $ gh worktree pr checkout 32 --reuse --update
+
   # Use as shell function to checkout and cd (add to ~/.bashrc or ~/.zshrc):
   $ ghwc() {
       local target=$(gh worktree pr checkout --shell "$@")
@@ -65,28 +143,345 @@ func main() {
 				cmd.SilenceErrors = true
 			}
 
-			// Handle --create flag for branch worktrees
-			if createBranch != "" {
-				return checkoutBranchWorktree(createBranch, &opts)
+			if opts.Update && !opts.Reuse {
+				return fmt.Errorf("--update requires --reuse")
+			}
+			if opts.Export && !opts.ShellMode {
+				return fmt.Errorf("--export requires --shell")
+			}
+			if opts.InteractiveMulti {
+				// Several worktrees leave no single path for a shell function to cd to
+				conflicts := []struct {
+					set  bool
+					flag string
+				}{
+					{len(args) > 0, "a PR argument"},
+					{opts.ShellMode, "--shell"},
+					{createBranch != "", "--create"},
+					{opts.Describe, "--describe"},
+					{opts.BranchName != "", "--branch"},
+					{opts.AtCommit != "", "--at-commit"},
+					{opts.RemoteBranch != "", "--remote-branch"},
+					{opts.Fork != "", "--fork"},
+					{opts.Ephemeral, "--ephemeral"},
+					{opts.OpenTerminal, "--open-terminal"},
+				}
+				for _, c := range conflicts {
+					if c.set {
+						return fmt.Errorf("--interactive-multi cannot be used with %s", c.flag)
+					}
+				}
+			}
+			if opts.NameFrom != "" {
+				if err := validate.NameFrom(opts.NameFrom); err != nil {
+					return err
+				}
+				if opts.NameFrom != worktree.NameFromNumber && (opts.Detach || opts.AtCommit != "") {
+					return fmt.Errorf("--name-from %s cannot be used with --detach or --at-commit", opts.NameFrom)
+				}
+			}
+			if opts.BranchExistsPolicy != "" {
+				if err := validate.BranchExistsPolicy(opts.BranchExistsPolicy); err != nil {
+					return err
+				}
+			}
+			if opts.IsolateGitDir {
+				conflicts := []struct {
+					set  bool
+					flag string
+				}{
+					{createBranch != "", "--create"},
+					{opts.Detach, "--detach"},
+					{opts.AtCommit != "", "--at-commit"},
+					{opts.NoFetch, "--no-fetch"},
+					{opts.Reuse, "--reuse"},
+					{opts.RemoteBranch != "", "--remote-branch"},
+					{opts.Fork != "", "--fork"},
+					{opts.FetchBase, "--checkout-base-too"},
+					{opts.PushRemote != "", "--push-remote"},
+					{len(opts.Sparse) > 0, "--sparse"},
+				}
+				for _, c := range conflicts {
+					if c.set {
+						return fmt.Errorf("--isolate-git-dir cannot be used with %s", c.flag)
+					}
+				}
+			}
+			if opts.RefreshExistingBranch {
+				conflicts := []struct {
+					set  bool
+					flag string
+				}{
+					{createBranch != "", "--create"},
+					{opts.Detach, "--detach"},
+					{opts.AtCommit != "", "--at-commit"},
+					{opts.IsolateGitDir, "--isolate-git-dir"},
+					{opts.Force, "--force"},
+					{opts.BranchExistsPolicy != "" && opts.BranchExistsPolicy != worktree.BranchExistsFF, "--branch-exists-policy " + opts.BranchExistsPolicy},
+				}
+				for _, c := range conflicts {
+					if c.set {
+						return fmt.Errorf("--refresh-existing-branch cannot be used with %s", c.flag)
+					}
+				}
+			}
+			if opts.LabelDir && opts.IntoCurrent {
+				return fmt.Errorf("--label-dir cannot be used with --into-current")
+			}
+			if opts.FetchBase && createBranch != "" {
+				return fmt.Errorf("--checkout-base-too cannot be used with --create")
+			}
+			if opts.NoFetch {
+				switch {
+				case createBranch != "":
+					return fmt.Errorf("--no-fetch cannot be used with --create")
+				case opts.AtCommit != "":
+					return fmt.Errorf("--no-fetch cannot be used with --at-commit")
+				case opts.ShallowSince != "":
+					return fmt.Errorf("--no-fetch cannot be used with --shallow-since")
+				case opts.FetchBase:
+					return fmt.Errorf("--no-fetch cannot be used with --checkout-base-too")
+				case opts.Update:
+					return fmt.Errorf("--no-fetch cannot be used with --update")
+				}
+			}
+			if len(opts.Sparse) > 0 {
+				if createBranch != "" {
+					return fmt.Errorf("--sparse cannot be used with --create")
+				}
+				for i, path := range opts.Sparse {
+					if err := validate.SparsePath(path); err != nil {
+						return err
+					}
+					// git expects forward slashes in sparse-checkout paths
+					opts.Sparse[i] = filepath.ToSlash(filepath.Clean(path))
+				}
+			}
+			if opts.Annotate && createBranch != "" {
+				return fmt.Errorf("--annotate cannot be used with --create")
+			}
+			if opts.Renormalize && createBranch != "" {
+				return fmt.Errorf("--renormalize cannot be used with --create")
+			}
+			if opts.StrictSubmodules && !opts.RecurseSubmodules {
+				return fmt.Errorf("--strict-submodules requires --recurse-submodules")
+			}
+			if opts.IgnoreSubmoduleErrors {
+				if opts.StrictSubmodules {
+					return fmt.Errorf("--ignore-submodule-errors cannot be used with --strict-submodules")
+				}
+				opts.RecurseSubmodules = true
+			}
+			if opts.Clean && !opts.Reuse {
+				return fmt.Errorf("--clean requires --reuse")
+			}
+			if opts.Tmux && opts.OpenTerminal {
+				return fmt.Errorf("--tmux cannot be used with --open-terminal")
+			}
+			if opts.Ephemeral != (opts.Run != "") {
+				return fmt.Errorf("--ephemeral and --run must be used together")
+			}
+			if opts.Ephemeral {
+				switch {
+				case createBranch != "":
+					return fmt.Errorf("--ephemeral cannot be used with --create")
+				case opts.Reuse:
+					return fmt.Errorf("--ephemeral cannot be used with --reuse (it would remove the existing worktree)")
+				case opts.ShellMode || opts.OpenTerminal || opts.Tmux:
+					return fmt.Errorf("--ephemeral cannot be used with --shell, --open-terminal or --tmux")
+				}
+				// The command's failure is reported through the exit code
+				cmd.SilenceUsage = true
+			}
+			if cmd.Flags().Changed("checks-timeout") && !opts.WaitForChecks {
+				return fmt.Errorf("--checks-timeout requires --wait-for-checks")
+			}
+			if opts.WaitForChecks {
+				if createBranch != "" {
+					return fmt.Errorf("--wait-for-checks cannot be used with --create")
+				}
+				if opts.ChecksTimeout <= 0 {
+					return fmt.Errorf("--checks-timeout must be positive")
+				}
+			}
+			if opts.MaxWorktrees < 0 {
+				return fmt.Errorf("--max-worktrees must not be negative")
+			}
+			if err := validate.PRState(opts.State); err != nil {
+				return err
+			}
+			if opts.AtCommit != "" {
+				if opts.ShallowSince != "" {
+					return fmt.Errorf("--at-commit cannot be used with --shallow-since")
+				}
+				if opts.BranchName != "" || opts.TitleBranch || createBranch != "" {
+					return fmt.Errorf("--at-commit creates a detached worktree and cannot be used with --branch, --title-branch or --create")
+				}
+				if err := validate.CommitSHA(opts.AtCommit); err != nil {
+					return fmt.Errorf("invalid --at-commit: %w", err)
+				}
+			}
+			if opts.RemoteBranch != "" {
+				if createBranch != "" || opts.AtCommit != "" {
+					return fmt.Errorf("--remote-branch cannot be used with --create or --at-commit")
+				}
+				if err := validate.BranchName(opts.RemoteBranch); err != nil {
+					return fmt.Errorf("invalid --remote-branch: %w", err)
+				}
+			}
+			if opts.Fork != "" {
+				if createBranch != "" || opts.AtCommit != "" {
+					return fmt.Errorf("--fork cannot be used with --create or --at-commit")
+				}
+				if err := validate.RepoName(opts.Fork); err != nil {
+					return fmt.Errorf("invalid --fork: %w", err)
+				}
+			}
+			if opts.SetupProfile != "" {
+				if opts.NoSetup {
+					return fmt.Errorf("--setup-profile cannot be used with --no-setup")
+				}
+				// Fail before creating anything if the profile doesn't exist
+				if err := checkSetupProfile(opts.SetupProfile); err != nil {
+					return err
+				}
+			}
+			if opts.ConfigFrom != "" {
+				// Fail before creating anything if the source worktree doesn't exist
+				source, err := resolveConfigSource(opts.ConfigFrom)
+				if err != nil {
+					return err
+				}
+				opts.ConfigSource = source
+			}
+			if opts.RebaseOnto != "" {
+				if createBranch != "" || opts.AtCommit != "" {
+					return fmt.Errorf("--rebase-onto cannot be used with --create or --at-commit")
+				}
+				if opts.WaitForChecks {
+					return fmt.Errorf("--rebase-onto cannot be used with --wait-for-checks (the rebased commits have no checks)")
+				}
+				if err := validate.BranchName(opts.RebaseOnto); err != nil {
+					return fmt.Errorf("invalid --rebase-onto: %w", err)
+				}
+			}
+			if opts.TitleBranch && opts.BranchName != "" {
+				return fmt.Errorf("--title-branch cannot be used with --branch")
+			}
+			if opts.BranchNaming != "" {
+				if err := validate.BranchNaming(opts.BranchNaming); err != nil {
+					return err
+				}
+				if opts.BranchName != "" || opts.TitleBranch || createBranch != "" {
+					return fmt.Errorf("--pr-branch-naming cannot be used with --branch, --title-branch or --create")
+				}
+				if opts.Detach || opts.AtCommit != "" {
+					return fmt.Errorf("--pr-branch-naming cannot be used with --detach or --at-commit")
+				}
+			}
+			if opts.ShallowSince != "" {
+				if err := validate.Date(opts.ShallowSince); err != nil {
+					return fmt.Errorf("invalid --shallow-since: %w", err)
+				}
+			}
+			tags, _ := cmd.Flags().GetBool("tags")
+			noTags, _ := cmd.Flags().GetBool("no-tags")
+			switch {
+			case tags && noTags:
+				return fmt.Errorf("--tags and --no-tags cannot be used together")
+			case tags || noTags:
+				opts.Tags = &tags
 			}
 
-			if len(args) > 0 {
-				return checkoutRun(&opts, args[0])
+			var err error
+			if createBranch != "" {
+				// Handle --create flag for branch worktrees
+				err = checkoutBranchWorktree(cmd.Context(), createBranch, &opts)
+			} else if len(args) > 0 && args[0] == "-" {
+				// Read the selector from stdin, e.g. a line picked with a fuzzy finder
+				var selector string
+				selector, err = readSelectorFromStdin()
+				if err == nil {
+					err = checkoutRun(cmd.Context(), &opts, selector)
+				}
+			} else if len(args) > 0 {
+				err = checkoutRun(cmd.Context(), &opts, args[0])
+			} else {
+				err = checkoutRunInteractive(cmd.Context(), &opts)
 			}
-			return checkoutRunInteractive(&opts)
+			if err != nil {
+				notifyCheckout(&opts, "", nil, err)
+			}
+			return err
 		},
 	}
 
 	checkoutCmd.Flags().BoolVarP(&opts.RecurseSubmodules, "recurse-submodules", "", false, "Update all submodules after checkout")
+	checkoutCmd.Flags().BoolVarP(&opts.StrictSubmodules, "strict-submodules", "", false, "Fail the checkout if updating submodules fails (requires --recurse-submodules)")
+	checkoutCmd.Flags().MarkDeprecated("strict-submodules", "submodule failures fail the checkout by default; use --ignore-submodule-errors to only warn")
+	checkoutCmd.Flags().BoolVarP(&opts.IgnoreSubmoduleErrors, "ignore-submodule-errors", "", false, "Update submodules like --recurse-submodules, but only warn if that fails")
 	checkoutCmd.Flags().BoolVarP(&opts.Force, "force", "f", false, "Reset the existing local branch to the latest state of the pull request")
+	checkoutCmd.Flags().StringVarP(&opts.BranchExistsPolicy, "branch-exists-policy", "", "", "What to do if the local branch already exists: {ff|reset|rename|error} (default: reset with --force, otherwise ff)")
+	checkoutCmd.Flags().BoolVarP(&opts.RefreshExistingBranch, "refresh-existing-branch", "", false, "Fast-forward an existing local branch to the PR before adding the worktree, so it isn't checked out stale")
 	checkoutCmd.Flags().BoolVarP(&opts.Detach, "detach", "", false, "Checkout PR with a detached HEAD")
 	checkoutCmd.Flags().StringVarP(&opts.BranchName, "branch", "b", "", "Local branch name to use (default [the name of the head branch])")
+	checkoutCmd.Flags().StringVarP(&opts.RebaseOnto, "rebase-onto", "", "", "Rebase the PR branch onto a ref (e.g. upstream/main) after creating the worktree")
+	checkoutCmd.Flags().BoolVarP(&opts.TitleBranch, "title-branch", "", false, "Name the local branch after the PR title instead of the head branch")
+	checkoutCmd.Flags().StringVarP(&opts.BranchNaming, "pr-branch-naming", "", "", "Name the local branch after the PR's {head|title|number}; number uses pr-<N> (default: worktree.pr_branch_naming, or head)")
 	checkoutCmd.Flags().BoolP("shell", "s", false, "Output path only for use in shell functions")
+	checkoutCmd.Flags().BoolVarP(&opts.Export, "export", "", false, "With --shell, print export statements for the worktree's path, branch and PR number instead of the path")
+	checkoutCmd.Flags().BoolVarP(&opts.SwitchExisting, "switch-existing", "", false, "Switch to a worktree that already has the PR or branch checked out instead of prompting")
+	checkoutCmd.Flags().IntVarP(&opts.MaxWorktrees, "max-worktrees", "", 0, "Refuse to create a worktree when this many PR and branch worktrees exist, unless --force is given (default: worktree.max, or no limit)")
+	checkoutCmd.Flags().StringVarP(&opts.SummaryFile, "summary-file", "", "", "Append a JSON record of the created worktree (timestamp, PR number, branch, path, commit) to a file")
+	checkoutCmd.Flags().BoolVarP(&opts.Annotate, "annotate", "", false, "Write an untracked "+worktree.AnnotationFile+" with the PR's number, title and URL into the worktree")
+	checkoutCmd.Flags().BoolVarP(&opts.LinkNodeModules, "link-node-modules", "", false, "Symlink node_modules of the main worktree into the new worktree instead of installing dependencies again")
+	checkoutCmd.Flags().BoolVarP(&opts.InteractiveMulti, "interactive-multi", "", false, "Select several pull requests interactively and create a worktree for each")
+	checkoutCmd.Flags().BoolVarP(&opts.NoMetadata, "no-metadata", "", false, "Don't record gh-worktree-* keys in the git config of the worktree's branch; list and switch then recognize it by directory name only")
 	checkoutCmd.Flags().StringP("create", "c", "", "Create a new branch worktree for local development")
+	checkoutCmd.Flags().BoolVarP(&opts.DirHash, "dir-hash", "", false, "Shorten long directory names derived from a branch or PR title to a prefix and a hash of the full name")
+	checkoutCmd.Flags().StringVarP(&opts.NameFrom, "name-from", "", "", "Name the PR worktree directory after the PR {number|head|title} or the local branch {branch} (default: worktree.name_from, or number)")
+	checkoutCmd.Flags().BoolVarP(&opts.LabelDir, "label-dir", "", false, "Create the PR worktree in a subdirectory named after the PR's first label (default: worktree.label_dir)")
+	checkoutCmd.Flags().BoolVarP(&opts.IntoCurrent, "into-current", "", false, "Create the worktree next to the current directory instead of next to the main worktree")
 	checkoutCmd.Flags().BoolVarP(&opts.NoSetup, "no-setup", "", false, "Skip post-creation setup commands")
+	checkoutCmd.Flags().StringVarP(&opts.SetupProfile, "setup-profile", "", "", "Run the named setup profile instead of the default setup commands")
+	checkoutCmd.Flags().BoolVarP(&opts.MirrorConfig, "mirror-config", "", false, "Copy the worktree-specific git config keys listed in mirror_config.keys from the main worktree")
+	checkoutCmd.Flags().StringVarP(&opts.ConfigFrom, "config-from", "", "", "Copy the git config keys listed in mirror_config.keys from the worktree of a PR number or branch")
+	checkoutCmd.Flags().BoolVarP(&opts.Reuse, "reuse", "", false, "Reuse an existing worktree instead of failing")
+	checkoutCmd.Flags().BoolVarP(&opts.Update, "update", "", false, "Fetch and fast-forward a reused worktree (requires --reuse)")
+	checkoutCmd.Flags().BoolVarP(&opts.Clean, "clean", "", false, "Remove untracked files from a reused worktree (requires --reuse; confirms unless --force)")
+	checkoutCmd.Flags().StringVarP(&opts.State, "state", "", "open", "Filter interactive selection by state: {open|closed|all}")
+	checkoutCmd.Flags().StringVarP(&opts.Author, "author", "A", "", "Filter interactive selection by author (\"@me\" for yourself)")
+	checkoutCmd.Flags().StringVarP(&opts.Assignee, "assignee", "", "", "Filter interactive selection by assignee (\"@me\" for yourself)")
+	checkoutCmd.Flags().BoolVarP(&opts.Requested, "requested", "", false, "Filter interactive selection to PRs requesting your review")
+	checkoutCmd.Flags().StringVarP(&opts.ShallowSince, "shallow-since", "", "", "Only fetch history after the given date (YYYY-MM-DD)")
+	checkoutCmd.Flags().BoolP("tags", "", false, "Fetch the tags pointing into the PR's history (default: fetch.tags, or --no-tags)")
+	checkoutCmd.Flags().BoolP("no-tags", "", false, "Don't fetch tags, overriding fetch.tags")
+	checkoutCmd.Flags().StringVarP(&opts.PushRemote, "push-remote", "", "", "Remote name or GitHub URL to push the branch to (overrides automatic pushRemote)")
+	checkoutCmd.Flags().StringVarP(&opts.AtCommit, "at-commit", "", "", "Create a detached worktree at a specific commit of the PR")
+	checkoutCmd.Flags().StringVarP(&opts.After, "after", "", "", "Command to run in the new worktree after creation (output goes to stderr)")
+	checkoutCmd.Flags().BoolVarP(&opts.Describe, "describe", "", false, "Print the PR description to stderr before creating the worktree (interactive mode asks to confirm)")
+	checkoutCmd.Flags().BoolVarP(&opts.OpenTerminal, "open-terminal", "", false, "Start $SHELL in the new worktree (ignored with --shell)")
+	checkoutCmd.Flags().BoolVarP(&opts.Tmux, "tmux", "", false, "Open the new worktree in a tmux window named after its directory, or switch to that window if it exists")
+	checkoutCmd.Flags().BoolVarP(&opts.Ephemeral, "ephemeral", "", false, "Remove the worktree and its branch after --run finishes, whether or not it succeeds")
+	checkoutCmd.Flags().StringVarP(&opts.Run, "run", "", "", "Command to run in an --ephemeral worktree; gh worktree exits with its status")
+	checkoutCmd.Flags().StringVarP(&opts.RemoteBranch, "remote-branch", "", "", "Check out this branch of the PR's head repository instead of the PR's head ref")
+	checkoutCmd.Flags().StringVarP(&opts.Fork, "fork", "", "", "Fetch the PR's head branch from the fork of this owner instead of the PR's head repository")
+	checkoutCmd.Flags().BoolVarP(&opts.Renormalize, "renormalize", "", false, "Rewrite the files after setup so that .gitattributes filters configured by then (e.g. LFS) are applied, without staging anything")
+	checkoutCmd.Flags().BoolVarP(&opts.NoFetch, "no-fetch", "", false, "Don't fetch the PR; check out refs fetched by a previous step")
+	checkoutCmd.Flags().BoolVarP(&opts.FetchBase, "checkout-base-too", "", false, "Also fetch the PR's base branch into its remote-tracking ref (e.g. upstream/main)")
+	checkoutCmd.Flags().StringSliceVarP(&opts.Sparse, "sparse", "", nil, "Only check out the given directories with sparse-checkout (comma-separated or repeated; requires git 2.25+)")
+	checkoutCmd.Flags().BoolVarP(&opts.IsolateGitDir, "isolate-git-dir", "", false, "Create a standalone clone with its own .git instead of a linked worktree (slower, uses more disk)")
+	checkoutCmd.Flags().BoolVarP(&opts.CheckDiskSpace, "check-disk-space", "", false, "Fail early if there isn't enough free disk space for the worktree")
+	checkoutCmd.Flags().BoolVarP(&opts.NoVerify, "no-verify", "", false, "Skip git hooks while fetching and creating the worktree")
+	checkoutCmd.Flags().BoolVarP(&opts.WaitForChecks, "wait-for-checks", "", false, "Wait until the PR's checks complete after creating the worktree")
+	checkoutCmd.Flags().DurationVarP(&opts.ChecksTimeout, "checks-timeout", "", 30*time.Minute, "How long to wait for checks with --wait-for-checks")
+	checkoutCmd.Flags().BoolVarP(&opts.Notify, "notify", "", false, "Show a desktop notification when checkout and setup finish")
 
 	var removeOpts struct {
-		Force bool
+		Force      bool
+		All        bool
+		Branch     bool
+		Everything bool
 	}
 
 	removeCmd := &cobra.Command{
@@ -102,21 +497,36 @@ func main() {
   $ gh worktree pr remove https://github.com/OWNER/REPO/pull/32
 
   # Force remove without confirmation
-  $ gh worktree pr remove 32 --force`,
+  $ gh worktree pr remove 32 --force
+
+  # Remove every PR worktree
+  $ gh worktree pr remove --all
+
+  # Remove every PR and branch worktree without confirmation
+  $ gh worktree pr remove --everything --force`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if removeOpts.All || removeOpts.Branch || removeOpts.Everything {
+				if len(args) > 0 {
+					return fmt.Errorf("cannot specify a worktree together with --all, --branch, or --everything")
+				}
+				includePR := removeOpts.All || removeOpts.Everything
+				includeBranch := removeOpts.Branch || removeOpts.Everything
+				return removeAllRun(cmd.Context(), includePR, includeBranch, removeOpts.Force)
+			}
 			if len(args) > 0 {
-				return removeRun(args[0], removeOpts.Force)
+				return removeRun(cmd.Context(), args[0], removeOpts.Force)
 			}
-			return removeRunInteractive(removeOpts.Force)
+			return removeRunInteractive(cmd.Context(), removeOpts.Force)
 		},
 	}
 
 	removeCmd.Flags().BoolVarP(&removeOpts.Force, "force", "f", false, "Force removal without confirmation")
+	removeCmd.Flags().BoolVarP(&removeOpts.All, "all", "a", false, "Remove all PR worktrees")
+	removeCmd.Flags().BoolVarP(&removeOpts.Branch, "branch", "", false, "Remove all branch worktrees")
+	removeCmd.Flags().BoolVarP(&removeOpts.Everything, "everything", "", false, "Remove all PR and branch worktrees")
 
-	var listOpts struct {
-		All bool
-	}
+	var listOpts listOptions
 
 	listCmd := &cobra.Command{
 		Use:   "list",
@@ -125,17 +535,63 @@ func main() {
   $ gh worktree pr list
 
   # List all worktrees (PR and branch)
-  $ gh worktree pr list --all`,
+  $ gh worktree pr list --all
+
+  # Show full commit SHAs
+  $ gh worktree pr list --abbrev 0
+
+  # Output worktrees as JSON
+  $ gh worktree pr list --json
+
+  # Group worktrees by branch prefix (e.g. alice/...)
+  $ gh worktree pr list --all --tree
+
+  # Redraw the list every 5 seconds until Ctrl-C
+  $ gh worktree pr list --all --watch --interval 5s
+
+  # Find worktrees without commits in the last 30 days, oldest first
+  $ gh worktree pr list --all --stale 720h --sort last-commit`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return listRun(listOpts.All)
+			if listOpts.Tree && listOpts.JSON {
+				return fmt.Errorf("--tree cannot be used with --json")
+			}
+			if listOpts.Watch {
+				if listOpts.JSON {
+					return fmt.Errorf("--watch cannot be used with --json")
+				}
+				if listOpts.Interval <= 0 {
+					return fmt.Errorf("--interval must be positive")
+				}
+			}
+			if cmd.Flags().Changed("stale") && listOpts.Stale <= 0 {
+				return fmt.Errorf("--stale must be positive")
+			}
+			if listOpts.Sort != "" && listOpts.Sort != worktree.SortLastCommit {
+				return fmt.Errorf("invalid --sort %q (must be %s)", listOpts.Sort, worktree.SortLastCommit)
+			}
+			if listOpts.Sort != "" && listOpts.Tree {
+				return fmt.Errorf("--sort cannot be used with --tree")
+			}
+			if listOpts.Watch {
+				return watchListRun(&listOpts)
+			}
+			return listRun(&listOpts)
 		},
 	}
 
 	listCmd.Flags().BoolVarP(&listOpts.All, "all", "a", false, "List all worktrees (PR and branch)")
+	listCmd.Flags().IntVarP(&listOpts.Abbrev, "abbrev", "", 8, "Number of commit SHA characters to show (0 for full SHA)")
+	listCmd.Flags().BoolVarP(&listOpts.JSON, "json", "", false, "Output worktrees as JSON")
+	listCmd.Flags().BoolVarP(&listOpts.Tree, "tree", "", false, "Group worktrees by branch prefix")
+	listCmd.Flags().BoolVarP(&listOpts.Watch, "watch", "w", false, "Clear the screen and redraw the list periodically until interrupted")
+	listCmd.Flags().DurationVarP(&listOpts.Interval, "interval", "", 2*time.Second, "How often to redraw the list with --watch")
+	listCmd.Flags().DurationVarP(&listOpts.Stale, "stale", "", 0, "Only show worktrees whose last commit is older than this (e.g. 720h)")
+	listCmd.Flags().StringVarP(&listOpts.Sort, "sort", "", "", "Sort worktrees by: {last-commit} (default: PR number and branch name)")
+	listCmd.Flags().BoolVarP(&listOpts.NoColor, "no-color", "", false, "Disable colored output (default: color only when stdout is a terminal and NO_COLOR is unset)")
 
 	switchCmd := &cobra.Command{
-		Use:   "switch [<number> | main]",
+		Use:   "switch [<number> | <branch> | main]",
 		Short: "Switch to an existing pull request worktree or main worktree",
 		Example: `  # Interactively select a worktree to switch to
   $ gh worktree pr switch
@@ -143,9 +599,15 @@ func main() {
   # Switch to specific PR worktree
   $ gh worktree pr switch 9060
   
+  # Switch to PR worktree by branch name
+  $ gh worktree pr switch feature-branch
+  
   # Switch to main worktree
   $ gh worktree pr switch main
   
+  # Check out the PR first if its worktree doesn't exist
+  $ gh worktree pr switch 9060 --create-if-missing
+  
   # Use as shell function (add to ~/.bashrc or ~/.zshrc):
   $ ghws() { 
       local target=$(gh worktree pr switch --shell "$@")
@@ -162,15 +624,21 @@ func main() {
 				cmd.SilenceUsage = true
 				cmd.SilenceErrors = true
 			}
-			prNumber := ""
+			createIfMissing, _ := cmd.Flags().GetBool("create-if-missing")
+			identifier := ""
 			if len(args) > 0 {
-				prNumber = args[0]
+				identifier = args[0]
 			}
-			return switchRun(shellModeFlag, prNumber)
+			absolute, _ := cmd.Flags().GetBool("absolute")
+			return switchRun(cmd.Context(), shellModeFlag, createIfMissing, absolute, identifier)
 		},
 	}
 
 	switchCmd.Flags().BoolP("shell", "s", false, "Output path only for use in shell functions")
+	switchCmd.Flags().BoolP("create-if-missing", "", false, "Check out the PR in a new worktree if it doesn't exist yet")
+	switchCmd.Flags().BoolP("absolute", "", false, "Output an absolute path instead of one relative to the current directory")
+
+	var promoteOpts promoteOptions
 
 	promoteCmd := &cobra.Command{
 		Use:   "promote [<branch>] [<pr-number>]",
@@ -182,12 +650,27 @@ func main() {
   $ gh worktree pr promote feature-auth
 
   # Promote with explicit PR number
-  $ gh worktree pr promote feature-auth 1234`,
+  $ gh worktree pr promote feature-auth 1234
+
+  # Create a PR for the branch if none exists, then promote
+  $ gh worktree pr promote feature-auth --create-pr --title "Add auth"
+
+  # Revert a PR worktree to a branch worktree (e.g. after the PR was closed)
+  $ gh worktree pr promote feature-auth --unpromote`,
 		Args: cobra.RangeArgs(0, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var branchName string
 			prNumber := 0
 
+			if promoteOpts.Unpromote {
+				if len(args) > 1 {
+					return fmt.Errorf("--unpromote takes no PR number")
+				}
+				if promoteOpts.CreatePR {
+					return fmt.Errorf("--unpromote cannot be used with --create-pr")
+				}
+			}
+
 			if len(args) == 0 {
 				// Get current branch name
 				currentBranch := git.GetBranchName(".")
@@ -206,7 +689,40 @@ func main() {
 					}
 				}
 			}
-			return promoteRun(branchName, prNumber)
+			if !promoteOpts.CreatePR && (promoteOpts.Title != "" || promoteOpts.Body != "" || promoteOpts.Base != "") {
+				return fmt.Errorf("--title, --body, and --base require --create-pr")
+			}
+			if promoteOpts.Unpromote {
+				return unpromoteRun(branchName)
+			}
+			return promoteRun(branchName, prNumber, &promoteOpts)
+		},
+	}
+
+	promoteCmd.Flags().BoolVarP(&promoteOpts.CreatePR, "create-pr", "", false, "Create a pull request for the branch if none exists")
+	promoteCmd.Flags().StringVarP(&promoteOpts.Title, "title", "t", "", "Title for the created pull request")
+	promoteCmd.Flags().StringVarP(&promoteOpts.Body, "body", "", "", "Body for the created pull request")
+	promoteCmd.Flags().StringVarP(&promoteOpts.Base, "base", "B", "", "Base branch for the created pull request (default [the repository's default branch])")
+	promoteCmd.Flags().BoolVarP(&promoteOpts.Unpromote, "unpromote", "", false, "Revert a PR worktree to a branch worktree, removing its PR metadata")
+
+	renameBranchCmd := &cobra.Command{
+		Use:   "rename-branch [<number> | <branch>] <new-name>",
+		Short: "Rename the local branch of an existing worktree",
+		Example: `  # Rename the branch of the worktree for PR 1234
+  $ gh worktree pr rename-branch 1234 fix-login-timeout
+
+  # Rename the branch of the current worktree
+  $ gh worktree pr rename-branch pr-1234`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				currentBranch := git.GetBranchName(".")
+				if currentBranch == "" || currentBranch == "HEAD" {
+					return fmt.Errorf("could not determine current branch. Please specify the worktree")
+				}
+				return renameBranchRun(currentBranch, args[0])
+			}
+			return renameBranchRun(args[0], args[1])
 		},
 	}
 
@@ -215,6 +731,7 @@ func main() {
 	prCmd.AddCommand(listCmd)
 	prCmd.AddCommand(switchCmd)
 	prCmd.AddCommand(promoteCmd)
+	prCmd.AddCommand(renameBranchCmd)
 	rootCmd.AddCommand(prCmd)
 
 	// Root-level switch command (unified switcher)
@@ -233,6 +750,9 @@ func main() {
   # Switch to main worktree
   $ gh worktree switch main
 
+  # Pick from recently switched-to worktrees, most recent first
+  $ gh worktree switch --recent
+
   # Use as shell function (add to ~/.bashrc or ~/.zshrc):
   $ ghws() {
       local target=$(gh worktree switch --shell "$@")
@@ -250,13 +770,41 @@ func main() {
 			if len(args) > 0 {
 				identifier = args[0]
 			}
-			return switchAllRun(shellModeFlag, identifier)
+			absolute, _ := cmd.Flags().GetBool("absolute")
+			recent, _ := cmd.Flags().GetBool("recent")
+			if recent && identifier != "" {
+				return fmt.Errorf("--recent cannot be used with a worktree argument")
+			}
+			return switchAllRun(shellModeFlag, absolute, recent, identifier)
 		},
 	}
 	rootSwitchCmd.Flags().BoolP("shell", "s", false, "Output path only for use in shell functions")
+	rootSwitchCmd.Flags().BoolP("absolute", "", false, "Output an absolute path instead of one relative to the current directory")
+	rootSwitchCmd.Flags().BoolP("recent", "", false, "Interactively select from recently switched-to worktrees, most recent first")
 	rootCmd.AddCommand(rootSwitchCmd)
 
-	if err := rootCmd.Execute(); err != nil {
+	var whereamiJSON bool
+	whereamiCmd := &cobra.Command{
+		Use:   "whereami",
+		Short: "Show the worktree containing the current directory",
+		Example: `  # Show the current worktree and its PR or branch metadata
+  $ gh worktree whereami
+
+  # Print the PR number, e.g. for a shell prompt
+  $ gh worktree whereami --json | jq -r '.prNumber // empty'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return whereamiRun(whereamiJSON)
+		},
+	}
+	whereamiCmd.Flags().BoolVarP(&whereamiJSON, "json", "", false, "Output as JSON")
+	rootCmd.AddCommand(whereamiCmd)
+
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		if !shellMode {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
@@ -264,162 +812,557 @@ func main() {
 	}
 }
 
-func checkoutRunInteractive(opts *worktree.CheckoutOptions) error {
-	// Get current repository
-	repo, err := repository.Current()
+// repoNameOf returns the repository name used in worktree names for the main
+// worktree at gitRoot
+func repoNameOf(gitRoot string) (string, error) {
+	repoName, err := worktree.ResolveRepoName(gitRoot, assumeRepoName)
 	if err != nil {
-		return fmt.Errorf("failed to get current repository: %w", err)
+		return "", fmt.Errorf("invalid repository name: %w", err)
 	}
+	return repoName, nil
+}
 
-	// Get PRs from API
-	client, err := api.DefaultRESTClient()
+// prWorktreePath returns where to create the worktree for a PR:
+// next to the main worktree, or next to the current directory with --into-current.
+// The directory is named according to --name-from or worktree.name_from, and
+// grouped by label with --label-dir or worktree.label_dir.
+func prWorktreePath(repoName string, pr *github.PullRequest, opts *worktree.CheckoutOptions) (string, error) {
+	nameFrom, err := worktree.ResolveNameFrom(opts.NameFrom)
 	if err != nil {
-		return fmt.Errorf("failed to create REST client: %w", err)
+		return "", err
+	}
+	// Detached worktrees have no branch to record metadata on, so only
+	// their repo-pr{N} name identifies them
+	if opts.Detach || opts.AtCommit != "" {
+		nameFrom = worktree.NameFromNumber
+	}
+	if nameFrom == worktree.NameFromBranch {
+		branchName, err := worktree.LocalBranchName(pr, opts)
+		if err != nil {
+			return "", err
+		}
+		named := *pr
+		named.Head.Ref = branchName
+		pr = &named
+	}
+	if opts.DirHash && nameFrom != worktree.NameFromNumber {
+		name, err := worktree.PRDirName(pr, nameFrom)
+		if err != nil {
+			return "", err
+		}
+		named := *pr
+		named.Head.Ref = worktree.ShortDirName(name)
+		pr = &named
+		nameFrom = worktree.NameFromHead
 	}
 
-	var prs []github.PullRequest
-	err = client.Get(fmt.Sprintf("repos/%s/%s/pulls?state=open&per_page=100", repo.Owner, repo.Name), &prs)
+	if !opts.IntoCurrent {
+		warnNestedDirNotIgnored()
+		labelDir, err := worktree.ResolveLabelDir(opts.LabelDir)
+		if err != nil {
+			return "", err
+		}
+		if labelDir {
+			return worktree.GeneratePathForPRByLabel(repoName, pr, nameFrom)
+		}
+		return worktree.GeneratePathForPR(repoName, pr, nameFrom)
+	}
+	baseDir, err := worktree.CurrentBaseDir()
 	if err != nil {
-		return fmt.Errorf("failed to get PRs: %w", err)
+		return "", err
 	}
+	return worktree.GeneratePathForPRIn(baseDir, repoName, pr, nameFrom)
+}
 
-	// Create candidates list
-	candidates := []string{}
-	for _, pr := range prs {
-		candidates = append(candidates, github.FormatPRCandidate(&pr))
+// branchWorktreePath is like prWorktreePath for branch worktrees
+func branchWorktreePath(repoName, branchName string, opts *worktree.CheckoutOptions) (string, error) {
+	if opts.DirHash {
+		branchName = worktree.ShortDirName(branchName)
+	}
+	if !opts.IntoCurrent {
+		warnNestedDirNotIgnored()
+		return worktree.GeneratePathForBranch(repoName, branchName)
+	}
+	baseDir, err := worktree.CurrentBaseDir()
+	if err != nil {
+		return "", err
 	}
+	return worktree.GeneratePathForBranchIn(baseDir, repoName, branchName)
+}
 
-	// Add "Create a new branch" option at the end
-	createNewBranchOption := "Create a new branch\t(local development)"
-	candidates = append(candidates, createNewBranchOption)
+// warnNestedDirNotIgnored warns if worktrees are created inside the main worktree
+// (worktree.location: nested) but their directory isn't gitignored, in which case
+// they show up as untracked files in the main worktree
+func warnNestedDirNotIgnored() {
+	nested, err := worktree.NestedDir()
+	if err != nil || nested == "" {
+		// Configuration errors are reported when generating the path
+		return
+	}
+	if !git.IsIgnored(filepath.Dir(nested), worktree.NestedDirName+"/") {
+		fmt.Fprintf(os.Stderr, "Warning: %s is not ignored by git; add \"/%s/\" to .gitignore\n", nested, worktree.NestedDirName)
+	}
+}
 
-	// Use gh CLI's built-in selection
-	selection, err := promptSelect("Select a pull request to check out", candidates)
+// resolveBranchNaming applies worktree.pr_branch_naming unless --pr-branch-naming is given
+func resolveBranchNaming(opts *worktree.CheckoutOptions) error {
+	naming, err := worktree.ResolveBranchNaming(opts.BranchNaming)
 	if err != nil {
-		if opts.ShellMode {
-			// In shell mode, if prompting fails, just return empty to avoid cd errors
-			return nil
-		}
 		return err
 	}
+	opts.BranchNaming = naming
+	return nil
+}
 
-	if selection == -1 {
-		if !opts.ShellMode {
-			fmt.Println("Cancelled.")
-		}
-		// In shell mode, output nothing when cancelled so cd doesn't change directory
+// checkWorktreeLimit refuses to create another worktree when the number of PR
+// and branch worktrees has reached --max-worktrees or worktree.max, unless
+// --force is given
+func checkWorktreeLimit(gitRoot, repoName string, opts *worktree.CheckoutOptions) error {
+	if opts.Force {
 		return nil
 	}
-
-	// Check if "Create a new branch" was selected
-	if selection == len(candidates)-1 {
-		// Prompt for branch name
-		p := prompter.New(os.Stdin, os.Stderr, os.Stderr)
-		branchName, err := p.Input("Enter branch name:", "")
+	limit, source := opts.MaxWorktrees, "--max-worktrees"
+	if limit == 0 {
+		config, err := setup.LoadConfig(gitRoot)
 		if err != nil {
-			if opts.ShellMode {
-				return nil
-			}
-			return err
+			return fmt.Errorf("failed to load config: %w", err)
 		}
-
-		// Trim whitespace
-		branchName = strings.TrimSpace(branchName)
-		if branchName == "" {
-			if !opts.ShellMode {
-				fmt.Println("Cancelled.")
-			}
-			return nil
+		limit, source = config.Worktree.Max, "worktree.max"
+		if limit < 0 {
+			return fmt.Errorf("invalid worktree.max %d (must not be negative)", limit)
 		}
-
-		// Create branch worktree
-		return checkoutBranchWorktree(branchName, opts)
+	}
+	if limit == 0 {
+		return nil
 	}
 
-	selectedPR := prs[selection]
-	
-	// Fetch full PR details to get maintainer_can_modify and other fields
-	var fullPR github.PullRequest
-	err = client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Name, selectedPR.Number), &fullPR)
+	prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
 	if err != nil {
-		return fmt.Errorf("failed to get full PR details: %w", err)
+		return fmt.Errorf("failed to list worktrees: %w", err)
 	}
+	if count := len(prWorktrees) + len(branchWorktrees); count >= limit {
+		return fmt.Errorf("%d worktrees already exist, reaching the limit of %d set by %s; remove some with `gh worktree pr remove` or use --force to create one anyway", count, limit, source)
+	}
+	return nil
+}
 
-	// Generate worktree path
+// resolveConfigSource finds the worktree to copy git config from for --config-from
+func resolveConfigSource(identifier string) (*worktree.Info, error) {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
-		return fmt.Errorf("failed to get git root: %w", err)
+		return nil, fmt.Errorf("failed to get git root: %w", err)
+	}
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return nil, err
 	}
 
-	repoName := filepath.Base(gitRoot)
-	if err := validate.RepoName(repoName); err != nil {
-		return fmt.Errorf("invalid repository name: %w", err)
+	prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
-	if err := validate.PRNumber(fullPR.Number); err != nil {
-		return fmt.Errorf("invalid PR number: %w", err)
+	source, err := worktree.FindByIdentifier(prWorktrees, branchWorktrees, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --config-from: %w", err)
 	}
+	if source == nil {
+		return nil, fmt.Errorf("invalid --config-from: no worktree found for %s", identifier)
+	}
+	return source, nil
+}
 
-	worktreePath, err := worktree.GeneratePath(repoName, fullPR.Number)
+// checkSetupProfile verifies that the named setup profile is configured
+func checkSetupProfile(profile string) error {
+	mainWorktree, err := git.GetMainWorktree()
 	if err != nil {
-		return fmt.Errorf("failed to generate worktree path: %w", err)
+		return fmt.Errorf("failed to get main worktree: %w", err)
 	}
 
-	// Check if worktree already exists
-	if _, err := os.Stat(worktreePath); err == nil {
-		if opts.ShellMode {
-			// In shell mode, output the existing path so cd still works
-			cwd, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get current directory: %w", err)
-			}
-			relPath, err := filepath.Rel(cwd, worktreePath)
-			if err != nil {
-				relPath = worktreePath
-			}
-			fmt.Print(relPath)
-			return nil
-		}
-		return fmt.Errorf("worktree for PR #%d already exists at %s", fullPR.Number, worktreePath)
+	config, err := setup.LoadConfig(mainWorktree)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
 	}
+	_, err = config.Setup.Commands(profile)
+	return err
+}
 
-	// Create worktree
-	creator, err := worktree.NewCreator(repo)
-	if err != nil {
-		return fmt.Errorf("failed to create worktree creator: %w", err)
+// extensionVersion returns the version set with ldflags, falling back to
+// the version recorded in the build info
+func extensionVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
 	}
+	return "dev"
+}
 
-	err = creator.Create(worktreePath, &fullPR, opts)
-	if err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+// versionRun prints the versions to include in bug reports
+func versionRun() error {
+	fmt.Printf("gh-worktree version %s\n", extensionVersion())
+
+	if gitVersion, err := git.Version(); err == nil {
+		fmt.Println(gitVersion)
+	} else {
+		fmt.Println("git version unknown")
 	}
 
-	// Output based on mode
-	if opts.ShellMode {
-		// Shell mode: output only the path for use in shell functions
-		// Get current working directory for relative path calculation
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
-		
-		// Convert absolute path to relative path
-		relPath, err := filepath.Rel(cwd, worktreePath)
+	// Only the first line; the rest is the release URL
+	if stdout, _, err := gh.Exec("--version"); err == nil {
+		fmt.Println(strings.SplitN(strings.TrimSpace(stdout.String()), "\n", 2)[0])
+	} else {
+		fmt.Println("gh version unknown")
+	}
+
+	return nil
+}
+
+// createNewBranchCandidate is the last candidate of the interactive checkout
+const createNewBranchCandidate = "＋ Create a new branch\t(local development)"
+
+// checkoutMultiple creates a worktree for each of the PRs selected from
+// candidates for --interactive-multi. PRs that already have a worktree are
+// skipped and failures don't stop the others; a summary is printed at the end.
+func checkoutMultiple(ctx context.Context, prs []github.PullRequest, candidates []string, opts *worktree.CheckoutOptions) error {
+	selections, err := promptMultiSelect("Select pull requests to check out", candidates)
+	if err != nil {
+		return err
+	}
+	if len(selections) == 0 {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	var created, skipped int
+	var failures []string
+	for _, selection := range selections {
+		pr := prs[selection]
+		fmt.Fprintf(os.Stderr, "→ Checking out PR #%d...\n", pr.Number)
+
+		err := checkoutRun(ctx, opts, strconv.Itoa(pr.Number))
+		var existsErr *existingWorktreeError
+		switch {
+		case err == nil:
+			created++
+		case errors.As(err, &existsErr):
+			skipped++
+			fmt.Fprintf(os.Stderr, "  Skipping: %v\n", err)
+		case errors.Is(err, errCheckoutInterrupted):
+			return err
+		default:
+			failures = append(failures, fmt.Sprintf("#%d: %v", pr.Number, err))
+			fmt.Fprintf(os.Stderr, "  ⚠ PR #%d: %v\n", pr.Number, err)
+		}
+	}
+
+	fmt.Printf("\nCreated %d worktree(s), skipped %d existing, %d failed\n", created, skipped, len(failures))
+	for _, failure := range failures {
+		fmt.Printf("  %s\n", failure)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to check out %d of %d pull requests", len(failures), len(selections))
+	}
+	return nil
+}
+
+func checkoutRunInteractive(ctx context.Context, opts *worktree.CheckoutOptions) error {
+	if err := resolveBranchNaming(opts); err != nil {
+		return err
+	}
+
+	// The current repository is only recognized on hosts gh is logged in to
+	if err := checkAuth(""); err != nil {
+		return err
+	}
+	repo, err := repository.Current()
+	if err != nil {
+		return fmt.Errorf("failed to get current repository: %w", err)
+	}
+	if err := checkAuth(repo.Host); err != nil {
+		return err
+	}
+
+	// Get PRs from API
+	client, err := api.DefaultRESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	listOpts := github.ListOptions{State: opts.State}
+	if gitRoot, err := git.GetRoot(); err == nil {
+		config, err := setup.LoadConfig(gitRoot)
 		if err != nil {
-			relPath = worktreePath // Fall back to absolute path
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		listOpts.PerPage = config.List.PerPage
+		listOpts.Sort = config.List.Sort
+		listOpts.Direction = config.List.Direction
+	}
+	query, queryWarnings := listOpts.Query()
+	for _, warning := range queryWarnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	var prs []github.PullRequest
+	err = client.Get(fmt.Sprintf("repos/%s/%s/pulls?%s", repo.Owner, repo.Name, query), &prs)
+	if err != nil {
+		return fmt.Errorf("failed to get PRs: %w", err)
+	}
+
+	// Filter by author and assignee on the client side since the pulls API doesn't support them
+	author, err := resolveLogin(client, opts.Author)
+	if err != nil {
+		return err
+	}
+	assignee, err := resolveLogin(client, opts.Assignee)
+	if err != nil {
+		return err
+	}
+	prs = github.FilterPRs(prs, author, assignee)
+
+	// Mark PRs where the current user is a requested reviewer. Their login is
+	// only looked up for --requested, unless a filter already resolved it.
+	var viewer string
+	switch {
+	case opts.Author == "@me":
+		viewer = author
+	case opts.Assignee == "@me":
+		viewer = assignee
+	case opts.Requested:
+		viewer, err = resolveLogin(client, "@me")
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Requested {
+		prs = github.FilterReviewRequested(prs, viewer)
+	}
+
+	// Create candidates list
+	candidates := []string{}
+	for _, pr := range prs {
+		candidates = append(candidates, github.FormatFilteredPRCandidate(&pr, author, assignee, viewer))
+	}
+
+	if opts.InteractiveMulti {
+		return checkoutMultiple(ctx, prs, candidates, opts)
+	}
+
+	// Add "Create a new branch" option at the end
+	candidates = append(candidates, createNewBranchCandidate)
+
+	// Use gh CLI's built-in selection. With --describe, the selected PR's
+	// description is shown first and declining it returns to the selection.
+	var selection int
+	for {
+		selection, err = promptSelect("Select a pull request to check out", candidates)
+		if err != nil {
+			if opts.ShellMode {
+				// In shell mode, if prompting fails, just return empty to avoid cd errors
+				return nil
+			}
+			return err
+		}
+
+		if selection == -1 {
+			if !opts.ShellMode {
+				fmt.Println("Cancelled.")
+			}
+			// In shell mode, output nothing when cancelled so cd doesn't change directory
+			return nil
+		}
+
+		if !opts.Describe || selection == len(candidates)-1 {
+			break
+		}
+		describePR(&prs[selection])
+		confirmed, err := promptConfirm(fmt.Sprintf("Check out PR #%d?", prs[selection].Number), true)
+		if err != nil {
+			if opts.ShellMode {
+				return nil
+			}
+			return err
+		}
+		if confirmed {
+			break
+		}
+	}
+
+	// Check if "Create a new branch" was selected
+	if selection == len(candidates)-1 {
+		branchName, err := promptBranchName()
+		if err != nil {
+			if opts.ShellMode {
+				return nil
+			}
+			return err
+		}
+		if branchName == "" {
+			if !opts.ShellMode {
+				fmt.Println("Cancelled.")
+			}
+			return nil
+		}
+
+		// Create branch worktree
+		return checkoutBranchWorktree(ctx, branchName, opts)
+	}
+
+	selectedPR := prs[selection]
+	
+	// Fetch full PR details to get maintainer_can_modify and other fields
+	var fullPR github.PullRequest
+	err = client.Get(fmt.Sprintf("repos/%s/%s/pulls/%d", repo.Owner, repo.Name, selectedPR.Number), &fullPR)
+	if err != nil {
+		return fmt.Errorf("failed to get full PR details: %w", err)
+	}
+	overrideHeadRef(&fullPR, opts)
+
+	// Generate worktree path
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
+	if err := validate.PRNumber(fullPR.Number); err != nil {
+		return fmt.Errorf("invalid PR number: %w", err)
+	}
+
+	worktreePath, err := prWorktreePath(repoName, &fullPR, opts)
+	if err != nil {
+		return fmt.Errorf("failed to generate worktree path: %w", err)
+	}
+
+	ctx, stopInterrupt := catchInterrupt(ctx)
+	defer stopInterrupt()
+
+	// Check if worktree already exists
+	exists, err := checkExistingPath(worktreePath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if opts.Reuse {
+			return reuseWorktree(worktreePath, fmt.Sprintf("#%d", fullPR.Number), opts)
+		}
+		if opts.ShellMode {
+			// In shell mode, output the existing path so cd still works
+			if err := printShellPath(worktreePath, opts); err != nil {
+				return err
+			}
+			return nil
+		}
+		return &existingWorktreeError{what: fmt.Sprintf("PR #%d", fullPR.Number), path: worktreePath}
+	}
+	if switched, err := offerExistingPRWorktree(&fullPR, opts); err != nil || switched {
+		return err
+	}
+	if err := checkWorktreeLimit(gitRoot, repoName, opts); err != nil {
+		return err
+	}
+
+	// Create worktree
+	creator, err := worktree.NewCreatorContext(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("failed to create worktree creator: %w", err)
+	}
+
+	err = creator.Create(worktreePath, &fullPR, opts)
+	if err != nil {
+		return createError(ctx, err)
+	}
+	stopInterrupt()
+	if opts.Ephemeral {
+		defer removeEphemeral(worktreePath)
+	}
+
+	if opts.RebaseOnto != "" {
+		fmt.Fprintf(os.Stderr, "→ Rebasing onto %s...\n", opts.RebaseOnto)
+		if err := worktree.Rebase(worktreePath, opts.RebaseOnto, opts.NoVerify); err != nil {
+			return err
+		}
+	}
+
+	if err := creator.Setup(worktreePath, opts); err != nil {
+		return err
+	}
+	if err := creator.Renormalize(worktreePath, opts); err != nil {
+		return err
+	}
+	if err := creator.Annotate(worktreePath, &fullPR, opts); err != nil {
+		return err
+	}
+
+	summaryWarnings := writeSummary(worktreePath, fullPR.Number, opts)
+	afterWarnings := runAfterCommand(worktreePath, opts)
+	hookWarnings := runPostCreateHook(worktreePath, fullPR.Number)
+	checkWarnings := waitForChecks(worktreePath, opts)
+	if opts.Tmux {
+		checkWarnings = append(checkWarnings, openTmuxWindow(worktreePath)...)
+	}
+
+	warnings := collectWarnings(creator.SetupWarnings(), summaryWarnings, afterWarnings, hookWarnings, checkWarnings)
+	notifyCheckout(opts, fmt.Sprintf("#%d", fullPR.Number), warnings, nil)
+	if opts.Ephemeral {
+		return runEphemeral(worktreePath, opts.Run)
+	}
+
+	// Output based on mode
+	if opts.ShellMode {
+		// Shell mode: output only the path for use in shell functions
+		if err := printShellPath(worktreePath, opts); err != nil {
+			return err
 		}
-		fmt.Print(relPath)
 	} else {
 		// Normal mode: output a friendly message
 		fmt.Printf("Created worktree for #%d at %s\n", fullPR.Number, worktreePath)
 		if fullPR.Title != "" {
 			fmt.Printf("Title: %s\n", fullPR.Title)
 		}
+		if opts.OpenTerminal {
+			return openTerminal(worktreePath)
+		}
 	}
 	return nil
 }
 
+// checkAuth fails early with what to do if gh has no token for host, or isn't
+// logged in to any host if host is empty, rather than with the generic error
+// of the first API request. Only the presence of a token is checked, which
+// needs no request.
+func checkAuth(host string) error {
+	if host == "" {
+		if len(auth.KnownHosts()) == 0 {
+			return fmt.Errorf("not logged in to GitHub; run `gh auth login` or set GH_TOKEN")
+		}
+		return nil
+	}
+	if token, _ := auth.TokenForHost(host); token == "" {
+		return fmt.Errorf("not logged in to %s; run `gh auth login --hostname %s` or set GH_TOKEN", host, host)
+	}
+	return nil
+}
+
+// resolveLogin normalizes a user filter, resolving "@me" to the authenticated user's login.
+func resolveLogin(client *api.RESTClient, login string) (string, error) {
+	if login != "@me" {
+		return strings.TrimPrefix(login, "@"), nil
+	}
+
+	var user github.User
+	if err := client.Get("user", &user); err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	return user.Login, nil
+}
+
 // checkoutBranchWorktree creates a new worktree for local development.
-func checkoutBranchWorktree(branchName string, opts *worktree.CheckoutOptions) error {
+func checkoutBranchWorktree(ctx context.Context, branchName string, opts *worktree.CheckoutOptions) error {
 	// Validate branch name
 	if err := validate.BranchName(branchName); err != nil {
 		return fmt.Errorf("invalid branch name: %w", err)
@@ -431,95 +1374,816 @@ func checkoutBranchWorktree(branchName string, opts *worktree.CheckoutOptions) e
 		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	repoName := filepath.Base(gitRoot)
-	if err := validate.RepoName(repoName); err != nil {
-		return fmt.Errorf("invalid repository name: %w", err)
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
+
+	// Generate worktree path for branch
+	worktreePath, err := branchWorktreePath(repoName, branchName, opts)
+	if err != nil {
+		return fmt.Errorf("failed to generate worktree path: %w", err)
+	}
+
+	// Check if worktree already exists
+	exists, err := checkExistingPath(worktreePath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if opts.Reuse {
+			return reuseWorktree(worktreePath, fmt.Sprintf("branch '%s'", branchName), opts)
+		}
+		if opts.ShellMode {
+			// In shell mode, output the existing path so cd still works
+			if err := printShellPath(worktreePath, opts); err != nil {
+				return err
+			}
+			return nil
+		}
+		return &existingWorktreeError{what: "branch " + branchName, path: worktreePath}
+	}
+
+	// Check if branch already exists
+	branchExists := git.BranchExists(branchName)
+
+	// git refuses to check out a branch in two worktrees; point to the existing one instead
+	if branchExists {
+		existing, err := worktree.FindByBranch(branchName)
+		if err != nil {
+			return fmt.Errorf("failed to list worktrees: %w", err)
+		}
+		if existing != nil {
+			return switchToCheckedOutBranch(branchName, existing.Path, opts)
+		}
+	}
+	if err := checkWorktreeLimit(gitRoot, repoName, opts); err != nil {
+		return err
+	}
+	noMetadata := opts.NoMetadata
+	if !noMetadata {
+		config, err := setup.LoadConfig(gitRoot)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		noMetadata = config.Worktree.NoMetadata != nil && *config.Worktree.NoMetadata
+	}
+
+	// Serialize adding the worktree with other gh worktree processes. The lock
+	// is taken after the checks above, which can prompt, so the path is checked again.
+	createLock, err := worktree.AcquireCreateLock()
+	if err != nil {
+		return err
+	}
+	defer createLock.Release()
+	if err := worktree.CheckNotCreated(worktreePath); err != nil {
+		return err
+	}
+	ctx, stopInterrupt := catchInterrupt(ctx)
+	defer stopInterrupt()
+
+	// Remember the base so it can be shown later
+	baseBranch := git.GetBranchName(".")
+	baseCommit := git.GetHeadCommit(".")
+
+	// Create worktree with new branch from HEAD
+	var cmd [][]string
+	if branchExists {
+		// Branch exists, checkout existing branch
+		cmd = [][]string{{"worktree", "add", worktreePath, branchName}}
+	} else {
+		// Create new branch from HEAD
+		cmd = [][]string{{"worktree", "add", "-b", branchName, worktreePath}}
+	}
+	if opts.NoVerify {
+		cmd = worktree.SkipHooks(cmd)
+	}
+
+	if err := git.ExecuteCommandsContext(ctx, cmd); err != nil {
+		if worktree.Interrupted(ctx, err) {
+			newBranch := ""
+			if !branchExists {
+				newBranch = branchName
+			}
+			worktree.RollBack(worktreePath, newBranch)
+		}
+		return createError(ctx, err)
+	}
+
+	if !noMetadata {
+		// Set worktree type metadata
+		if err := worktree.SetWorktreeType(branchName, "branch"); err != nil {
+			return fmt.Errorf("failed to set worktree type: %w", err)
+		}
+
+		// Record the base only for new branches since existing ones weren't created from HEAD
+		if !branchExists && baseCommit != "" {
+			if err := worktree.SetBranchBase(branchName, baseBranch, baseCommit); err != nil {
+				return fmt.Errorf("failed to set branch base: %w", err)
+			}
+		}
+	}
+
+	createLock.Release()
+	stopInterrupt()
+
+	// Run post-creation setup if not disabled
+	var setupWarnings []string
+	if opts.MirrorConfig || opts.ConfigSource != nil || !opts.NoSetup || opts.LinkNodeModules {
+		mainWorktree, err := git.GetMainWorktree()
+		if err != nil {
+			return fmt.Errorf("failed to get main worktree: %w", err)
+		}
+
+		if opts.MirrorConfig {
+			setupWarnings, err = setup.MirrorConfig(worktreePath, mainWorktree)
+			if err != nil {
+				return fmt.Errorf("failed to mirror git config: %w", err)
+			}
+		}
+
+		if opts.ConfigSource != nil {
+			warnings, err := setup.MirrorConfigFrom(worktreePath, branchName, opts.ConfigSource.Path, opts.ConfigSource.Branch, mainWorktree)
+			if err != nil {
+				return fmt.Errorf("failed to copy git config: %w", err)
+			}
+			setupWarnings = append(setupWarnings, warnings...)
+		}
+
+		warnings, err := setup.LinkDirs(worktreePath, mainWorktree, opts.LinkDirs(), !opts.NoSetup)
+		if err != nil {
+			return fmt.Errorf("failed to link directories: %w", err)
+		}
+		setupWarnings = append(setupWarnings, warnings...)
+
+		if !opts.NoSetup {
+			warnings, err := setup.RunSetup(worktreePath, mainWorktree, opts.SetupProfile)
+			if err != nil {
+				return fmt.Errorf("failed to run setup: %w", err)
+			}
+			setupWarnings = append(setupWarnings, warnings...)
+		}
+	}
+
+	summaryWarnings := writeSummary(worktreePath, 0, opts)
+	afterWarnings := runAfterCommand(worktreePath, opts)
+	hookWarnings := runPostCreateHook(worktreePath, 0)
+	if opts.Tmux {
+		hookWarnings = append(hookWarnings, openTmuxWindow(worktreePath)...)
+	}
+
+	notifyCheckout(opts, fmt.Sprintf("branch '%s'", branchName), collectWarnings(setupWarnings, summaryWarnings, afterWarnings, hookWarnings), nil)
+
+	// Output based on mode
+	if opts.ShellMode {
+		// Shell mode: output only the path for use in shell functions
+		if err := printShellPath(worktreePath, opts); err != nil {
+			return err
+		}
+	} else {
+		// Normal mode: output a friendly message
+		fmt.Printf("Created worktree for branch '%s' at %s\n", branchName, worktreePath)
+		if opts.OpenTerminal {
+			return openTerminal(worktreePath)
+		}
+	}
+	return nil
+}
+
+// openTerminal starts an interactive $SHELL (or /bin/sh) in the worktree and
+// waits for it, so that exiting the shell returns to the original directory.
+// The shell's exit status is not an error of the checkout.
+func openTerminal(worktreePath string) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	fmt.Fprintf(os.Stderr, "Starting %s in %s (exit to return)\n", shell, worktreePath)
+	cmd := exec.Command(shell)
+	cmd.Dir = worktreePath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to start %s: %w", shell, err)
+	}
+	return nil
+}
+
+// catchInterrupt returns a context that is canceled by the first SIGINT or
+// SIGTERM, so that an interrupted checkout rolls back and releases the creation
+// lock instead of dying midway. Later signals have their default effect, so
+// interrupting again exits at once. stop ends the handling; it is safe to call
+// more than once.
+func catchInterrupt(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			fmt.Fprintln(os.Stderr, "\nInterrupted; cleaning up (interrupt again to exit immediately)...")
+			cancel()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// createError wraps an error from creating a worktree, reporting an interrupt
+// as such rather than as the failure of the git command it stopped
+func createError(ctx context.Context, err error) error {
+	if worktree.Interrupted(ctx, err) {
+		return errCheckoutInterrupted
+	}
+	return fmt.Errorf("failed to create worktree: %w", err)
+}
+
+// errCheckoutInterrupted is returned when a checkout is interrupted with Ctrl-C
+var errCheckoutInterrupted = errors.New("checkout interrupted")
+
+// existingWorktreeError reports that the worktree to create already exists
+type existingWorktreeError struct {
+	what string
+	path string
+}
+
+func (e *existingWorktreeError) Error() string {
+	return fmt.Sprintf("worktree for %s already exists at %s", e.what, e.path)
+}
+
+// exitCodeError makes gh worktree exit with the status of a command it ran,
+// such as --run, without printing an error of its own
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.code)
+}
+
+// runEphemeral runs command in the worktree for --run. Interrupts go to the
+// command only, so that the deferred removeEphemeral still runs. A failing
+// command is returned as an *exitCodeError.
+func runEphemeral(worktreePath, command string) error {
+	fmt.Fprintf(os.Stderr, "→ Running %s in %s...\n", command, worktreePath)
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = worktreePath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code := exitErr.ExitCode()
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+				// Like shells, report a command killed by a signal as 128+signal
+				code = 128 + int(status.Signal())
+			}
+			return &exitCodeError{code: code}
+		}
+		return fmt.Errorf("failed to run %s: %w", command, err)
+	}
+	return nil
+}
+
+// removeEphemeral removes an --ephemeral worktree and its branch after it was
+// created, even if a later step failed. Failures are printed as warnings.
+func removeEphemeral(worktreePath string) {
+	fmt.Fprintf(os.Stderr, "→ Removing ephemeral worktree %s...\n", worktreePath)
+	wt, err := worktree.FindByPath(worktreePath)
+	if err == nil && wt == nil && worktree.IsIsolated(worktreePath) {
+		wt = &worktree.Info{Path: worktreePath, Isolated: true}
+	}
+	if err != nil || wt == nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to find worktree %s to remove it\n", worktreePath)
+		return
+	}
+	// Not canceled with the command: the worktree must go even if the command was interrupted
+	removeWorktrees(context.Background(), []*worktree.Info{wt}, true)
+}
+
+// switchToCheckedOutBranch handles --create for a branch that is already checked
+// out in another worktree by offering to switch to that worktree instead
+func switchToCheckedOutBranch(branchName, existingPath string, opts *worktree.CheckoutOptions) error {
+	message := fmt.Sprintf("branch '%s' is already checked out in the worktree at %s", branchName, existingPath)
+
+	if !opts.SwitchExisting {
+		confirmed, err := promptConfirm(fmt.Sprintf("Branch '%s' is already checked out at %s. Switch to it?", branchName, existingPath), true)
+		if err != nil || !confirmed {
+			return fmt.Errorf("%s; use `gh worktree switch %s` to go there", message, branchName)
+		}
+	}
+
+	return printSwitchHint(fmt.Sprintf("branch '%s'", branchName), existingPath, opts)
+}
+
+// offerExistingPRWorktree looks for a worktree that already has the PR checked
+// out under another path and offers to switch to it instead of creating a
+// near-duplicate. It reports whether it switched.
+func offerExistingPRWorktree(pr *github.PullRequest, opts *worktree.CheckoutOptions) (bool, error) {
+	var localBranch string
+	if !opts.Detach && opts.AtCommit == "" {
+		var err error
+		localBranch, err = worktree.LocalBranchName(pr, opts)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	existing, err := worktree.FindDuplicate(pr, localBranch)
+	if err != nil {
+		return false, fmt.Errorf("failed to look for an existing worktree: %w", err)
+	}
+	if existing == nil {
+		return false, nil
+	}
+	if opts.InteractiveMulti {
+		return false, &existingWorktreeError{what: fmt.Sprintf("PR #%d", pr.Number), path: existing.Path}
+	}
+
+	if !opts.SwitchExisting {
+		if !assumeYes && !isTerminal(os.Stdin) {
+			fmt.Fprintf(os.Stderr, "Warning: PR #%d is already checked out at %s; use --switch-existing to switch to it\n", pr.Number, existing.Path)
+			return false, nil
+		}
+		confirmed, err := promptConfirm(fmt.Sprintf("PR #%d is already checked out at %s. Switch to it?", pr.Number, existing.Path), true)
+		if err != nil || !confirmed {
+			return false, nil
+		}
+	}
+	return true, printSwitchHint(fmt.Sprintf("PR #%d", pr.Number), existing.Path, opts)
+}
+
+// printSwitchHint points the user at an existing worktree: its path in shell
+// mode, otherwise the cd command to run
+func printSwitchHint(what, existingPath string, opts *worktree.CheckoutOptions) error {
+	if opts.ShellMode {
+		return printShellPath(existingPath, opts)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	fmt.Printf("To switch to worktree for %s:\n", what)
+	fmt.Printf("cd %s\n", worktree.RelativePath(cwd, existingPath))
+	return nil
+}
+
+// readSelectorFromStdin reads the first line from stdin and extracts the PR number from it.
+func readSelectorFromStdin() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read from stdin: %w", err)
+	}
+
+	prNumber, err := github.ParseCandidateLine(line)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PR from stdin: %w", err)
+	}
+	return strconv.Itoa(prNumber), nil
+}
+
+// checkExistingPath reports whether a registered worktree exists at path.
+// An existing empty directory can be used by git and counts as free. Other
+// contents, e.g. left over from an interrupted run, are removed after confirmation.
+// The main worktree is never a valid target.
+func checkExistingPath(path string) (bool, error) {
+	isMain, err := worktree.IsMain(path)
+	if err != nil {
+		return false, err
+	}
+	if isMain {
+		return false, fmt.Errorf("refusing to create a worktree at %s: it is the main worktree", path)
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+
+	registered, err := worktree.IsRegistered(path)
+	if err != nil {
+		return false, err
+	}
+	if registered || worktree.IsIsolated(path) {
+		return true, nil
+	}
+
+	if !info.IsDir() {
+		return false, fmt.Errorf("%s already exists and is not a directory", path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return false, nil
+	}
+
+	// Never offer to delete something that looks like another repository or worktree
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return false, fmt.Errorf("%s already exists and contains a .git entry that git doesn't know as a worktree of this repository; run `git worktree prune` or remove it manually", path)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s already exists but is not a registered worktree (%d entries), probably left over from an interrupted run.\n", path, len(entries))
+	confirmed, err := promptConfirm(fmt.Sprintf("Remove %s and create the worktree?", path), false)
+	if err != nil {
+		return false, fmt.Errorf("%s already exists and is not a worktree: %w", path, err)
+	}
+	if !confirmed {
+		return false, fmt.Errorf("%s already exists and is not a worktree", path)
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return false, fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return false, nil
+}
+
+// cleanWorktree removes untracked files from a reused worktree,
+// asking for confirmation first unless force is set
+func cleanWorktree(worktreePath string, force bool) error {
+	files, err := worktree.UntrackedFiles(worktreePath)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	if !force {
+		fmt.Fprintf(os.Stderr, "Untracked files in %s:\n", worktreePath)
+		for _, file := range files {
+			fmt.Fprintf(os.Stderr, "  %s\n", file)
+		}
+		confirmed, err := promptConfirm(fmt.Sprintf("Remove %d untracked file(s)?", len(files)), false)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("clean cancelled; the worktree was left unchanged")
+		}
+	}
+
+	if err := worktree.Clean(worktreePath); err != nil {
+		return fmt.Errorf("failed to clean worktree: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Removed %d untracked file(s)\n", len(files))
+	return nil
+}
+
+// reuseWorktree reports an existing worktree as the checkout result,
+// optionally fast-forwarding it first.
+func reuseWorktree(worktreePath, label string, opts *worktree.CheckoutOptions) error {
+	if opts.Clean {
+		if err := cleanWorktree(worktreePath, opts.Force); err != nil {
+			return err
+		}
+	}
+
+	if opts.Update {
+		if err := worktree.Update(worktreePath); err != nil {
+			return fmt.Errorf("failed to update worktree: %w", err)
+		}
+	}
+
+	notifyCheckout(opts, label, nil, nil)
+
+	if opts.ShellMode {
+		if err := printShellPath(worktreePath, opts); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if opts.Update {
+		fmt.Printf("Updated existing worktree for %s at %s\n", label, worktreePath)
+	} else {
+		fmt.Printf("Reusing existing worktree for %s at %s\n", label, worktreePath)
+	}
+	return nil
+}
+
+// runAfterCommand runs the --after command in the new worktree.
+// Its output goes to stderr so that it never mixes with the path printed in shell mode.
+func runAfterCommand(worktreePath string, opts *worktree.CheckoutOptions) []string {
+	if opts.After == "" {
+		return nil
+	}
+
+	mainWorktree, err := git.GetMainWorktree()
+	if err != nil {
+		warning := fmt.Sprintf("failed to get main worktree: %v", err)
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		return []string{warning}
+	}
+	return setup.RunAfter(worktreePath, mainWorktree, opts.After)
+}
+
+// describePR prints the PR's description to stderr for --describe. It goes
+// through $GH_PAGER or $PAGER if set and stderr is a terminal.
+func describePR(pr *github.PullRequest) {
+	description := github.FormatDescription(pr)
+
+	pager := os.Getenv("GH_PAGER")
+	if pager == "" {
+		pager = os.Getenv("PAGER")
+	}
+	if pager != "" && pager != "cat" && isTerminal(os.Stderr) {
+		cmd := exec.Command("sh", "-c", pager)
+		cmd.Stdin = strings.NewReader(description)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err == nil {
+			return
+		}
+	}
+	fmt.Fprint(os.Stderr, description)
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// overrideHeadRef replaces the PR's head ref with --remote-branch, so that it is
+// fetched and tracked instead and the local branch and --name-from head use it
+func overrideHeadRef(pr *github.PullRequest, opts *worktree.CheckoutOptions) {
+	if opts.RemoteBranch == "" {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Using branch %s instead of the PR's head %s\n", opts.RemoteBranch, pr.Head.Ref)
+	pr.Head.Ref = opts.RemoteBranch
+}
+
+// runPostCreateHook notifies the configured hooks.post_create command of the
+// new worktree. prNumber is 0 for branch worktrees.
+func runPostCreateHook(worktreePath string, prNumber int) []string {
+	mainWorktree, err := git.GetMainWorktree()
+	if err != nil {
+		warning := fmt.Sprintf("failed to get main worktree: %v", err)
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		return []string{warning}
+	}
+
+	event := setup.PostCreateEvent{Path: worktreePath, PRNumber: prNumber, Type: "branch"}
+	if prNumber > 0 {
+		event.Type = "pr"
+	}
+	// Detached worktrees have no branch
+	if branch := git.GetBranchName(worktreePath); branch != "HEAD" {
+		event.Branch = branch
+	}
+	return setup.RunPostCreateHook(mainWorktree, event)
+}
+
+// openTmuxWindow opens the worktree in a tmux window named after its directory
+// for --tmux, or switches to the window of that name if there already is one.
+// Outside tmux, or if tmux fails, a warning is returned instead since the
+// worktree has already been created.
+func openTmuxWindow(worktreePath string) []string {
+	warn := func(warning string) []string {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		return []string{warning}
+	}
+	if os.Getenv("TMUX") == "" {
+		return warn("--tmux ignored: not running inside tmux")
+	}
+
+	name := filepath.Base(worktreePath)
+	// Windows are looked up by ID since names may contain characters tmux
+	// treats specially in targets
+	out, err := exec.Command("tmux", "list-windows", "-F", "#{window_id} #{window_name}").Output()
+	if err != nil {
+		return warn(fmt.Sprintf("failed to list tmux windows: %v", err))
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		id, windowName, _ := strings.Cut(line, " ")
+		if windowName != name {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Switching to tmux window %s\n", name)
+		if out, err := exec.Command("tmux", "select-window", "-t", id).CombinedOutput(); err != nil {
+			return warn(fmt.Sprintf("failed to switch to tmux window %s: %v: %s", name, err, strings.TrimSpace(string(out))))
+		}
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Opening tmux window %s\n", name)
+	if out, err := exec.Command("tmux", "new-window", "-n", name, "-c", worktreePath).CombinedOutput(); err != nil {
+		return warn(fmt.Sprintf("failed to open tmux window %s: %v: %s", name, err, strings.TrimSpace(string(out))))
+	}
+	return nil
+}
+
+// writeSummary appends a record of the created worktree to --summary-file.
+// A failure is returned as a warning since the worktree has already been created.
+func writeSummary(worktreePath string, prNumber int, opts *worktree.CheckoutOptions) []string {
+	if opts.SummaryFile == "" {
+		return nil
+	}
+
+	record := worktree.SummaryRecord{
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+		PRNumber:  prNumber,
+		Path:      worktreePath,
+		Commit:    git.GetHeadCommit(worktreePath),
+	}
+	if absPath, err := filepath.Abs(worktreePath); err == nil {
+		record.Path = absPath
+	}
+	// Detached worktrees have no branch
+	if branch := git.GetBranchName(worktreePath); branch != "HEAD" {
+		record.Branch = branch
+	}
+	if err := worktree.AppendSummary(opts.SummaryFile, record); err != nil {
+		warning := err.Error()
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		return []string{warning}
+	}
+	return nil
+}
+
+// waitForChecks blocks until the checks of the checked out commit complete if
+// --wait-for-checks is set. Failures and timeouts are returned as warnings
+// since the worktree has already been created.
+func waitForChecks(worktreePath string, opts *worktree.CheckoutOptions) []string {
+	if !opts.WaitForChecks {
+		return nil
+	}
+
+	warn := func(format string, args ...any) []string {
+		warning := fmt.Sprintf(format, args...)
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", warning)
+		return []string{warning}
 	}
 
-	// Generate worktree path for branch
-	worktreePath, err := worktree.GeneratePathForBranch(repoName, branchName)
+	repo, err := repository.Current()
 	if err != nil {
-		return fmt.Errorf("failed to generate worktree path: %w", err)
+		return warn("failed to get current repository: %v", err)
+	}
+	client, err := api.DefaultRESTClient()
+	if err != nil {
+		return warn("failed to create REST client: %v", err)
+	}
+	sha := git.GetHeadCommit(worktreePath)
+	if sha == "" {
+		return warn("failed to get the commit of %s", worktreePath)
 	}
 
-	// Check if worktree already exists
-	if _, err := os.Stat(worktreePath); err == nil {
-		if opts.ShellMode {
-			// In shell mode, output the existing path so cd still works
-			cwd, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get current directory: %w", err)
-			}
-			relPath, err := filepath.Rel(cwd, worktreePath)
-			if err != nil {
-				relPath = worktreePath
-			}
-			fmt.Print(relPath)
-			return nil
+	fmt.Fprintf(os.Stderr, "→ Waiting for checks on %s (timeout %s)...\n", sha[:8], opts.ChecksTimeout)
+	last := ""
+	summary, err := github.WaitForChecks(client, repo.Owner, repo.Name, sha, opts.ChecksTimeout, func(s github.CheckSummary) {
+		// Only print when something changed
+		line := fmt.Sprintf("%d passed, %d failed, %d pending", s.Passed, s.Failed, s.Pending)
+		if line != last {
+			fmt.Fprintf(os.Stderr, "  %s\n", line)
+			last = line
 		}
-		return fmt.Errorf("worktree for branch %s already exists at %s", branchName, worktreePath)
+	})
+	if err != nil {
+		return warn("failed to get checks: %v", err)
 	}
 
-	// Check if branch already exists
-	branchExists := git.BranchExists(branchName)
-
-	// Create worktree with new branch from HEAD
-	var cmd [][]string
-	if branchExists {
-		// Branch exists, checkout existing branch
-		cmd = [][]string{{"worktree", "add", worktreePath, branchName}}
+	switch summary.State() {
+	case github.CheckFailure:
+		return warn("Checks failed (%d of %d)", summary.Failed, summary.Total())
+	case github.CheckPending:
+		return warn("Timed out after %s waiting for checks (%d pending)", opts.ChecksTimeout, summary.Pending)
+	}
+	if summary.Total() == 0 {
+		fmt.Fprintln(os.Stderr, "  ✓ No checks reported")
 	} else {
-		// Create new branch from HEAD
-		cmd = [][]string{{"worktree", "add", "-b", branchName, worktreePath}}
+		fmt.Fprintln(os.Stderr, "  ✓ Checks passed")
 	}
+	return nil
+}
 
-	if err := git.ExecuteCommands(cmd); err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+// switchTargetPath returns the path switch prints for targetPath: absolute
+// with --absolute (absolute) or as configured by switch.absolute_paths,
+// otherwise relative to the current directory
+func switchTargetPath(gitRoot, targetPath string, absolute bool) (string, error) {
+	if !absolute {
+		config, err := setup.LoadConfig(gitRoot)
+		if err != nil {
+			return "", fmt.Errorf("failed to load config: %w", err)
+		}
+		absolute, err = config.Switch.Absolute(targetPath == gitRoot)
+		if err != nil {
+			return "", err
+		}
+	}
+	if absolute {
+		return filepath.ToSlash(filepath.Clean(targetPath)), nil
 	}
 
-	// Set worktree type metadata
-	if err := worktree.SetWorktreeType(branchName, "branch"); err != nil {
-		return fmt.Errorf("failed to set worktree type: %w", err)
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
 	}
+	return worktree.RelativePath(cwd, targetPath), nil
+}
 
-	// Run post-creation setup if not disabled
-	if !opts.NoSetup {
-		mainWorktree, err := git.GetMainWorktree()
+// printShellPath prints the path of a worktree relative to the current
+// directory, for the shell function to cd into, or with --export, statements
+// exporting the worktree's absolute path, branch and PR number
+func printShellPath(worktreePath string, opts *worktree.CheckoutOptions) error {
+	if opts.Export {
+		wt, err := worktree.FindByPath(worktreePath)
 		if err != nil {
-			return fmt.Errorf("failed to get main worktree: %w", err)
+			return err
 		}
-
-		if err := setup.RunSetup(worktreePath, mainWorktree); err != nil {
-			return fmt.Errorf("failed to run setup: %w", err)
+		if wt == nil {
+			// Isolated clones aren't listed as worktrees
+			absPath, err := filepath.Abs(worktreePath)
+			if err != nil {
+				return fmt.Errorf("failed to resolve path %s: %w", worktreePath, err)
+			}
+			wt = &worktree.Info{Path: absPath}
+			if branch := git.GetBranchName(worktreePath); branch != "HEAD" {
+				wt.Branch = branch
+			}
 		}
+		fmt.Print(worktree.ShellExports(wt))
+		return nil
 	}
 
-	// Output based on mode
-	if opts.ShellMode {
-		// Shell mode: output only the path for use in shell functions
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
-
-		relPath, err := filepath.Rel(cwd, worktreePath)
-		if err != nil {
-			relPath = worktreePath
-		}
-		fmt.Print(relPath)
-	} else {
-		// Normal mode: output a friendly message
-		fmt.Printf("Created worktree for branch '%s' at %s\n", branchName, worktreePath)
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
+	fmt.Print(worktree.RelativePath(cwd, worktreePath))
 	return nil
 }
 
-func checkoutRun(opts *worktree.CheckoutOptions, selector string) error {
-	// Get current repository
+// collectWarnings concatenates the warnings of the checkout steps into a new
+// slice, leaving the slices it is given untouched
+func collectWarnings(lists ...[]string) []string {
+	var warnings []string
+	for _, list := range lists {
+		warnings = append(warnings, list...)
+	}
+	return warnings
+}
+
+// notifyCheckout sends a desktop notification about the checkout result if --notify is set.
+func notifyCheckout(opts *worktree.CheckoutOptions, label string, setupWarnings []string, err error) {
+	if !opts.Notify {
+		return
+	}
+
+	switch {
+	case err != nil:
+		notify.Send("gh worktree: checkout failed", err.Error())
+	case len(setupWarnings) > 0:
+		notify.Send("gh worktree: checkout completed with warnings",
+			fmt.Sprintf("Worktree for %s is ready, but %d setup command(s) failed", label, len(setupWarnings)))
+	default:
+		notify.Send("gh worktree: checkout completed", fmt.Sprintf("Worktree for %s is ready", label))
+	}
+}
+
+func checkoutRun(ctx context.Context, opts *worktree.CheckoutOptions, selector string) error {
+	if err := resolveBranchNaming(opts); err != nil {
+		return err
+	}
+
+	// The current repository is only recognized on hosts gh is logged in to
+	if err := checkAuth(""); err != nil {
+		return err
+	}
 	repo, err := repository.Current()
 	if err != nil {
 		return fmt.Errorf("failed to get current repository: %w", err)
 	}
+	if err := checkAuth(repo.Host); err != nil {
+		return err
+	}
 
 	// Parse PR number from selector
 	prNumber, err := github.ParsePRNumber(selector)
@@ -538,6 +2202,10 @@ func checkoutRun(opts *worktree.CheckoutOptions, selector string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get PR details: %w", err)
 	}
+	overrideHeadRef(&pr, opts)
+	if opts.Describe {
+		describePR(&pr)
+	}
 
 	// Generate worktree path
 	gitRoot, err := git.GetRoot()
@@ -545,82 +2213,121 @@ func checkoutRun(opts *worktree.CheckoutOptions, selector string) error {
 		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	repoName := filepath.Base(gitRoot)
-	if err := validate.RepoName(repoName); err != nil {
-		return fmt.Errorf("invalid repository name: %w", err)
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
 	}
 	if err := validate.PRNumber(prNumber); err != nil {
 		return fmt.Errorf("invalid PR number: %w", err)
 	}
 
-	worktreePath, err := worktree.GeneratePath(repoName, prNumber)
+	worktreePath, err := prWorktreePath(repoName, &pr, opts)
 	if err != nil {
 		return fmt.Errorf("failed to generate worktree path: %w", err)
 	}
 
+	ctx, stopInterrupt := catchInterrupt(ctx)
+	defer stopInterrupt()
+
 	// Check if worktree already exists
-	if _, err := os.Stat(worktreePath); err == nil {
+	exists, err := checkExistingPath(worktreePath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if opts.Reuse {
+			return reuseWorktree(worktreePath, fmt.Sprintf("#%d", prNumber), opts)
+		}
 		if opts.ShellMode {
 			// In shell mode, output the existing path so cd still works
-			cwd, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get current directory: %w", err)
-			}
-			relPath, err := filepath.Rel(cwd, worktreePath)
-			if err != nil {
-				relPath = worktreePath
+			if err := printShellPath(worktreePath, opts); err != nil {
+				return err
 			}
-			fmt.Print(relPath)
 			return nil
 		}
-		return fmt.Errorf("worktree for PR #%d already exists at %s", prNumber, worktreePath)
+		return &existingWorktreeError{what: fmt.Sprintf("PR #%d", prNumber), path: worktreePath}
+	}
+	if switched, err := offerExistingPRWorktree(&pr, opts); err != nil || switched {
+		return err
+	}
+	if err := checkWorktreeLimit(gitRoot, repoName, opts); err != nil {
+		return err
 	}
 
 	// Create worktree
-	creator, err := worktree.NewCreator(repo)
+	creator, err := worktree.NewCreatorContext(ctx, repo)
 	if err != nil {
 		return fmt.Errorf("failed to create worktree creator: %w", err)
 	}
 
 	err = creator.Create(worktreePath, &pr, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+		return createError(ctx, err)
+	}
+	stopInterrupt()
+	if opts.Ephemeral {
+		defer removeEphemeral(worktreePath)
+	}
+
+	if opts.RebaseOnto != "" {
+		fmt.Fprintf(os.Stderr, "→ Rebasing onto %s...\n", opts.RebaseOnto)
+		if err := worktree.Rebase(worktreePath, opts.RebaseOnto, opts.NoVerify); err != nil {
+			return err
+		}
+	}
+
+	if err := creator.Setup(worktreePath, opts); err != nil {
+		return err
+	}
+	if err := creator.Renormalize(worktreePath, opts); err != nil {
+		return err
+	}
+	if err := creator.Annotate(worktreePath, &pr, opts); err != nil {
+		return err
+	}
+
+	summaryWarnings := writeSummary(worktreePath, prNumber, opts)
+	afterWarnings := runAfterCommand(worktreePath, opts)
+	hookWarnings := runPostCreateHook(worktreePath, prNumber)
+	checkWarnings := waitForChecks(worktreePath, opts)
+	if opts.Tmux {
+		checkWarnings = append(checkWarnings, openTmuxWindow(worktreePath)...)
+	}
+
+	warnings := collectWarnings(creator.SetupWarnings(), summaryWarnings, afterWarnings, hookWarnings, checkWarnings)
+	notifyCheckout(opts, fmt.Sprintf("#%d", prNumber), warnings, nil)
+	if opts.Ephemeral {
+		return runEphemeral(worktreePath, opts.Run)
 	}
 
 	// Output based on mode
 	if opts.ShellMode {
 		// Shell mode: output only the path for use in shell functions
-		// Get current working directory for relative path calculation
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
-		
-		// Convert absolute path to relative path
-		relPath, err := filepath.Rel(cwd, worktreePath)
-		if err != nil {
-			relPath = worktreePath // Fall back to absolute path
+		if err := printShellPath(worktreePath, opts); err != nil {
+			return err
 		}
-		fmt.Print(relPath)
 	} else {
 		// Normal mode: output a friendly message
 		fmt.Printf("Created worktree for #%d at %s\n", prNumber, worktreePath)
 		if pr.Title != "" {
 			fmt.Printf("Title: %s\n", pr.Title)
 		}
+		if opts.OpenTerminal {
+			return openTerminal(worktreePath)
+		}
 	}
 	return nil
 }
 
-func removeRun(selector string, force bool) error {
+func removeRun(ctx context.Context, selector string, force bool) error {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
 		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	repoName := filepath.Base(gitRoot)
-	if err := validate.RepoName(repoName); err != nil {
-		return fmt.Errorf("invalid repository name: %w", err)
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
 	}
 
 	var worktreePath string
@@ -653,16 +2360,39 @@ func removeRun(selector string, force bool) error {
 		isBranchWorktree = true
 	}
 
-	// Check if worktree exists
+	// Worktrees created with --into-current are elsewhere; find them by metadata
 	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
-		if isBranchWorktree {
-			return fmt.Errorf("worktree for branch %s does not exist at %s", selector, worktreePath)
+		if wt := findRelocatedWorktree(repoName, selector, isBranchWorktree); wt != nil {
+			worktreePath = wt.Path
+		}
+	}
+	// Before teardown or anything else touches it
+	if err := worktree.CheckNotMain(worktreePath, "remove"); err != nil {
+		return err
+	}
+
+	// Check if worktree exists. A worktree whose directory was deleted by hand
+	// is still registered with git and is pruned instead.
+	missing := false
+	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
+		if registered, _ := worktree.IsRegistered(worktreePath); !registered {
+			if isBranchWorktree {
+				return fmt.Errorf("worktree for branch %s does not exist at %s", selector, worktreePath)
+			}
+			return fmt.Errorf("worktree for PR #%d does not exist at %s", prNumber, worktreePath)
 		}
-		return fmt.Errorf("worktree for PR #%d does not exist at %s", prNumber, worktreePath)
+		missing = true
+		fmt.Fprintf(os.Stderr, "Worktree directory %s no longer exists; pruning its registration\n", worktreePath)
 	}
 
 	// Get branch name before removing worktree
 	branchName := git.GetBranchName(worktreePath)
+	if missing {
+		branchName = ""
+		if wt, err := worktree.FindByPath(worktreePath); err == nil && wt != nil && !wt.Detached {
+			branchName = wt.Branch
+		}
+	}
 
 	// Get title/metadata from git config before removing
 	title := ""
@@ -670,18 +2400,24 @@ func removeRun(selector string, force bool) error {
 		if isBranchWorktree {
 			title = "(local development)"
 		} else {
-			title = worktree.GetPRTitle(worktreePath, branchName)
+			title = worktree.GetPRTitle(branchName)
 		}
 	}
 
+	// Run teardown while the worktree still exists
+	runTeardown(worktreePath, branchName)
+
+	// An isolated clone's branch lives in the clone and is removed with it
+	isolated := worktree.IsIsolated(worktreePath)
+
 	// Remove the worktree
-	err = worktree.Remove(worktreePath, force)
+	err = worktree.RemoveContext(ctx, worktreePath, force)
 	if err != nil {
 		return fmt.Errorf("failed to remove worktree: %w", err)
 	}
 
 	// Delete the branch (this also removes branch-specific metadata)
-	if branchName != "" && branchName != "HEAD" {
+	if branchName != "" && branchName != "HEAD" && !isolated {
 		if err := validate.BranchName(branchName); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: invalid branch name %s: %v\n", branchName, err)
 		} else {
@@ -706,13 +2442,96 @@ func removeRun(selector string, force bool) error {
 	return nil
 }
 
-func listRun(showAll bool) error {
+// findRelocatedWorktree looks up a worktree by its metadata, e.g. one that isn't
+// at its default path. Returns nil if it can't be found.
+func findRelocatedWorktree(repoName, selector string, isBranchWorktree bool) *worktree.Info {
+	prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
+	if err != nil {
+		return nil
+	}
+	if isBranchWorktree {
+		prWorktrees = nil
+	} else {
+		branchWorktrees = nil
+	}
+	wt, _ := worktree.FindByIdentifier(prWorktrees, branchWorktrees, selector)
+	return wt
+}
+
+// listOptions represents options for listing worktrees
+type listOptions struct {
+	All      bool
+	Abbrev   int
+	JSON     bool
+	Tree     bool
+	Watch    bool
+	Interval time.Duration
+	// Stale hides worktrees with a commit more recent than this
+	Stale time.Duration
+	// Sort is "" for the default order or worktree.SortLastCommit
+	Sort    string
+	NoColor bool
+}
+
+// needsLastCommit reports whether the last commit dates of worktrees are read
+func (o *listOptions) needsLastCommit() bool {
+	return o.Stale > 0 || o.Sort == worktree.SortLastCommit
+}
+
+func listRun(opts *listOptions) error {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
+	autoPrune(gitRoot, repoName)
+
+	return printList(repoName, opts)
+}
+
+// watchListRun clears the screen and prints the list every interval until
+// interrupted. Merged PRs are offered for auto_prune once, before the first redraw.
+func watchListRun(opts *listOptions) error {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
 		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	repoName := filepath.Base(gitRoot)
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
+	autoPrune(gitRoot, repoName)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Move the cursor home and clear the screen
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("Every %s: gh worktree pr list\t%s\n\n", opts.Interval, time.Now().Format(time.DateTime))
+		if err := printList(repoName, opts); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// printList prints the worktrees of the repository for listRun and watchListRun
+func printList(repoName string, opts *listOptions) error {
+	abbrev := opts.Abbrev
+	palette := color.New(color.Enabled(isTerminal(os.Stdout), opts.NoColor))
 
 	// Get current working directory for relative path calculation
 	cwd, err := os.Getwd()
@@ -720,12 +2539,45 @@ func listRun(showAll bool) error {
 		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	if showAll {
+	formatPR := func(wt *worktree.Info) string {
+		title := wt.Title
+		if title == "" {
+			title = "(no title)"
+		}
+
+		if wt.Isolated {
+			title += " (isolated clone)"
+		}
+
+		relPath := worktree.RelativePath(cwd, wt.Path)
+
+		return fmt.Sprintf("%s\t%s\t%s\t%s\t%s%s", palette.Green(fmt.Sprintf("#%d", wt.PRNumber)), palette.Cyan(wt.BranchLabel(abbrev)),
+			palette.Yellow(wt.ShortCommit(abbrev)), title, palette.Dim(relPath), lastCommitColumn(wt, palette))
+	}
+	formatBranch := func(wt *worktree.Info) string {
+		relPath := worktree.RelativePath(cwd, wt.Path)
+
+		base := "(unknown base)"
+		if b := wt.Base(abbrev); b != "" {
+			base = "from " + b
+		}
+
+		return fmt.Sprintf("%s\t%s\t(local development)\t%s\t%s%s", palette.Cyan(wt.BranchLabel(abbrev)), palette.Yellow(wt.ShortCommit(abbrev)),
+			base, palette.Dim(relPath), lastCommitColumn(wt, palette))
+	}
+
+	if opts.All {
 		// List both PR and branch worktrees
 		prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
 		if err != nil {
 			return fmt.Errorf("failed to get worktrees: %w", err)
 		}
+		prWorktrees = filterList(prWorktrees, opts)
+		branchWorktrees = filterList(branchWorktrees, opts)
+
+		if opts.JSON {
+			return printJSON(append(prWorktrees, branchWorktrees...))
+		}
 
 		if len(prWorktrees) == 0 && len(branchWorktrees) == 0 {
 			fmt.Println("No worktrees found.")
@@ -734,20 +2586,8 @@ func listRun(showAll bool) error {
 
 		// List PR worktrees
 		if len(prWorktrees) > 0 {
-			fmt.Printf("PR worktrees:\n")
-			for _, wt := range prWorktrees {
-				title := wt.Title
-				if title == "" {
-					title = "(no title)"
-				}
-
-				relPath, err := filepath.Rel(cwd, wt.Path)
-				if err != nil {
-					relPath = wt.Path
-				}
-
-				fmt.Printf("  #%d\t%s\t%s\t%s\n", wt.PRNumber, wt.Branch, title, relPath)
-			}
+			fmt.Println(palette.Bold("PR worktrees:"))
+			printWorktreeLines(prWorktrees, opts.Tree, palette, formatPR)
 		}
 
 		// List branch worktrees
@@ -755,15 +2595,8 @@ func listRun(showAll bool) error {
 			if len(prWorktrees) > 0 {
 				fmt.Println()
 			}
-			fmt.Printf("Branch worktrees:\n")
-			for _, wt := range branchWorktrees {
-				relPath, err := filepath.Rel(cwd, wt.Path)
-				if err != nil {
-					relPath = wt.Path
-				}
-
-				fmt.Printf("  %s\t(local development)\t%s\n", wt.Branch, relPath)
-			}
+			fmt.Println(palette.Bold("Branch worktrees:"))
+			printWorktreeLines(branchWorktrees, opts.Tree, palette, formatBranch)
 		}
 	} else {
 		// List only PR worktrees (default behavior)
@@ -771,38 +2604,134 @@ func listRun(showAll bool) error {
 		if err != nil {
 			return fmt.Errorf("failed to get PR worktrees: %w", err)
 		}
+		prWorktrees = filterList(prWorktrees, opts)
+
+		if opts.JSON {
+			return printJSON(prWorktrees)
+		}
 
 		if len(prWorktrees) == 0 {
 			fmt.Println("No PR worktrees found.")
 			return nil
 		}
 
-		fmt.Printf("PR worktrees:\n")
-		for _, wt := range prWorktrees {
-			title := wt.Title
-			if title == "" {
-				title = "(no title)"
-			}
+		fmt.Println(palette.Bold("PR worktrees:"))
+		printWorktreeLines(prWorktrees, opts.Tree, palette, formatPR)
+	}
 
-			relPath, err := filepath.Rel(cwd, wt.Path)
-			if err != nil {
-				relPath = wt.Path
-			}
+	return nil
+}
+
+// filterList applies --stale and --sort to worktrees, reading their last
+// commit dates if either is set
+func filterList(worktrees []*worktree.Info, opts *listOptions) []*worktree.Info {
+	if !opts.needsLastCommit() {
+		return worktrees
+	}
+	worktree.LoadLastCommitTimes(worktrees)
+	if opts.Stale > 0 {
+		worktrees = worktree.FilterStale(worktrees, opts.Stale, time.Now())
+	}
+	if opts.Sort == worktree.SortLastCommit {
+		worktree.SortByLastCommit(worktrees)
+	}
+	return worktrees
+}
+
+// lastCommitColumn returns the date of the worktree's last commit as an extra
+// list column if it was read, or "" otherwise
+func lastCommitColumn(wt *worktree.Info, palette *color.Palette) string {
+	if wt.LastCommit.IsZero() {
+		return ""
+	}
+	return "\t" + palette.Dim("last commit "+wt.LastCommit.Format(time.DateOnly))
+}
+
+// printWorktreeLines prints one indented line per worktree.
+// In tree mode, worktrees whose branches share a prefix are nested under it.
+func printWorktreeLines(worktrees []*worktree.Info, tree bool, palette *color.Palette, format func(*worktree.Info) string) {
+	if !tree {
+		for _, wt := range worktrees {
+			fmt.Printf("  %s\n", format(wt))
+		}
+		return
+	}
 
-			fmt.Printf("  #%d\t%s\t%s\t%s\n", wt.PRNumber, wt.Branch, title, relPath)
+	for _, group := range worktree.GroupByPrefix(worktrees) {
+		indent := "  "
+		if group.Prefix != "" {
+			fmt.Printf("  %s\n", palette.Bold(group.Prefix+"/"))
+			indent = "    "
+		}
+		for _, wt := range group.Worktrees {
+			fmt.Printf("%s%s\n", indent, format(wt))
 		}
 	}
+}
+
+// printJSON writes worktrees to stdout as an indented JSON array.
+func printJSON(worktrees []*worktree.Info) error {
+	if worktrees == nil {
+		worktrees = []*worktree.Info{}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(worktrees)
+}
+
+func whereamiRun(jsonOutput bool) error {
+	wt, err := worktree.FindByPath(".")
+	if err != nil {
+		return err
+	}
+	if wt == nil {
+		return fmt.Errorf("not inside a worktree")
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(wt)
+	}
+
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+
+	fmt.Printf("Path: %s\n", wt.Path)
+	switch {
+	case wt.Path == gitRoot:
+		fmt.Println("Type: main")
+	case wt.PRNumber != 0:
+		fmt.Printf("Type: PR #%d\n", wt.PRNumber)
+	default:
+		fmt.Println("Type: branch")
+	}
+	fmt.Printf("Branch: %s\n", wt.BranchLabel(8))
+	fmt.Printf("Commit: %s\n", wt.ShortCommit(8))
+	if wt.Title != "" {
+		fmt.Printf("Title: %s\n", wt.Title)
+	}
+	if base := wt.Base(8); base != "" {
+		fmt.Printf("Base: %s\n", base)
+	}
 
 	return nil
 }
 
-func switchRun(shellMode bool, prNumber string) error {
+func switchRun(ctx context.Context, shellMode, createIfMissing, absolute bool, identifier string) error {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
 		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	repoName := filepath.Base(gitRoot)
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
+	autoPrune(gitRoot, repoName)
 	prWorktrees, err := worktree.ListPRWorktrees(repoName)
 	if err != nil {
 		return fmt.Errorf("failed to get PR worktrees: %w", err)
@@ -812,27 +2741,42 @@ func switchRun(shellMode bool, prNumber string) error {
 	var targetPath string
 
 	// Handle direct selection
-	if prNumber != "" {
-		if prNumber == "main" {
+	if identifier != "" {
+		if identifier == "main" {
 			// Handle main worktree selection
 			targetPath = gitRoot
 		} else {
-			// Handle PR number selection
-			prNum, err := github.ParsePRNumber(prNumber)
+			// Handle PR number or branch name selection
+			selectedWorktree, err = worktree.FindByIdentifier(prWorktrees, nil, identifier)
 			if err != nil {
-				return fmt.Errorf("invalid PR number: %w", err)
+				return err
 			}
 
-			for _, wt := range prWorktrees {
-				if wt.PRNumber == prNum {
-					selectedWorktree = wt
-					break
+			if selectedWorktree == nil && createIfMissing {
+				prNum, err := github.ParsePRNumber(identifier)
+				if err != nil {
+					return fmt.Errorf("cannot create worktree for '%s': not a PR number", identifier)
+				}
+
+				// In shell mode checkout prints the path itself
+				if err := checkoutRun(ctx, &worktree.CheckoutOptions{ShellMode: shellMode}, identifier); err != nil {
+					return err
+				}
+				if shellMode {
+					return nil
+				}
+
+				// Look the worktree up rather than regenerating its path, which
+				// depends on worktree.name_from
+				selectedWorktree = findRelocatedWorktree(repoName, strconv.Itoa(prNum), false)
+				if selectedWorktree == nil {
+					return fmt.Errorf("failed to find the worktree created for #%d", prNum)
 				}
 			}
 
 			if selectedWorktree == nil {
 				if !shellMode {
-					fmt.Printf("Worktree for #%d not found.\n", prNum)
+					fmt.Printf("Worktree '%s' not found.\n", identifier)
 				}
 				return nil
 			}
@@ -853,7 +2797,7 @@ func switchRun(shellMode bool, prNumber string) error {
 			}
 			candidates = append(candidates, fmt.Sprintf("#%d\t%s\t%s",
 				wt.PRNumber,
-				wt.Branch,
+				wt.BranchLabel(7),
 				title))
 		}
 
@@ -881,22 +2825,16 @@ func switchRun(shellMode bool, prNumber string) error {
 			targetPath = gitRoot
 		} else {
 			// PR worktree selected (adjust index since main is first)
-			selectedWorktree = prWorktrees[selection-1]
-			targetPath = selectedWorktree.Path
-		}
-	}
-
-	// Get current working directory for relative path calculation
-	cwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+			selectedWorktree = prWorktrees[selection-1]
+			targetPath = selectedWorktree.Path
+		}
 	}
 
-	// Convert absolute path to relative path
-	relPath, err := filepath.Rel(cwd, targetPath)
+	relPath, err := switchTargetPath(gitRoot, targetPath, absolute)
 	if err != nil {
-		relPath = targetPath // Fall back to absolute path
+		return err
 	}
+	recordSwitch(targetPath)
 
 	// Output based on mode
 	if shellMode {
@@ -904,7 +2842,7 @@ func switchRun(shellMode bool, prNumber string) error {
 		fmt.Print(relPath)
 	} else {
 		// Normal mode: output a friendly message with command
-		if prNumber == "main" || (prNumber == "" && targetPath == gitRoot) {
+		if identifier == "main" || (identifier == "" && targetPath == gitRoot) {
 			fmt.Printf("To switch to main worktree:\n")
 		} else {
 			fmt.Printf("To switch to worktree for #%d:\n", selectedWorktree.PRNumber)
@@ -915,8 +2853,17 @@ func switchRun(shellMode bool, prNumber string) error {
 	return nil
 }
 
+// promoteOptions represents options for promoting a branch worktree
+type promoteOptions struct {
+	CreatePR  bool
+	Title     string
+	Body      string
+	Base      string
+	Unpromote bool
+}
+
 // promoteRun promotes a branch worktree to a PR worktree.
-func promoteRun(branchName string, prNumber int) error {
+func promoteRun(branchName string, prNumber int, opts *promoteOptions) error {
 	// Validate branch name
 	if err := validate.BranchName(branchName); err != nil {
 		return fmt.Errorf("invalid branch name: %w", err)
@@ -945,15 +2892,34 @@ func promoteRun(branchName string, prNumber int) error {
 			return fmt.Errorf("failed to create REST client: %w", err)
 		}
 
+		// The PR's head lives wherever the branch is pushed, e.g. the user's fork
+		pushRemote, err := branchPushRemote(branchName)
+		if err != nil {
+			return err
+		}
+		headOwner := repo.Owner
+		if pushRemote.Repo != nil {
+			headOwner = pushRemote.Repo.Owner
+		}
+
 		var prs []github.PullRequest
 		err = client.Get(fmt.Sprintf("repos/%s/%s/pulls?head=%s:%s&state=open", 
-			repo.Owner, repo.Name, repo.Owner, branchName), &prs)
+			repo.Owner, repo.Name, headOwner, branchName), &prs)
 		if err != nil {
 			return fmt.Errorf("failed to get PRs for branch: %w", err)
 		}
 
+		if len(prs) == 0 && opts.CreatePR {
+			pr, err := createPRForBranch(client, repo, branchName, pushRemote, opts)
+			if err != nil {
+				return fmt.Errorf("failed to create PR: %w", err)
+			}
+			fmt.Printf("Created PR #%d for branch '%s'\n", pr.Number, branchName)
+			prs = append(prs, *pr)
+		}
+
 		if len(prs) == 0 {
-			return fmt.Errorf("no open PR found for branch %s. Please create a PR first or specify the PR number", branchName)
+			return fmt.Errorf("no open PR found for branch %s. Please create a PR first, use --create-pr, or specify the PR number", branchName)
 		}
 
 		if len(prs) > 1 {
@@ -993,14 +2959,168 @@ func promoteRun(branchName string, prNumber int) error {
 	return nil
 }
 
-// switchAllRun switches to any worktree (PR, branch, or main).
-func switchAllRun(shellMode bool, identifier string) error {
+// renameBranchRun renames the local branch of the worktree found for identifier
+func renameBranchRun(identifier, newName string) error {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
+
+	prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
+	if err != nil {
+		return fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	wt, err := worktree.FindByIdentifier(prWorktrees, branchWorktrees, identifier)
+	if err != nil {
+		return err
+	}
+	if wt == nil {
+		return fmt.Errorf("no worktree found for %s", identifier)
+	}
+
+	oldName := wt.Branch
+	if err := worktree.RenameBranch(wt, newName); err != nil {
+		return err
+	}
+	fmt.Printf("Renamed branch '%s' to '%s' in %s\n", oldName, newName, wt.Path)
+	return nil
+}
+
+// unpromoteRun reverts a PR worktree to a branch worktree.
+// The worktree directory and branch are left as they are.
+func unpromoteRun(branchName string) error {
+	if err := validate.BranchName(branchName); err != nil {
+		return fmt.Errorf("invalid branch name: %w", err)
+	}
+
+	worktreeType, err := worktree.GetWorktreeType(branchName)
+	if err != nil {
+		return fmt.Errorf("failed to get worktree type: %w", err)
+	}
+	if worktreeType != "pr" {
+		return fmt.Errorf("branch %s is not a PR worktree", branchName)
+	}
+
+	if err := worktree.DemoteToBranch(branchName); err != nil {
+		return fmt.Errorf("failed to unpromote worktree: %w", err)
+	}
+
+	fmt.Printf("Reverted worktree for branch '%s' to a branch worktree\n", branchName)
+	return nil
+}
+
+// createPRForBranch pushes the branch to pushRemote and opens a pull request for it.
+// The title and body are prompted for if they weren't given as flags.
+func createPRForBranch(client *api.RESTClient, repo repository.Repository, branchName string, pushRemote *git.Remote, opts *promoteOptions) (*github.PullRequest, error) {
+	title := opts.Title
+	body := opts.Body
+	if title == "" {
+		p := prompter.New(os.Stdin, os.Stderr, os.Stderr)
+		var err error
+		title, err = p.Input("Title", branchName)
+		if err != nil {
+			return nil, err
+		}
+		title = strings.TrimSpace(title)
+		if title == "" {
+			return nil, fmt.Errorf("title cannot be empty")
+		}
+
+		if body == "" {
+			body, err = p.Input("Body", "")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	base := opts.Base
+	if base == "" {
+		var repoInfo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := client.Get(fmt.Sprintf("repos/%s/%s", repo.Owner, repo.Name), &repoInfo); err != nil {
+			return nil, fmt.Errorf("failed to get default branch: %w", err)
+		}
+		base = repoInfo.DefaultBranch
+	}
+	if err := validate.BranchName(base); err != nil {
+		return nil, fmt.Errorf("invalid base branch: %w", err)
+	}
+
+	// The head branch must exist on the remote before a PR can be opened
+	if err := git.ExecuteCommands([][]string{{"push", "--set-upstream", pushRemote.Name, branchName}}); err != nil {
+		return nil, fmt.Errorf("failed to push branch: %w", err)
+	}
+
+	// A branch pushed to a fork is named owner:branch
+	head := branchName
+	if pushRemote.Repo != nil && !strings.EqualFold(pushRemote.Repo.Owner, repo.Owner) {
+		head = pushRemote.Repo.Owner + ":" + branchName
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"title": title,
+		"head":  head,
+		"base":  base,
+		"body":  body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PR request: %w", err)
+	}
+
+	var pr github.PullRequest
+	err = client.Post(fmt.Sprintf("repos/%s/%s/pulls", repo.Owner, repo.Name), bytes.NewReader(payload), &pr)
+	if err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// branchPushRemote returns the remote that `git push` pushes branchName to:
+// branch.<name>.pushRemote, then remote.pushDefault, then branch.<name>.remote,
+// falling back to origin
+func branchPushRemote(branchName string) (*git.Remote, error) {
+	name := "origin"
+	for _, key := range []string{"branch." + branchName + ".pushRemote", "remote.pushDefault", "branch." + branchName + ".remote"} {
+		output, err := git.Command("config", "--get", key).Output()
+		// "." is the local repository, which branches of new worktrees track
+		if value := strings.TrimSpace(string(output)); err == nil && value != "" && value != "." {
+			name = value
+			break
+		}
+	}
+
+	remotes, err := git.GetRemotes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remotes: %w", err)
+	}
+	for _, remote := range remotes {
+		if remote.Name == name {
+			return remote, nil
+		}
+	}
+	// checkout sets the pushRemote of cross-repository PR branches to the
+	// fork's URL. Anything else is left for git push to report.
+	return git.NewRemote(name, name), nil
+}
+
+// switchAllRun switches to any worktree (PR, branch, or main). With recent,
+// the worktree is picked from the recently switched-to ones instead.
+func switchAllRun(shellMode, absolute, recent bool, identifier string) error {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
 		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	repoName := filepath.Base(gitRoot)
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
 	prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
 	if err != nil {
 		return fmt.Errorf("failed to get worktrees: %w", err)
@@ -1008,37 +3128,38 @@ func switchAllRun(shellMode bool, identifier string) error {
 
 	var targetPath string
 
-	// Handle direct selection
-	if identifier != "" {
+	if recent {
+		targetPath, err = selectRecentWorktree(gitRoot, prWorktrees, branchWorktrees)
+		if err != nil {
+			if shellMode {
+				return nil
+			}
+			return err
+		}
+		if targetPath == "" {
+			if !shellMode {
+				fmt.Println("Cancelled.")
+			}
+			return nil
+		}
+	} else if identifier != "" {
+		// Handle direct selection
 		if identifier == "main" {
 			targetPath = gitRoot
 		} else {
-			// Try to parse as PR number
-			if prNum, err := github.ParsePRNumber(identifier); err == nil {
-				for _, wt := range prWorktrees {
-					if wt.PRNumber == prNum {
-						targetPath = wt.Path
-						break
-					}
-				}
-			}
-
-			// If not found as PR, try to find as branch name
-			if targetPath == "" {
-				for _, wt := range branchWorktrees {
-					if wt.Branch == identifier {
-						targetPath = wt.Path
-						break
-					}
-				}
+			// Match by PR number first, then by branch name
+			wt, err := worktree.FindByIdentifier(prWorktrees, branchWorktrees, identifier)
+			if err != nil {
+				return err
 			}
 
-			if targetPath == "" {
+			if wt == nil {
 				if !shellMode {
 					fmt.Printf("Worktree '%s' not found.\n", identifier)
 				}
 				return nil
 			}
+			targetPath = wt.Path
 		}
 	} else {
 		// Interactive selection
@@ -1061,7 +3182,7 @@ func switchAllRun(shellMode bool, identifier string) error {
 		// Add branch worktrees
 		for _, wt := range branchWorktrees {
 			candidates = append(candidates, fmt.Sprintf("%s\t(local development)",
-				wt.Branch))
+				wt.BranchLabel(7)))
 		}
 
 		// Use gh CLI's built-in selection
@@ -1092,17 +3213,11 @@ func switchAllRun(shellMode bool, identifier string) error {
 		}
 	}
 
-	// Get current working directory for relative path calculation
-	cwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
-
-	// Convert absolute path to relative path
-	relPath, err := filepath.Rel(cwd, targetPath)
+	relPath, err := switchTargetPath(gitRoot, targetPath, absolute)
 	if err != nil {
-		relPath = targetPath
+		return err
 	}
+	recordSwitch(targetPath)
 
 	// Output based on mode
 	if shellMode {
@@ -1121,13 +3236,75 @@ func switchAllRun(shellMode bool, identifier string) error {
 	return nil
 }
 
-func removeRunInteractive(force bool) error {
+// selectRecentWorktree prompts for one of the recently switched-to worktrees,
+// most recent first, and returns its path, or "" if the prompt was cancelled.
+func selectRecentWorktree(gitRoot string, prWorktrees, branchWorktrees []*worktree.Info) (string, error) {
+	commonDir, err := git.GetCommonDir()
+	if err != nil {
+		return "", err
+	}
+	paths, err := worktree.RecentPaths(filepath.Join(commonDir, worktree.HistoryFile))
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no recently switched-to worktrees")
+	}
+
+	labels := map[string]string{gitRoot: "main\t(main worktree)"}
+	for _, wt := range prWorktrees {
+		title := wt.Title
+		if title == "" {
+			title = "(no title)"
+		}
+		labels[wt.Path] = fmt.Sprintf("#%d\t%s", wt.PRNumber, title)
+	}
+	for _, wt := range branchWorktrees {
+		labels[wt.Path] = fmt.Sprintf("%s\t(local development)", wt.BranchLabel(7))
+	}
+
+	candidates := make([]string, len(paths))
+	for i, path := range paths {
+		label, ok := labels[path]
+		if !ok {
+			// Still on disk but no longer a worktree of this repository
+			label = path
+		}
+		candidates[i] = label
+	}
+
+	selection, err := promptSelect("Select a recent worktree to switch to", candidates)
+	if err != nil {
+		return "", err
+	}
+	if selection == -1 {
+		return "", nil
+	}
+	return paths[selection], nil
+}
+
+// recordSwitch adds path to the repository's switch history for
+// `gh worktree switch --recent`. Failing to record it doesn't fail the switch.
+func recordSwitch(path string) {
+	commonDir, err := git.GetCommonDir()
+	if err == nil {
+		err = worktree.RecordSwitch(filepath.Join(commonDir, worktree.HistoryFile), path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record switch history: %v\n", err)
+	}
+}
+
+func removeRunInteractive(ctx context.Context, force bool) error {
 	gitRoot, err := git.GetRoot()
 	if err != nil {
 		return fmt.Errorf("failed to get git root: %w", err)
 	}
 
-	repoName := filepath.Base(gitRoot)
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
 	prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
 	if err != nil {
 		return fmt.Errorf("failed to get worktrees: %w", err)
@@ -1149,15 +3326,15 @@ func removeRunInteractive(force bool) error {
 		}
 		candidates = append(candidates, fmt.Sprintf("#%d\t%s\t%s",
 			wt.PRNumber,
-			wt.Branch,
+			wt.BranchLabel(7),
 			title))
 	}
 
 	// Add branch worktrees
 	for _, wt := range branchWorktrees {
 		candidates = append(candidates, fmt.Sprintf("branch:%s\t%s\t(local development)",
-			wt.Branch,
-			wt.Branch))
+			wt.BranchLabel(7),
+			wt.BranchLabel(7)))
 	}
 
 	// Use gh CLI's built-in selection
@@ -1183,14 +3360,22 @@ func removeRunInteractive(force bool) error {
 		isBranchWorktree = true
 	}
 
+	if err := worktree.CheckNotMain(selectedWorktree.Path, "remove"); err != nil {
+		return err
+	}
+
+	// Run teardown while the worktree still exists
+	runTeardown(selectedWorktree.Path, selectedWorktree.Branch)
+
 	// Remove the worktree
-	err = worktree.Remove(selectedWorktree.Path, force)
+	err = worktree.RemoveContext(ctx, selectedWorktree.Path, force)
 	if err != nil {
 		return fmt.Errorf("failed to remove worktree: %w", err)
 	}
 
-	// Delete the branch (this also removes branch-specific metadata)
-	if selectedWorktree.Branch != "" && selectedWorktree.Branch != "HEAD" {
+	// Delete the branch (this also removes branch-specific metadata).
+	// An isolated clone's branch lives in the clone and is gone with it.
+	if !selectedWorktree.Detached && selectedWorktree.Branch != "" && !selectedWorktree.Isolated {
 		if err := validate.BranchName(selectedWorktree.Branch); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: invalid branch name %s: %v\n", selectedWorktree.Branch, err)
 		} else {
@@ -1204,7 +3389,7 @@ func removeRunInteractive(force bool) error {
 
 	// Output based on worktree type
 	if isBranchWorktree {
-		fmt.Printf("Removed worktree for branch '%s' at %s\n", selectedWorktree.Branch, selectedWorktree.Path)
+		fmt.Printf("Removed worktree for branch '%s' at %s\n", selectedWorktree.BranchLabel(7), selectedWorktree.Path)
 	} else {
 		fmt.Printf("Removed worktree for #%d at %s\n", selectedWorktree.PRNumber, selectedWorktree.Path)
 		if selectedWorktree.Title != "" {
@@ -1215,6 +3400,219 @@ func removeRunInteractive(force bool) error {
 	return nil
 }
 
+// removeAllRun removes every PR worktree and/or branch worktree after a single confirmation.
+// Failures are collected as warnings so that one broken worktree doesn't stop the rest.
+func removeAllRun(ctx context.Context, includePR, includeBranch, force bool) error {
+	gitRoot, err := git.GetRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get git root: %w", err)
+	}
+
+	repoName, err := repoNameOf(gitRoot)
+	if err != nil {
+		return err
+	}
+	prWorktrees, branchWorktrees, err := worktree.ListAllWorktrees(repoName)
+	if err != nil {
+		return fmt.Errorf("failed to get worktrees: %w", err)
+	}
+
+	var targets []*worktree.Info
+	if includePR {
+		targets = append(targets, prWorktrees...)
+	}
+	if includeBranch {
+		targets = append(targets, branchWorktrees...)
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("No worktrees found.")
+		return nil
+	}
+
+	// Show what will be removed and confirm once
+	fmt.Printf("The following %d worktree(s) will be removed:\n", len(targets))
+	for _, wt := range targets {
+		if wt.PRNumber != 0 {
+			fmt.Printf("  #%d\t%s\t%s\n", wt.PRNumber, wt.BranchLabel(7), wt.Path)
+		} else {
+			fmt.Printf("  %s\t%s\n", wt.BranchLabel(7), wt.Path)
+		}
+	}
+
+	if !force {
+		confirmed, err := promptConfirm(fmt.Sprintf("Remove %d worktree(s) and their branches?", len(targets)), false)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	removed := removeWorktrees(ctx, targets, force)
+	fmt.Printf("Removed %d of %d worktree(s)\n", removed, len(targets))
+
+	return nil
+}
+
+// removeWorktrees removes the worktrees and their branches, running teardown first.
+// Failures are printed as warnings and the number of removed worktrees is returned.
+func removeWorktrees(ctx context.Context, targets []*worktree.Info, force bool) int {
+	var warnings []string
+	removed := 0
+	for _, wt := range targets {
+		if err := worktree.CheckNotMain(wt.Path, "remove"); err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		runTeardown(wt.Path, wt.Branch)
+
+		if err := worktree.RemoveContext(ctx, wt.Path, force); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to remove worktree %s: %v", wt.Path, err))
+			continue
+		}
+		removed++
+
+		// Delete the branch (this also removes branch-specific metadata).
+		// An isolated clone's branch lives in the clone and is gone with it.
+		if !wt.Detached && wt.Branch != "" && !wt.Isolated {
+			if err := validate.BranchName(wt.Branch); err != nil {
+				warnings = append(warnings, fmt.Sprintf("invalid branch name %s: %v", wt.Branch, err))
+			} else if err := worktree.DeleteBranch(wt.Branch); err != nil {
+				warnings = append(warnings, fmt.Sprintf("failed to delete branch %s: %v", wt.Branch, err))
+			}
+		}
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return removed
+}
+
+// autoPrune offers to remove the worktrees of merged PRs if auto_prune is enabled.
+// It runs at the start of list and switch; all output goes to stderr so that
+// --json and --shell output stay intact. Failures only produce a warning.
+func autoPrune(gitRoot, repoName string) {
+	config, err := setup.LoadConfig(gitRoot)
+	if err != nil || config.AutoPrune == nil || !*config.AutoPrune {
+		return
+	}
+
+	prWorktrees, err := worktree.ListPRWorktrees(repoName)
+	if err != nil || len(prWorktrees) == 0 {
+		return
+	}
+
+	// Never offer to remove the worktree we're in
+	cwd, _ := os.Getwd()
+	current, _ := worktree.FindByPath(cwd)
+
+	var numbers []int
+	byNumber := make(map[int]*worktree.Info)
+	for _, wt := range prWorktrees {
+		if wt.PRNumber == 0 || (current != nil && current.Path == wt.Path) {
+			continue
+		}
+		numbers = append(numbers, wt.PRNumber)
+		byNumber[wt.PRNumber] = wt
+	}
+	if len(numbers) == 0 {
+		return
+	}
+
+	repo, err := repository.Current()
+	if err != nil {
+		return
+	}
+	client, err := api.DefaultRESTClient()
+	if err != nil {
+		return
+	}
+	merged, err := github.MergedPRs(client, repo.Owner, repo.Name, numbers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: auto_prune: %v\n", err)
+		return
+	}
+	if len(merged) == 0 {
+		return
+	}
+
+	var targets []*worktree.Info
+	fmt.Fprintf(os.Stderr, "The PRs of %d worktree(s) have been merged:\n", len(merged))
+	for _, n := range merged {
+		wt := byNumber[n]
+		targets = append(targets, wt)
+		fmt.Fprintf(os.Stderr, "  #%d\t%s\t%s\n", wt.PRNumber, wt.BranchLabel(7), wt.Path)
+	}
+
+	confirmed, err := promptConfirm(fmt.Sprintf("Remove %d merged worktree(s) and their branches?", len(targets)), false)
+	if err != nil || !confirmed {
+		return
+	}
+	removed := removeWorktrees(context.Background(), targets, false)
+	fmt.Fprintf(os.Stderr, "Removed %d of %d merged worktree(s)\n", removed, len(targets))
+}
+
+// runTeardown runs the configured teardown commands for a worktree about to be removed.
+// Failures are only reported as warnings and never block the removal.
+func runTeardown(worktreePath, branchName string) {
+	// A worktree whose directory is gone has nothing to tear down
+	if _, err := os.Stat(worktreePath); os.IsNotExist(err) {
+		return
+	}
+
+	mainWorktree, err := git.GetMainWorktree()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to get main worktree for teardown: %v\n", err)
+		return
+	}
+
+	if _, err := setup.RunTeardown(worktreePath, mainWorktree, branchName); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to run teardown: %v\n", err)
+	}
+}
+
+// promptBranchName asks for the name of a new branch until a valid one is entered.
+// An empty name means the user cancelled.
+func promptBranchName() (string, error) {
+	p := prompter.New(os.Stdin, os.Stderr, os.Stderr)
+	for {
+		branchName, err := p.Input("Enter branch name (empty to cancel):", "")
+		if err != nil {
+			return "", err
+		}
+
+		branchName = strings.TrimSpace(branchName)
+		if branchName == "" {
+			return "", nil
+		}
+		if err := validate.BranchName(branchName); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid branch name: %v\n", err)
+			continue
+		}
+		return branchName, nil
+	}
+}
+
+// promptConfirm asks a yes/no question, answering yes without prompting when --yes is set
+func promptConfirm(message string, defaultValue bool) (bool, error) {
+	if assumeYes {
+		fmt.Fprintf(os.Stderr, "%s Yes (--yes)\n", message)
+		return true, nil
+	}
+	p := prompter.New(os.Stdin, os.Stderr, os.Stderr)
+	return p.Confirm(message, defaultValue)
+}
+
+// promptMultiSelect asks for any number of candidates and returns their indices
+func promptMultiSelect(message string, candidates []string) ([]int, error) {
+	p := prompter.New(os.Stdin, os.Stderr, os.Stderr)
+	return p.MultiSelect(message, nil, candidates)
+}
+
 func promptSelect(message string, candidates []string) (int, error) {
 	// Use gh CLI's built-in prompter - output prompts to stderr to avoid capture by $()
 	p := prompter.New(os.Stdin, os.Stderr, os.Stderr)